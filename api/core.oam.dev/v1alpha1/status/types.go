@@ -0,0 +1,99 @@
+package status
+
+import (
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Ready is true when all the objects of the application or module are ready.
+	Ready = v1alpha1.Ready
+	// Progressing is true while objects are being created or rolled out.
+	Progressing v1alpha1.ApplicationConditionType = "Progressing"
+	// Degraded is true when objects failed or are invalid.
+	Degraded v1alpha1.ApplicationConditionType = "Degraded"
+)
+
+// Condition is the ApplicationCondition of oam-go-sdk with the generation of the ApplicationConfiguration it was observed at.
+type Condition struct {
+	Type               v1alpha1.ApplicationConditionType `json:"type"`
+	Status             corev1.ConditionStatus            `json:"status"`
+	ObservedGeneration int64                             `json:"observedGeneration,omitempty"`
+	LastUpdateTime     metav1.Time                       `json:"lastUpdateTime,omitempty"`
+	LastTransitionTime metav1.Time                       `json:"lastTransitionTime,omitempty"`
+	Reason             string                            `json:"reason,omitempty"`
+	Message            string                            `json:"message,omitempty"`
+}
+
+// ModuleStatus is the ModuleStatus of oam-go-sdk with conditions and the <name>@<version> of the
+// ComponentSchematic rendered. Status is kept as display text.
+type ModuleStatus struct {
+	NamespacedName    string      `json:"name,omitempty"`
+	Kind              string      `json:"kind,omitempty"`
+	GroupVersion      string      `json:"groupVersion,omitempty"`
+	Status            string      `json:"status,omitempty"`
+	Conditions        []Condition `json:"conditions,omitempty"`
+	ComponentRevision string      `json:"componentRevision,omitempty"`
+}
+
+// ApplicationConfigurationStatus is the ApplicationConfigurationStatus of oam-go-sdk with the conditions
+// of the modules, the generation it was observed at and its revisions. It is written by merge patches of the status,
+// so that the fields unknown to oam-go-sdk are kept.
+type ApplicationConfigurationStatus struct {
+	Phase              v1alpha1.ApplicationPhase `json:"phase,omitempty"`
+	ObservedGeneration int64                     `json:"observedGeneration,omitempty"`
+	Modules            []ModuleStatus            `json:"modules,omitempty"`
+	Conditions         []Condition               `json:"conditions,omitempty"`
+	Resources          []v1alpha1.ResourceStatus `json:"resources,omitempty"`
+	// CurrentRevision is the newest revision recorded, LastAppliedRevision the revision rendered.
+	CurrentRevision     int64 `json:"currentRevision,omitempty"`
+	LastAppliedRevision int64 `json:"lastAppliedRevision,omitempty"`
+	// Drift lists the resources whose live fields differ from their last applied rendering.
+	Drift []DriftStatus `json:"drift,omitempty"`
+}
+
+// DriftStatus is a resource of a component instance with the paths of the fields which drifted,
+// changed or removed, or list items added. Reverted is true if the rendering was applied again.
+type DriftStatus struct {
+	ApiVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`
+	Component  string   `json:"component,omitempty"`
+	Fields     []string `json:"fields"`
+	Reverted   bool     `json:"reverted,omitempty"`
+}
+
+// GetCondition returns the condition of the type, or nil.
+func GetCondition(conditions []Condition, t v1alpha1.ApplicationConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of its type the way oam-go-sdk does, the times are kept
+// if nothing but the observed generation changes.
+func SetCondition(conditions []Condition, c Condition) []Condition {
+	existing := GetCondition(conditions, c.Type)
+	if existing == nil {
+		now := metav1.Now()
+		c.LastUpdateTime = now
+		c.LastTransitionTime = now
+		return append(conditions, c)
+	}
+	if existing.Status == c.Status && existing.Reason == c.Reason && existing.Message == c.Message {
+		existing.ObservedGeneration = c.ObservedGeneration
+		return conditions
+	}
+	now := metav1.Now()
+	c.LastUpdateTime = now
+	c.LastTransitionTime = existing.LastTransitionTime
+	if existing.Status != c.Status {
+		c.LastTransitionTime = now
+	}
+	*existing = c
+	return conditions
+}
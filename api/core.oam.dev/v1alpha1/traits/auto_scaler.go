@@ -1,8 +1,8 @@
 package traits
 
 type AutoScaler struct {
-	Minimum string `json:"minimum"`
-	Maximum string `json:"maximum"`
-	Memory  string `json:"memory,omitempty"`
-	Cpu     string `json:"cpu,omitempty"`
+	Minimum int32 `json:"minimum"`
+	Maximum int32 `json:"maximum"`
+	Memory  int32 `json:"memory,omitempty"`
+	Cpu     int32 `json:"cpu,omitempty"`
 }
@@ -0,0 +1,5 @@
+package traits
+
+type DependsOn struct {
+	Instances []string `json:"instances"`
+}
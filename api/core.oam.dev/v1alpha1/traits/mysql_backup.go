@@ -0,0 +1,10 @@
+package traits
+
+type MysqlBackup struct {
+	Schedule     string `json:"schedule"`
+	Retention    int32  `json:"retention,omitempty"`
+	PvcName      string `json:"pvcName,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	Size         string `json:"size,omitempty"`
+	Image        string `json:"image,omitempty"`
+}
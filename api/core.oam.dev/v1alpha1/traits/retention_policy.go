@@ -0,0 +1,11 @@
+package traits
+
+type RetentionPolicy struct {
+	Policies []ResourceRetention `json:"policies"`
+}
+
+type ResourceRetention struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name,omitempty"`
+	Policy string `json:"policy"`
+}
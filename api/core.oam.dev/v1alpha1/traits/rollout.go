@@ -0,0 +1,18 @@
+package traits
+
+import "k8s.io/apimachinery/pkg/util/intstr"
+
+type Rollout struct {
+	Strategy                string              `json:"strategy,omitempty"`
+	MaxSurge                *intstr.IntOrString `json:"maxSurge,omitempty"`
+	MaxUnavailable          *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	Steps                   []RolloutStep       `json:"steps,omitempty"`
+	PreviewSeconds          int32               `json:"previewSeconds,omitempty"`
+	AutoPromote             *bool               `json:"autoPromote,omitempty"`
+	ProgressDeadlineSeconds int32               `json:"progressDeadlineSeconds,omitempty"`
+}
+
+type RolloutStep struct {
+	Weight       int32 `json:"weight"`
+	PauseSeconds int32 `json:"pauseSeconds,omitempty"`
+}
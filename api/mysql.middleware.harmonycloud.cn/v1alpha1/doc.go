@@ -1,3 +1,4 @@
 // +k8s:deepcopy-gen=package,register
+// +groupName=mysql.middleware.harmonycloud.cn
 
 package v1alpha1
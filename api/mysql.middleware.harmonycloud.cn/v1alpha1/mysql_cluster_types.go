@@ -85,7 +85,7 @@ type MysqlClusterSpec struct {
 	Repository string `json:"repository,omitempty"`
 
 	//切换主从
-	SwitchMaster ClusterSwitch `json:"clusterSwitch, omitempty"`
+	SwitchMaster ClusterSwitch `json:"clusterSwitch,omitempty"`
 
 	// 镜像版本，根据镜像版本进行特化处理
 	Version string `json:"version,omitempty"`
@@ -97,7 +97,7 @@ type MysqlClusterSpec struct {
 	DeployStrategy MysqlClusterDeployStrategy `json:"deployStrategy,omitempty"`
 
 	// 业务部署
-	BusinessDeploy []BusinessDeploy `json:"businessDeploy, omitempty"`
+	BusinessDeploy []BusinessDeploy `json:"businessDeploy,omitempty"`
 
 	// statefulset 模板
 	Statefulset StatefulSetPolicy `json:"statefulset,omitempty"`
@@ -129,7 +129,7 @@ type MysqlClusterSpec struct {
 	TargetNodeName string `json:"targetNodeName,omitempty"`
 
 	//批量删除
-	BatchDelete bool `json:"targetNodeName,omitempty"`
+	BatchDelete bool `json:"batchDelete,omitempty"`
 }
 
 type MigratePolicy struct {
@@ -286,7 +286,7 @@ type TransferMcCluster struct {
 	//是否开始迁移
 	Start string `json:"start,omitempty"`
 	//迁移到某步骤
-	Step string `json:"start,omitempty"`
+	Step string `json:"step,omitempty"`
 	//是否失败
 	Failed bool `json:"failed,omitempty"`
 	//旧集群名称
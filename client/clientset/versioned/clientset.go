@@ -21,8 +21,8 @@ package versioned
 import (
 	"fmt"
 
-	harmonycloudv1alpha1 "hc-oam-controller/client/clientset/versioned/typed/harmonycloud.cn/v1alpha1"
 	harmonycloudv1beta1 "hc-oam-controller/client/clientset/versioned/typed/harmonycloud.cn/v1beta1"
+	mysqlv1alpha1 "hc-oam-controller/client/clientset/versioned/typed/mysql.middleware.harmonycloud.cn/v1alpha1"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -31,15 +31,15 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	HarmonycloudV1beta1() harmonycloudv1beta1.HarmonycloudV1beta1Interface
-	HarmonycloudV1alpha1() harmonycloudv1alpha1.HarmonycloudV1alpha1Interface
+	MysqlV1alpha1() mysqlv1alpha1.MysqlV1alpha1Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
 // version included in a Clientset.
 type Clientset struct {
 	*discovery.DiscoveryClient
-	harmonycloudV1beta1  *harmonycloudv1beta1.HarmonycloudV1beta1Client
-	harmonycloudV1alpha1 *harmonycloudv1alpha1.HarmonycloudV1alpha1Client
+	harmonycloudV1beta1 *harmonycloudv1beta1.HarmonycloudV1beta1Client
+	mysqlV1alpha1       *mysqlv1alpha1.MysqlV1alpha1Client
 }
 
 // HarmonycloudV1beta1 retrieves the HarmonycloudV1beta1Client
@@ -47,9 +47,9 @@ func (c *Clientset) HarmonycloudV1beta1() harmonycloudv1beta1.HarmonycloudV1beta
 	return c.harmonycloudV1beta1
 }
 
-// HarmonycloudV1alpha1 retrieves the HarmonycloudV1alpha1Client
-func (c *Clientset) HarmonycloudV1alpha1() harmonycloudv1alpha1.HarmonycloudV1alpha1Interface {
-	return c.harmonycloudV1alpha1
+// MysqlV1alpha1 retrieves the MysqlV1alpha1Client
+func (c *Clientset) MysqlV1alpha1() mysqlv1alpha1.MysqlV1alpha1Interface {
+	return c.mysqlV1alpha1
 }
 
 // Discovery retrieves the DiscoveryClient
@@ -77,7 +77,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
-	cs.harmonycloudV1alpha1, err = harmonycloudv1alpha1.NewForConfig(&configShallowCopy)
+	cs.mysqlV1alpha1, err = mysqlv1alpha1.NewForConfig(&configShallowCopy)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +94,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.harmonycloudV1beta1 = harmonycloudv1beta1.NewForConfigOrDie(c)
-	cs.harmonycloudV1alpha1 = harmonycloudv1alpha1.NewForConfigOrDie(c)
+	cs.mysqlV1alpha1 = mysqlv1alpha1.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -104,7 +104,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.harmonycloudV1beta1 = harmonycloudv1beta1.New(c)
-	cs.harmonycloudV1alpha1 = harmonycloudv1alpha1.New(c)
+	cs.mysqlV1alpha1 = mysqlv1alpha1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -21,10 +21,10 @@ package fake
 import (
 	clientset "hc-oam-controller/client/clientset/versioned"
 
-	harmonycloudv1alpha1 "hc-oam-controller/client/clientset/versioned/typed/harmonycloud.cn/v1alpha1"
-	fakeharmonycloudv1alpha1 "hc-oam-controller/client/clientset/versioned/typed/harmonycloud.cn/v1alpha1/fake"
 	harmonycloudv1beta1 "hc-oam-controller/client/clientset/versioned/typed/harmonycloud.cn/v1beta1"
 	fakeharmonycloudv1beta1 "hc-oam-controller/client/clientset/versioned/typed/harmonycloud.cn/v1beta1/fake"
+	mysqlv1alpha1 "hc-oam-controller/client/clientset/versioned/typed/mysql.middleware.harmonycloud.cn/v1alpha1"
+	fakemysqlv1alpha1 "hc-oam-controller/client/clientset/versioned/typed/mysql.middleware.harmonycloud.cn/v1alpha1/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -84,7 +84,7 @@ func (c *Clientset) HarmonycloudV1beta1() harmonycloudv1beta1.HarmonycloudV1beta
 	return &fakeharmonycloudv1beta1.FakeHarmonycloudV1beta1{Fake: &c.Fake}
 }
 
-// HarmonycloudV1alpha1 retrieves the HarmonycloudV1alpha1Client
-func (c *Clientset) HarmonycloudV1alpha1() harmonycloudv1alpha1.HarmonycloudV1alpha1Interface {
-	return &fakeharmonycloudv1alpha1.FakeHarmonycloudV1alpha1{Fake: &c.Fake}
+// MysqlV1alpha1 retrieves the MysqlV1alpha1Client
+func (c *Clientset) MysqlV1alpha1() mysqlv1alpha1.MysqlV1alpha1Interface {
+	return &fakemysqlv1alpha1.FakeMysqlV1alpha1{Fake: &c.Fake}
 }
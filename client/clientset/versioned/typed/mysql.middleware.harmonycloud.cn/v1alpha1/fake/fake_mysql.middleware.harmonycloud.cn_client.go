@@ -19,22 +19,22 @@ limitations under the License.
 package fake
 
 import (
-	v1alpha1 "hc-oam-controller/client/clientset/versioned/typed/harmonycloud.cn/v1alpha1"
+	v1alpha1 "hc-oam-controller/client/clientset/versioned/typed/mysql.middleware.harmonycloud.cn/v1alpha1"
 	rest "k8s.io/client-go/rest"
 	testing "k8s.io/client-go/testing"
 )
 
-type FakeHarmonycloudV1alpha1 struct {
+type FakeMysqlV1alpha1 struct {
 	*testing.Fake
 }
 
-func (c *FakeHarmonycloudV1alpha1) MysqlClusters(namespace string) v1alpha1.MysqlClusterInterface {
+func (c *FakeMysqlV1alpha1) MysqlClusters(namespace string) v1alpha1.MysqlClusterInterface {
 	return &FakeMysqlClusters{c, namespace}
 }
 
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
-func (c *FakeHarmonycloudV1alpha1) RESTClient() rest.Interface {
+func (c *FakeMysqlV1alpha1) RESTClient() rest.Interface {
 	var ret *rest.RESTClient
 	return ret
 }
@@ -21,7 +21,7 @@ package fake
 import (
 	"context"
 
-	v1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
+	v1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels "k8s.io/apimachinery/pkg/labels"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -32,13 +32,13 @@ import (
 
 // FakeMysqlClusters implements MysqlClusterInterface
 type FakeMysqlClusters struct {
-	Fake *FakeHarmonycloudV1alpha1
+	Fake *FakeMysqlV1alpha1
 	ns   string
 }
 
-var mysqlclustersResource = schema.GroupVersionResource{Group: "harmonycloud.cn", Version: "v1alpha1", Resource: "mysqlclusters"}
+var mysqlclustersResource = schema.GroupVersionResource{Group: "mysql.middleware.harmonycloud.cn", Version: "v1alpha1", Resource: "mysqlclusters"}
 
-var mysqlclustersKind = schema.GroupVersionKind{Group: "harmonycloud.cn", Version: "v1alpha1", Kind: "MysqlCluster"}
+var mysqlclustersKind = schema.GroupVersionKind{Group: "mysql.middleware.harmonycloud.cn", Version: "v1alpha1", Kind: "MysqlCluster"}
 
 // Get takes name of the mysqlCluster, and returns the corresponding mysqlCluster object, and an error if there is any.
 func (c *FakeMysqlClusters) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.MysqlCluster, err error) {
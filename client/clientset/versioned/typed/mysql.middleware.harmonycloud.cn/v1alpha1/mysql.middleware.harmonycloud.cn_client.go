@@ -21,26 +21,26 @@ package v1alpha1
 import (
 	"hc-oam-controller/client/clientset/versioned/scheme"
 
-	v1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
+	v1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	rest "k8s.io/client-go/rest"
 )
 
-type HarmonycloudV1alpha1Interface interface {
+type MysqlV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	MysqlClustersGetter
 }
 
-// HarmonycloudV1alpha1Client is used to interact with features provided by the harmonycloud.cn group.
-type HarmonycloudV1alpha1Client struct {
+// MysqlV1alpha1Client is used to interact with features provided by the mysql.middleware.harmonycloud.cn group.
+type MysqlV1alpha1Client struct {
 	restClient rest.Interface
 }
 
-func (c *HarmonycloudV1alpha1Client) MysqlClusters(namespace string) MysqlClusterInterface {
+func (c *MysqlV1alpha1Client) MysqlClusters(namespace string) MysqlClusterInterface {
 	return newMysqlClusters(c, namespace)
 }
 
-// NewForConfig creates a new HarmonycloudV1alpha1Client for the given config.
-func NewForConfig(c *rest.Config) (*HarmonycloudV1alpha1Client, error) {
+// NewForConfig creates a new MysqlV1alpha1Client for the given config.
+func NewForConfig(c *rest.Config) (*MysqlV1alpha1Client, error) {
 	config := *c
 	if err := setConfigDefaults(&config); err != nil {
 		return nil, err
@@ -49,12 +49,12 @@ func NewForConfig(c *rest.Config) (*HarmonycloudV1alpha1Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &HarmonycloudV1alpha1Client{client}, nil
+	return &MysqlV1alpha1Client{client}, nil
 }
 
-// NewForConfigOrDie creates a new HarmonycloudV1alpha1Client for the given config and
+// NewForConfigOrDie creates a new MysqlV1alpha1Client for the given config and
 // panics if there is an error in the config.
-func NewForConfigOrDie(c *rest.Config) *HarmonycloudV1alpha1Client {
+func NewForConfigOrDie(c *rest.Config) *MysqlV1alpha1Client {
 	client, err := NewForConfig(c)
 	if err != nil {
 		panic(err)
@@ -62,9 +62,9 @@ func NewForConfigOrDie(c *rest.Config) *HarmonycloudV1alpha1Client {
 	return client
 }
 
-// New creates a new HarmonycloudV1alpha1Client for the given RESTClient.
-func New(c rest.Interface) *HarmonycloudV1alpha1Client {
-	return &HarmonycloudV1alpha1Client{c}
+// New creates a new MysqlV1alpha1Client for the given RESTClient.
+func New(c rest.Interface) *MysqlV1alpha1Client {
+	return &MysqlV1alpha1Client{c}
 }
 
 func setConfigDefaults(config *rest.Config) error {
@@ -82,7 +82,7 @@ func setConfigDefaults(config *rest.Config) error {
 
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
-func (c *HarmonycloudV1alpha1Client) RESTClient() rest.Interface {
+func (c *MysqlV1alpha1Client) RESTClient() rest.Interface {
 	if c == nil {
 		return nil
 	}
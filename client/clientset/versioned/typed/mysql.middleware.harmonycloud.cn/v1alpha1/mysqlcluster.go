@@ -23,7 +23,7 @@ import (
 	scheme "hc-oam-controller/client/clientset/versioned/scheme"
 	"time"
 
-	v1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
+	v1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	types "k8s.io/apimachinery/pkg/types"
 	watch "k8s.io/apimachinery/pkg/watch"
@@ -57,7 +57,7 @@ type mysqlClusters struct {
 }
 
 // newMysqlClusters returns a MysqlClusters
-func newMysqlClusters(c *HarmonycloudV1alpha1Client, namespace string) *mysqlClusters {
+func newMysqlClusters(c *MysqlV1alpha1Client, namespace string) *mysqlClusters {
 	return &mysqlClusters{
 		client: c.RESTClient(),
 		ns:     namespace,
@@ -26,6 +26,7 @@ import (
 	time "time"
 
 	harmonycloudcn "hc-oam-controller/client/informers/externalversions/harmonycloud.cn"
+	mysqlmiddlewareharmonycloudcn "hc-oam-controller/client/informers/externalversions/mysql.middleware.harmonycloud.cn"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -173,8 +174,13 @@ type SharedInformerFactory interface {
 	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
 
 	Harmonycloud() harmonycloudcn.Interface
+	Mysql() mysqlmiddlewareharmonycloudcn.Interface
 }
 
 func (f *sharedInformerFactory) Harmonycloud() harmonycloudcn.Interface {
 	return harmonycloudcn.New(f, f.namespace, f.tweakListOptions)
 }
+
+func (f *sharedInformerFactory) Mysql() mysqlmiddlewareharmonycloudcn.Interface {
+	return mysqlmiddlewareharmonycloudcn.New(f, f.namespace, f.tweakListOptions)
+}
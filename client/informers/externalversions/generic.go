@@ -21,8 +21,8 @@ package externalversions
 import (
 	"fmt"
 
-	v1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
 	v1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	v1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -53,14 +53,14 @@ func (f *genericInformer) Lister() cache.GenericLister {
 // TODO extend this to unknown resources with a client pool
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
-	// Group=harmonycloud.cn, Version=v1alpha1
-	case v1alpha1.SchemeGroupVersion.WithResource("mysqlclusters"):
-		return &genericInformer{resource: resource.GroupResource(), informer: f.Harmonycloud().V1alpha1().MysqlClusters().Informer()}, nil
-
-		// Group=harmonycloud.cn, Version=v1beta1
+	// Group=harmonycloud.cn, Version=v1beta1
 	case v1beta1.SchemeGroupVersion.WithResource("horizontalpodautoscalers"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Harmonycloud().V1beta1().HorizontalPodAutoscalers().Informer()}, nil
 
+		// Group=mysql.middleware.harmonycloud.cn, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("mysqlclusters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Mysql().V1alpha1().MysqlClusters().Informer()}, nil
+
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
@@ -21,7 +21,6 @@ package harmonycloud
 import (
 	internalinterfaces "hc-oam-controller/client/informers/externalversions/internalinterfaces"
 
-	v1alpha1 "hc-oam-controller/client/informers/externalversions/harmonycloud.cn/v1alpha1"
 	v1beta1 "hc-oam-controller/client/informers/externalversions/harmonycloud.cn/v1beta1"
 )
 
@@ -29,8 +28,6 @@ import (
 type Interface interface {
 	// V1beta1 provides access to shared informers for resources in V1beta1.
 	V1beta1() v1beta1.Interface
-	// V1alpha1 provides access to shared informers for resources in V1alpha1.
-	V1alpha1() v1alpha1.Interface
 }
 
 type group struct {
@@ -48,8 +45,3 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (g *group) V1beta1() v1beta1.Interface {
 	return v1beta1.New(g.factory, g.namespace, g.tweakListOptions)
 }
-
-// V1alpha1 returns a new v1alpha1.Interface.
-func (g *group) V1alpha1() v1alpha1.Interface {
-	return v1alpha1.New(g.factory, g.namespace, g.tweakListOptions)
-}
@@ -24,8 +24,8 @@ import (
 	internalinterfaces "hc-oam-controller/client/informers/externalversions/internalinterfaces"
 	time "time"
 
-	harmonycloudcnv1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
-	v1alpha1 "hc-oam-controller/client/listers/harmonycloud.cn/v1alpha1"
+	mysqlmiddlewareharmonycloudcnv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	v1alpha1 "hc-oam-controller/client/listers/mysql.middleware.harmonycloud.cn/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	watch "k8s.io/apimachinery/pkg/watch"
@@ -62,16 +62,16 @@ func NewFilteredMysqlClusterInformer(client versioned.Interface, namespace strin
 				if tweakListOptions != nil {
 					tweakListOptions(&options)
 				}
-				return client.HarmonycloudV1alpha1().MysqlClusters(namespace).List(context.TODO(), options)
+				return client.MysqlV1alpha1().MysqlClusters(namespace).List(context.TODO(), options)
 			},
 			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
 				if tweakListOptions != nil {
 					tweakListOptions(&options)
 				}
-				return client.HarmonycloudV1alpha1().MysqlClusters(namespace).Watch(context.TODO(), options)
+				return client.MysqlV1alpha1().MysqlClusters(namespace).Watch(context.TODO(), options)
 			},
 		},
-		&harmonycloudcnv1alpha1.MysqlCluster{},
+		&mysqlmiddlewareharmonycloudcnv1alpha1.MysqlCluster{},
 		resyncPeriod,
 		indexers,
 	)
@@ -82,7 +82,7 @@ func (f *mysqlClusterInformer) defaultInformer(client versioned.Interface, resyn
 }
 
 func (f *mysqlClusterInformer) Informer() cache.SharedIndexInformer {
-	return f.factory.InformerFor(&harmonycloudcnv1alpha1.MysqlCluster{}, f.defaultInformer)
+	return f.factory.InformerFor(&mysqlmiddlewareharmonycloudcnv1alpha1.MysqlCluster{}, f.defaultInformer)
 }
 
 func (f *mysqlClusterInformer) Lister() v1alpha1.MysqlClusterLister {
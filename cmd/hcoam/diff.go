@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	hcversioned "hc-oam-controller/client/clientset/versioned"
+	"hc-oam-controller/controllers"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// diff prints the changes a reconcile of the ApplicationConfigurations in the files would make to the cluster.
+// Nothing is written to the cluster.
+func diff(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var files stringsFlag
+	fs.Var(&files, "f", "A file or directory containing ApplicationConfigurations, can be repeated. The ComponentSchematics, Traits and WorkloadTypes in the files replace the live ones.")
+	namespace := fs.String("n", "default", "The namespace of the ApplicationConfigurations without namespace.")
+	kubeconfig := fs.String("kubeconfig", "", "Path to a kubeconfig, the default config is used if not set.")
+	output := fs.String("o", "text", "Output format, text or json.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no file given, use -f")
+	}
+
+	var config *rest.Config
+	var err error
+	if *kubeconfig != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	} else {
+		config, err = ctrl.GetConfig()
+	}
+	if err != nil {
+		return err
+	}
+	oamclient, err := versioned.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	k8sclient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	hcclient, err := hcversioned.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	dynamicclient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	controllers.RegisterBuiltins()
+	resolver := newFileResolver()
+	var acs []*v1alpha1.ApplicationConfiguration
+	for _, f := range files {
+		docs, err := readDocuments(f)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			ac, err := resolver.add(doc, *namespace)
+			if err != nil {
+				return err
+			}
+			if ac != nil {
+				acs = append(acs, ac)
+			}
+		}
+	}
+
+	result := map[string][]controllers.ResourceDiff{}
+	for _, ac := range acs {
+		diffs, warnings, err := controllers.DiffApplicationConfiguration(ac, resolver.objects(), oamclient, k8sclient, hcclient, dynamicclient)
+		if err != nil {
+			return fmt.Errorf("ApplicationConfiguration %s: %v", ac.Name, err)
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(stderr, "warning: ApplicationConfiguration %s: %s\n", ac.Name, w)
+		}
+		if *output == "json" {
+			result[ac.Namespace+"/"+ac.Name] = diffs
+			continue
+		}
+		fmt.Fprintf(stdout, "ApplicationConfiguration %s/%s:\n", ac.Namespace, ac.Name)
+		if len(diffs) == 0 {
+			fmt.Fprintln(stdout, "  no changes")
+		}
+		for _, d := range diffs {
+			fmt.Fprintf(stdout, "%s %s %s %s\n", d.Action, d.ApiVersion, d.Kind, d.Name)
+			for _, c := range d.Changes {
+				switch {
+				case c.Old == nil:
+					fmt.Fprintf(stdout, "  + %s: %v\n", c.Path, c.New)
+				case c.New == nil:
+					fmt.Fprintf(stdout, "  - %s: %v\n", c.Path, c.Old)
+				default:
+					fmt.Fprintf(stdout, "  ~ %s: %v -> %v\n", c.Path, c.Old, c.New)
+				}
+			}
+		}
+	}
+	if *output == "json" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(data))
+	}
+	return nil
+}
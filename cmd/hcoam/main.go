@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `hcoam is the command line tool of hc-oam-controller.
+
+Usage:
+  hcoam render [-n namespace] -f FILE [-f FILE ...]
+  hcoam diff [-n namespace] [-kubeconfig FILE] [-o text|json] -f FILE [-f FILE ...]
+
+Commands:
+  render  Render ApplicationConfigurations to the kubernetes objects hc-oam-controller would create
+  diff    Show the changes a reconcile of ApplicationConfigurations would make to the cluster, without writing
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "render":
+		err = render(os.Args[2:], os.Stdout, os.Stderr)
+	case "diff":
+		err = diff(os.Args[2:], os.Stdout, os.Stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
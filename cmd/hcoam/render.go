@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	"hc-oam-controller/controllers"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = hcv1alpha1.AddToScheme(scheme)
+	_ = hcv1beta1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+	_ = batchv1beta1.AddToScheme(scheme)
+	_ = extensionsv1beta1.AddToScheme(scheme)
+	_ = v2beta2.AddToScheme(scheme)
+}
+
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// render prints the objects rendered from the ApplicationConfigurations in the files as YAML.
+func render(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var files stringsFlag
+	fs.Var(&files, "f", "A file or directory containing ApplicationConfigurations, ComponentSchematics, Traits and WorkloadTypes, can be repeated.")
+	namespace := fs.String("n", "default", "The namespace of the objects without namespace.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no file given, use -f")
+	}
+
+	controllers.RegisterBuiltins()
+	resolver := newFileResolver()
+	var acs []*v1alpha1.ApplicationConfiguration
+	for _, f := range files {
+		docs, err := readDocuments(f)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			ac, err := resolver.add(doc, *namespace)
+			if err != nil {
+				return err
+			}
+			if ac != nil {
+				acs = append(acs, ac)
+			}
+		}
+	}
+
+	first := true
+	for _, ac := range acs {
+		objects, warnings, err := controllers.RenderApplicationConfiguration(ac, resolver)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(stderr, "warning: ApplicationConfiguration %s: %s\n", ac.Name, w)
+		}
+		for _, obj := range objects {
+			out, err := toYAML(obj)
+			if err != nil {
+				return err
+			}
+			if !first {
+				fmt.Fprintln(stdout, "---")
+			}
+			first = false
+			stdout.Write(out)
+		}
+	}
+	return nil
+}
+
+// readDocuments returns the YAML or JSON documents in the file, or in the .yaml, .yml and .json files of the directory.
+func readDocuments(path string) ([][]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	if info.IsDir() {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json":
+				paths = append(paths, filepath.Join(path, e.Name()))
+			}
+		}
+	} else {
+		paths = append(paths, path)
+	}
+
+	var docs [][]byte
+	for _, p := range paths {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		for {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("%s: %v", p, err)
+			}
+			if len(raw) > 0 && string(raw) != "null" {
+				docs = append(docs, raw)
+			}
+		}
+	}
+	return docs, nil
+}
+
+// fileResolver resolves the ComponentSchematics, Traits and WorkloadTypes read from the files.
+type fileResolver struct {
+	components    map[string]*v1alpha1.ComponentSchematic
+	traits        map[string]*v1alpha1.Trait
+	workloadTypes map[string]*v1alpha1.WorkloadType
+}
+
+func newFileResolver() *fileResolver {
+	return &fileResolver{
+		components:    map[string]*v1alpha1.ComponentSchematic{},
+		traits:        map[string]*v1alpha1.Trait{},
+		workloadTypes: map[string]*v1alpha1.WorkloadType{},
+	}
+}
+
+// add keeps the ComponentSchematic, Trait or WorkloadType of the JSON document, and returns it if it is an ApplicationConfiguration.
+// Other kinds are ignored.
+func (r *fileResolver) add(doc []byte, namespace string) (*v1alpha1.ApplicationConfiguration, error) {
+	var meta runtime.TypeMeta
+	if err := json.Unmarshal(doc, &meta); err != nil {
+		return nil, err
+	}
+	switch meta.Kind {
+	case "ApplicationConfiguration":
+		ac := new(v1alpha1.ApplicationConfiguration)
+		if err := json.Unmarshal(doc, ac); err != nil {
+			return nil, err
+		}
+		if ac.Namespace == "" {
+			ac.Namespace = namespace
+		}
+		return ac, nil
+	case "ComponentSchematic":
+		comp := new(v1alpha1.ComponentSchematic)
+		if err := json.Unmarshal(doc, comp); err != nil {
+			return nil, err
+		}
+		if comp.Namespace == "" {
+			comp.Namespace = namespace
+		}
+		r.components[comp.Namespace+"/"+comp.Name] = comp
+	case "Trait":
+		trait := new(v1alpha1.Trait)
+		if err := json.Unmarshal(doc, trait); err != nil {
+			return nil, err
+		}
+		r.traits[trait.Name] = trait
+	case "WorkloadType":
+		wt := new(v1alpha1.WorkloadType)
+		if err := json.Unmarshal(doc, wt); err != nil {
+			return nil, err
+		}
+		r.workloadTypes[controllers.WorkloadTypeName(wt)] = wt
+	}
+	return nil, nil
+}
+
+// objects returns the ComponentSchematics, Traits and WorkloadTypes read from the files.
+func (r *fileResolver) objects() []runtime.Object {
+	var objects []runtime.Object
+	for _, comp := range r.components {
+		objects = append(objects, comp.DeepCopy())
+	}
+	for _, trait := range r.traits {
+		objects = append(objects, trait.DeepCopy())
+	}
+	for _, wt := range r.workloadTypes {
+		objects = append(objects, wt.DeepCopy())
+	}
+	return objects
+}
+
+func (r *fileResolver) GetComponentSchematic(namespace, name string) (*v1alpha1.ComponentSchematic, error) {
+	if comp, ok := r.components[namespace+"/"+name]; ok {
+		return comp.DeepCopy(), nil
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.Resource("componentschematics"), name)
+}
+
+func (r *fileResolver) GetTrait(name string) (*v1alpha1.Trait, error) {
+	if trait, ok := r.traits[name]; ok {
+		return trait.DeepCopy(), nil
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.Resource("traits"), name)
+}
+
+func (r *fileResolver) GetWorkloadType(workloadType string) (*v1alpha1.WorkloadType, error) {
+	if wt, ok := r.workloadTypes[workloadType]; ok {
+		return wt.DeepCopy(), nil
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.Resource("workloadtypes"), workloadType)
+}
+
+// toYAML marshals the object with its apiVersion and kind set.
+func toYAML(obj runtime.Object) ([]byte, error) {
+	if !obj.GetObjectKind().GroupVersionKind().Empty() {
+		// objects rendered from templates
+		return yaml.Marshal(obj)
+	}
+	gvks, _, err := scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	return yaml.Marshal(obj)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	// the example of the manual-scaler trait, with an ApplicationConfiguration of a missing component
+	err := render([]string{"-f", "../../examples/traits/manual-scaler", "-f", "../../examples/samples/simple-example/application-configurations.yaml"}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	docs := strings.Split(stdout.String(), "\n---\n")
+	if len(docs) != 2 {
+		t.Fatalf("render() printed %d objects, want a Deployment and a Service:\n%s", len(docs), stdout.String())
+	}
+	for i, want := range []string{"kind: Deployment", "kind: Service"} {
+		if !strings.Contains(docs[i], want) || !strings.Contains(docs[i], "application: manual-scaler-example") {
+			t.Errorf("object %d = %s, want %s of manual-scaler-example", i, docs[i], want)
+		}
+	}
+	if !strings.Contains(docs[0], "replicas: 3") {
+		t.Errorf("Deployment = %s, want the replicas of the manual-scaler", docs[0])
+	}
+	if want := "warning: ApplicationConfiguration simple-app: ComponentSchematic stateless-component not found"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("render() warnings = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestRenderWithoutFiles(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := render(nil, &stdout, &stderr); err == nil {
+		t.Error("render() without -f succeeded, want an error")
+	}
+}
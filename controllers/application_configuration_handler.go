@@ -7,18 +7,22 @@ import (
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 	"github.com/oam-dev/oam-go-sdk/pkg/oam"
 	"github.com/oam-dev/oam-go-sdk/pkg/util"
-	hcv1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
+	acstatus "hc-oam-controller/api/core.oam.dev/v1alpha1/status"
 	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/autoscaling/v2beta2"
 	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	apiv1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/types"
 	"strings"
+	"time"
 
 	//"k8s.io/api/networking/v1beta1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -34,205 +38,279 @@ func (s *ApplicationConfigurationHandler) Handle(ctx *oam.ActionContext, obj run
 		return errors.New("type mismatch")
 	}
 	handlerLog.Info("Received ApplicationConfiguration.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
+	if eType == oam.Delete {
+		return teardown(s, ac)
+	}
+	if err := ensureFinalizer(s, ac); err != nil {
+		handlerLog.Info("Add finalizer failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Error", err)
+		return err
+	}
 
-	owner := *v1.NewControllerRef(ac, v1alpha1.SchemeGroupVersion.WithKind("ApplicationConfiguration"))
-	for _, compConf := range ac.Spec.Components {
-		annotations := map[string]string{
-			"application": ac.Name,
-			"component":   compConf.ComponentName,
-			"instance":    compConf.InstanceName,
+	// sensitive values are masked in the events of this reconcile
+	masker := &maskingRecorder{EventRecorder: s.Recorder}
+	handler := *s
+	handler.Recorder = masker
+	s = &handler
+
+	// the requested revision is rendered instead of the spec, and no revision is recorded while it is
+	if number := ac.Annotations[RollbackAnnotation]; number != "" {
+		revision, err := getRevision(s, ac, getRollbackRevision(ac))
+		if err != nil {
+			handlerLog.Info("Get revision failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Revision", number, "Error", err)
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, NotFound, fmt.Sprintf(RevisionNotFoundMessage, number, ac.Name, err))
+		} else {
+			s.rollback = revision
+			ac.Spec = revision.Spec
 		}
-		comp, err := s.Oamclient.CoreV1alpha1().ComponentSchematics(ac.Namespace).Get(compConf.ComponentName, v1.GetOptions{})
+	}
+	var schematics []v1alpha1.ComponentSchematic
+
+	// the live resources are listed before they are written, to compare them with the rendering last applied to
+	// them and to prune the ones not rendered anymore
+	live, listErr := listInstanceObjects(s, ac)
+	if listErr != nil {
+		handlerLog.Info("List resources error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Error", listErr)
+	}
+	drift := newDriftDetector(ac, live)
+
+	owner := *v1.NewControllerRef(ac, v1alpha1.SchemeGroupVersion.WithKind("ApplicationConfiguration"))
+	desired := newDesiredResources()
+	var rejectedTraits []string
+	var waiting []string
+	var rollouts []string
+	var rolloutDelay time.Duration
+	components, dependencyErrs := orderComponents(ac.Spec.Components)
+	for _, compConf := range components {
+		annotations := instanceAnnotations(ac, compConf)
+		comp, err := s.getComponentSchematic(ac.Namespace, compConf.ComponentName)
 		if err != nil {
 			s.Recorder.Event(ac, apiv1.EventTypeWarning, NotFound, fmt.Sprintf(ComponentNotFound, compConf.ComponentName))
 			return err
 		}
+		if _, version := splitComponentName(compConf.ComponentName); version == "" {
+			schematics = append(schematics, *comp)
+		}
 
-		parameterMap := parseParameters(compConf.ParameterValues, ac.Spec.Variables)
-
-		//create or update configmaps before create workloads
-		configMaps := convertConfigMaps(owner, annotations, compConf, *comp, parameterMap)
-		if err := createOrUpdateConfigMaps(s, ac, compConf.ComponentName, configMaps); err != nil {
-			handlerLog.Info("Create or update configMaps error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+		parameterMap, err := parseParameters(comp.Spec.Parameters, compConf.ParameterValues, ac.Spec.Variables)
+		if err != nil {
+			msg := masker.mask(fmt.Sprintf(ParametersInvalidMessage, compConf.InstanceName, err.Error()))
+			handlerLog.Info("Invalid parameters.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", msg)
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, InvalidParameters, msg)
+			addResourceStatus(&ac.Status.Resources, compConf.ComponentName, OamV1alpha1GroupVersion, Component, compConf.InstanceName, Workload, fmt.Sprintf(InvalidStatus, msg))
+			desired.keep(compConf.InstanceName)
+			continue
 		}
+		masker.add(*comp, parameterMap)
+		removeResourceStatus(&ac.Status.Resources, compConf.ComponentName, Component, compConf.InstanceName)
 
-		//create pvcs before create workloads
-		pvcs := convertPvcsFromVolumeMounters(owner, annotations, *comp, compConf.Traits)
-		if err := createOrUpdatePvcs(s, ac, compConf.ComponentName, pvcs); err != nil {
-			handlerLog.Info("Create pvcs error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+		// render the component after its dependencies are healthy
+		if e, ok := dependencyErrs[compConf.InstanceName]; ok {
+			handlerLog.Info("Invalid dependencies.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Message", e.Message)
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, e.Reason, e.Message)
+			addResourceStatus(&ac.Status.Resources, compConf.ComponentName, OamV1alpha1GroupVersion, Component, compConf.InstanceName, Workload, fmt.Sprintf(InvalidStatus, e.Message))
+			desired.keep(compConf.InstanceName)
+			continue
+		}
+		if dependencies := unhealthyDependencies(ac, compConf); len(dependencies) > 0 {
+			msg := fmt.Sprintf(WaitingMessage, compConf.InstanceName, strings.Join(dependencies, ", "))
+			handlerLog.Info("Waiting for dependencies.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Dependencies", dependencies)
+			s.Recorder.Event(ac, apiv1.EventTypeNormal, Waiting, msg)
+			waiting = append(waiting, msg)
+			desired.keep(compConf.InstanceName)
+			continue
 		}
 
-		switch comp.Spec.WorkloadType {
-		case WorkloadTypeServer, WorkloadTypeSingletonServer, WorkloadTypeWorker, WorkloadTypeSingletonWorker:
-			deployment := convertDeployment(owner, annotations, compConf, *comp, parameterMap)
-			var apiVersion string
+		// sensitive values are rendered into a Secret and referenced by the env and config files
+		parameterMap, parametersSecret := convertSensitiveParameters(owner, annotations, compConf, *comp, parameterMap)
 
-			//manuel-scaler trait
-			replicas := *getManuelScale(compConf.Traits)
-			if comp.Spec.WorkloadType == WorkloadTypeSingletonServer || comp.Spec.WorkloadType == WorkloadTypeSingletonWorker {
-				replicas = 1
+		//create or update configmaps before create workloads
+		configMaps := convertConfigMaps(owner, annotations, compConf, *comp, parameterMap)
+		var written []apiv1.ConfigMap
+		for i := range configMaps {
+			desired.add(ConfigMapApiVersion, ConfigMapKind, configMaps[i].Name)
+			labelApplication(ac, &configMaps[i])
+			if drift.apply(s, ac, compConf.InstanceName, &configMaps[i]) {
+				written = append(written, configMaps[i])
 			}
-			deployment.Spec.Replicas = &replicas
-
-			//volume-mounter trait
-			volumes := getVolumesFromVolumeMounters(compConf.Traits)
-			volumes = append(volumes, convertVolumesFromConfig(configMaps)...)
-			deployment.Spec.Template.Spec.Volumes = volumes
-
-			//log-pilot trait
-			volumes = append(volumes, getLogPilotVolumes(compConf.Traits)...)
-			deployment.Spec.Template.Spec.Volumes = volumes
-			for i, _ := range deployment.Spec.Template.Spec.Containers {
-				injectLogPilotConfigs(&deployment.Spec.Template.Spec.Containers[i], compConf.Traits)
-				// resources-policy
-				injectResourcesPolicy(&deployment.Spec.Template.Spec.Containers[i], compConf.Traits)
+		}
+		if err := createOrUpdateConfigMaps(s, ac, compConf.ComponentName, written); err != nil {
+			handlerLog.Info("Create or update configMaps error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+		} else {
+			// ConfigMaps have no status, the rendered ones stand for the written ones
+			for i := range written {
+				replaceObject(live, compConf.InstanceName, &written[i])
 			}
-
-			// host-policy trait
-			injectHostPolicy(&deployment.Spec.Template.Spec, compConf.Traits)
-			// schedule-policy
-			injectSchedulePolicy(ac.Namespace, &deployment.Spec.Template.Spec, compConf.Traits)
-
-			if err := createOrUpdateDeployment(s, ac, compConf.ComponentName, deployment); err != nil {
-				handlerLog.Info("Create or update deployment error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+		}
+		for i := range written {
+			if err := drift.remove(s, ac, &written[i]); err != nil {
+				handlerLog.Info("Remove fields error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "ConfigMap", written[i].Name, "Error", err)
 			}
+		}
 
-			if comp.Spec.WorkloadType == WorkloadTypeServer || comp.Spec.WorkloadType == WorkloadTypeSingletonServer {
-				service := convertService(owner, annotations, compConf, *comp)
-				if err := createOrUpdateService(s, ac, compConf.ComponentName, service); err != nil {
-					handlerLog.Info("Create or update service error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
-				}
-
-				//ingress trait
-				ingress := convertIngress(owner, annotations, compConf.InstanceName, compConf.Traits)
-				if err := createOrUpdateIngress(s, ac, compConf.ComponentName, ingress); err != nil {
-					handlerLog.Info("Create or update ingress error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
-				}
-
-			}
+		renderer, err := resolveWorkloadRenderer(&clusterResolver{Oamclient: s.Oamclient, Reader: s.Reader}, comp.Spec.WorkloadType)
+		if err != nil {
+			handlerLog.Info("Resolve workload type failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "WorkloadType", comp.Spec.WorkloadType, "Error", err)
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, Failed, err.Error())
+			desired.keep(compConf.InstanceName)
+			continue
+		}
+		if renderer == nil {
+			//You could register you own renderer according to workloadType
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, Undefined, fmt.Sprintf(WorkeloadTypeUndefined, comp.Spec.WorkloadType))
+			desired.keep(compConf.InstanceName)
+			continue
+		}
 
-			//auto-scaler trait
-			if comp.Spec.WorkloadType == WorkloadTypeServer || comp.Spec.WorkloadType == WorkloadTypeWorker {
-				//apiVersion = "extensions/v1beta1"
-				apiVersion = "apps/v1"
-				hpa := convertHpa(owner, annotations, "Deployment", apiVersion, compConf.InstanceName, compConf.Traits)
-				if err := createOrUpdateHpa(s, ac, compConf.ComponentName, hpa); err != nil {
-					handlerLog.Info("Create or update hpa error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
-				}
+		// reject the traits which do not apply to the workload type or have invalid properties
+		traits, rejected, err := applicableTraits(s, ac, compConf, comp.Spec.WorkloadType)
+		if err != nil {
+			handlerLog.Info("Get traits error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+			desired.keep(compConf.InstanceName)
+			continue
+		}
+		rejectedTraits = append(rejectedTraits, rejected...)
+		compConf.Traits = traits
 
+		objects, err := renderer.Render(&RenderContext{
+			Owner:                  owner,
+			Namespace:              ac.Namespace,
+			Annotations:            annotations,
+			ApplicationAnnotations: ac.Annotations,
+			ComponentConfiguration: compConf,
+			Component:              *comp,
+			Parameters:             parameterMap,
+			ConfigMaps:             configMaps,
+		})
+		if err != nil {
+			handlerLog.Info("Render workload failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "WorkloadType", comp.Spec.WorkloadType, "Error", err)
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, Failed, err.Error())
+			desired.keep(compConf.InstanceName)
+			continue
+		}
+		if parametersSecret != nil {
+			objects = append(objects, parametersSecret)
+		}
+		annotateConfigHashes(objects, configMaps)
+		if err := completeConnectionSecrets(s, ac.Namespace, objects); err != nil {
+			handlerLog.Info("Complete connection secrets failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, Failed, err.Error())
+			desired.keep(compConf.InstanceName)
+			continue
+		}
+		if rollout := getRollout(compConf); rollout != nil {
+			labelRevision(compConf.InstanceName, objects)
+			var msg string
+			var delay time.Duration
+			objects, msg, delay, err = progressRollout(s, ac, compConf, rollout, objects)
+			if err != nil {
+				handlerLog.Info("Get rollout error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+				desired.keep(compConf.InstanceName)
+				continue
 			}
-
-			//better-auto-scaler trait
-			if comp.Spec.WorkloadType == WorkloadTypeServer || comp.Spec.WorkloadType == WorkloadTypeWorker {
-				apiVersion = "apps/v1"
-				hcHpa := convertHcHpa(owner, annotations, "Deployment", apiVersion, compConf.InstanceName, compConf.Traits)
-				if err := createOrUpdateHcHpa(s, ac, compConf.ComponentName, hcHpa); err != nil {
-					handlerLog.Info("Create or update hcHpa error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+			if msg != "" {
+				rollouts = append(rollouts, msg)
+				if rolloutDelay == 0 || delay < rolloutDelay {
+					rolloutDelay = delay
 				}
 			}
-
-		case WorkloadTypeTask, WorkloadTypeSingletonTask:
-			job := convertJob(owner, annotations, compConf, *comp, parameterMap)
-			var apiVersion string
-
-			//manuel-scaler trait
-			parallelism := *getManuelScale(compConf.Traits)
-			if comp.Spec.WorkloadType == WorkloadTypeSingletonTask {
-				parallelism = 1
-			}
-			job.Spec.Parallelism = &parallelism
-
-			//volume-mounter trait
-			volumes := getVolumesFromVolumeMounters(compConf.Traits)
-			volumes = append(volumes, convertVolumesFromConfig(configMaps)...)
-			job.Spec.Template.Spec.Volumes = volumes
-
-			//log-pilot trait
-			volumes = append(volumes, getLogPilotVolumes(compConf.Traits)...)
-			job.Spec.Template.Spec.Volumes = volumes
-			for i, _ := range job.Spec.Template.Spec.Containers {
-				injectLogPilotConfigs(&job.Spec.Template.Spec.Containers[i], compConf.Traits)
-				// resources-policy
-				injectResourcesPolicy(&job.Spec.Template.Spec.Containers[i], compConf.Traits)
-			}
-
-			// host-policy trait
-			injectHostPolicy(&job.Spec.Template.Spec, compConf.Traits)
-			// schedule-policy
-			injectSchedulePolicy(ac.Namespace, &job.Spec.Template.Spec, compConf.Traits)
-
-			if err := createOrUpdateJob(s, ac, compConf.ComponentName, job); err != nil {
-				handlerLog.Info("Create or update job error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
-			}
-
-			//auto-scaler trait
-			if comp.Spec.WorkloadType == WorkloadTypeTask {
-				apiVersion = "batch/v1"
-				hpa := convertHpa(owner, annotations, "Job", apiVersion, compConf.InstanceName, compConf.Traits)
-				if err := createOrUpdateHpa(s, ac, compConf.ComponentName, hpa); err != nil {
-					handlerLog.Info("Create or update hpa error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
-				}
+		}
+		sortObjects(objects)
+		for _, object := range objects {
+			key, err := getResourceKey(object)
+			if err != nil {
+				handlerLog.Info("Unsupported object rendered.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+				desired.keep(compConf.InstanceName)
+				continue
 			}
-
-			//better-auto-scaler trait
-			if comp.Spec.WorkloadType == WorkloadTypeTask {
-				apiVersion = "batch/v1"
-				hcHpa := convertHcHpa(owner, annotations, "Job", apiVersion, compConf.InstanceName, compConf.Traits)
-				if err := createOrUpdateHcHpa(s, ac, compConf.ComponentName, hcHpa); err != nil {
-					handlerLog.Info("Create or update hcHpa error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
-				}
+			desired.add(key.ApiVersion, key.Kind, key.Name)
+			labelApplication(ac, object)
+			if !drift.apply(s, ac, compConf.InstanceName, object) {
+				continue
 			}
-
-		case WorkloadTypeMysqlCluster:
-			mysqlCluster, mysqlCm, mysqlPvc, err := convertMysqlCluster(owner, compConf, *comp, parameterMap)
+			result, err := createOrUpdateObject(s, ac, compConf.ComponentName, object)
 			if err != nil {
-				handlerLog.Info("Convert configuration for MysqlCluster failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
-			}
-
-			if err := createOrUpdateConfigMap(s, ac, compConf.ComponentName, *mysqlCm); err != nil {
-				handlerLog.Info("Create or update configMap for MysqlCluster failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+				handlerLog.Info("Create or update "+strings.ToLower(key.Kind)+" error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+				continue
 			}
-
-			//volume-mounter trait
-			if err := createOrUpdatePvc(s, ac, compConf.ComponentName, *mysqlPvc); err != nil {
-				handlerLog.Info("Create or update pvc for MysqlCluster failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+			replaceObject(live, compConf.InstanceName, result)
+			if err := drift.remove(s, ac, object); err != nil {
+				handlerLog.Info("Remove fields error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, key.Kind, key.Name, "Error", err)
 			}
+		}
+	}
 
-			//manuel-scaler trait
-			mysqlReplicas := *getManuelScale(compConf.Traits)
-			mysqlCluster.Spec.Replicas = &mysqlReplicas
+	if len(rejectedTraits) > 0 {
+		ac.Status.SetConditionFalse(TraitsApplied, TraitNotApplicable, strings.Join(rejectedTraits, "; "))
+	} else {
+		ac.Status.SetConditionTrue(TraitsApplied, "", "")
+	}
 
-			if err := createOrUpdateMysqlCluster(s, ac, compConf.ComponentName, mysqlCluster); err != nil {
-				handlerLog.Info("Create or update MysqlCluster error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Error", err)
+	if len(dependencyErrs) > 0 {
+		var reason string
+		var messages []string
+		for _, compConf := range components {
+			if e, ok := dependencyErrs[compConf.InstanceName]; ok {
+				reason = e.Reason
+				messages = append(messages, e.Message)
 			}
+		}
+		ac.Status.SetConditionFalse(DependenciesReady, reason, strings.Join(messages, "; "))
+	} else if len(waiting) > 0 {
+		ac.Status.SetConditionFalse(DependenciesReady, Waiting, strings.Join(waiting, "; "))
+	} else {
+		ac.Status.SetConditionTrue(DependenciesReady, "", "")
+	}
 
-		default:
-			//You could launch you own CRD here according to workloadType
-			s.Recorder.Event(ac, apiv1.EventTypeWarning, Undefined, fmt.Sprintf(WorkeloadTypeUndefined, comp.Spec.WorkloadType))
+	// prune resources of removed components and traits, unless the live resources could not be listed
+	if listErr == nil {
+		if err := pruneResources(s, ac, desired, live); err != nil {
+			handlerLog.Info("Prune resources error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Error", err)
 		}
 	}
 
-	// update status
-	if err := updateModuleStatus(s, ac); err != nil {
+	// report the drift of the resources from their last applied rendering
+	if err := updateDriftStatus(s, ac, drift); err != nil {
+		handlerLog.Info("Update drift status error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Error", err)
+	}
+
+	// record the revision rendered, or the revision rolled back to
+	if err := updateRevisionStatus(s, ac, schematics); err != nil {
+		handlerLog.Info("Record revision error.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Error", err)
+	}
+
+	// update status from the objects listed, with the objects written in their place
+	err := listErr
+	if err == nil {
+		err = updateModuleStatus(s, ac, live)
+	}
+	if err != nil {
 		handlerLog.Info("ApplicationConfiguration sync failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 		s.Recorder.Event(ac, apiv1.EventTypeWarning, SyncFailed, fmt.Sprintf(err.Error()))
 	} else {
 		s.Recorder.Event(ac, apiv1.EventTypeNormal, Synced, fmt.Sprintf(SyncSuccessfuly))
 	}
 
+	// requeue until the dependencies are healthy and the rollouts are done, when the next step of a rollout is due
+	if len(waiting) > 0 || len(rollouts) > 0 {
+		delay := requeueInterval(s)
+		if len(rollouts) > 0 && (len(waiting) == 0 || rolloutDelay < delay) {
+			delay = rolloutDelay
+		}
+		requeue(s, ac, delay, append(waiting, rollouts...))
+	}
 	return nil
 }
 
 func createOrUpdateConfigMaps(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, configMaps []apiv1.ConfigMap) error {
 	for _, configmap := range configMaps {
-		if err := createOrUpdateConfigMap(s, applicationConfiguration, component, configmap); err != nil {
+		if _, err := createOrUpdateConfigMap(s, applicationConfiguration, component, configmap); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func createOrUpdateConfigMap(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, configMap apiv1.ConfigMap) error {
+func createOrUpdateConfigMap(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, configMap apiv1.ConfigMap) (*apiv1.ConfigMap, error) {
 	configMapClient := s.K8sclient.CoreV1().ConfigMaps(applicationConfiguration.Namespace)
 	tmpCm, _ := configMapClient.Get(configMap.Name, v1.GetOptions{})
 	applicationConfiguration.GetObjectMeta()
@@ -240,42 +318,65 @@ func createOrUpdateConfigMap(s *ApplicationConfigurationHandler, applicationConf
 		patchDate, _ := json.Marshal(configMap)
 		cmResult, err := configMapClient.Patch(configMap.Name, types.MergePatchType, patchDate)
 		if util.SpecEqual(tmpCm, configMap.Data, false) {
-			return nil
+			return tmpCm, nil
 		}
 		if err != nil {
 			handlerLog.Info("ConfigMap patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, ConfigMapKind, configMap.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, configMap.Name, ConfigMapApiVersion, ConfigMapKind, configMap.Annotations[Instance], configMap.Annotations[Role], PatchFailed)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if cmResult.ResourceVersion != tmpCm.ResourceVersion {
 			handlerLog.Info("ConfigMap patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "ConfigMap", cmResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, apiv1.ResourceConfigMaps, cmResult.Name))
 		}
+		return cmResult, nil
 	} else {
 		cmResult, err := configMapClient.Create(&configMap)
 		if err != nil {
 			handlerLog.Info("ConfigMap create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "ConfigMap", configMap.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, configMap.Name, ConfigMapApiVersion, ConfigMapKind, configMap.Annotations["instance"], configMap.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("ConfigMap created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "ConfigMap", cmResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, apiv1.ResourceConfigMaps, cmResult.Name))
 		}
+		return cmResult, nil
 	}
-	return nil
 }
 
-func createOrUpdatePvcs(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, pvcs []apiv1.PersistentVolumeClaim) error {
-	for _, pvc := range pvcs {
-		if err := createOrUpdatePvc(s, applicationConfiguration, component, pvc); err != nil {
-			return err
+func createOrUpdateSecret(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, secret *apiv1.Secret) (*apiv1.Secret, error) {
+	secretClient := s.K8sclient.CoreV1().Secrets(applicationConfiguration.Namespace)
+	tmpSecret, _ := secretClient.Get(secret.Name, v1.GetOptions{})
+	if v1.IsControlledBy(tmpSecret, applicationConfiguration.GetObjectMeta()) {
+		patchData, _ := json.Marshal(secret)
+		secretResult, err := secretClient.Patch(secret.Name, types.MergePatchType, patchData)
+		if err != nil {
+			handlerLog.Info("Secret patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, SecretKind, secret.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, secret.Name, SecretApiVersion, SecretKind, secret.Annotations[Instance], secret.Annotations[Role], PatchFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else if secretResult.ResourceVersion != tmpSecret.ResourceVersion {
+			handlerLog.Info("Secret patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, SecretKind, secretResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, apiv1.ResourceSecrets, secretResult.Name))
+		}
+		return secretResult, nil
+	} else {
+		secretResult, err := secretClient.Create(secret)
+		if err != nil {
+			handlerLog.Info("Secret create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, SecretKind, secret.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, secret.Name, SecretApiVersion, SecretKind, secret.Annotations[Instance], secret.Annotations[Role], CreateFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else {
+			handlerLog.Info("Secret created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, SecretKind, secretResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, apiv1.ResourceSecrets, secretResult.Name))
 		}
+		return secretResult, nil
 	}
-	return nil
 }
 
-func createOrUpdatePvc(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, pvc apiv1.PersistentVolumeClaim) error {
+func createOrUpdatePvc(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, pvc apiv1.PersistentVolumeClaim) (*apiv1.PersistentVolumeClaim, error) {
 	pvcsClient := s.K8sclient.CoreV1().PersistentVolumeClaims(applicationConfiguration.Namespace)
 
 	tmpPvc, _ := pvcsClient.Get(pvc.Name, v1.GetOptions{})
@@ -286,100 +387,167 @@ func createOrUpdatePvc(s *ApplicationConfigurationHandler, applicationConfigurat
 			handlerLog.Info("ApplicationConfiguration patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "PersistentVolumeClaim", pvc.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, pvc.Name, PvcApiVersion, "PersistentVolumeClaim", pvc.Annotations["instance"], pvc.Annotations["role"], "Patch Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if pvcResult.ResourceVersion != tmpPvc.ResourceVersion {
 			handlerLog.Info("ApplicationConfiguration patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "PersistentVolumeClaim", pvcResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, apiv1.ResourcePersistentVolumeClaims, pvcResult.Name))
 		}
+		return pvcResult, nil
 	} else {
 		pvcResult, err := pvcsClient.Create(&pvc)
 		if err != nil {
 			handlerLog.Info("ApplicationConfiguration create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "PersistentVolumeClaim", pvc.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, pvc.Name, PvcApiVersion, "PersistentVolumeClaim", pvc.Annotations["instance"], pvc.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("ApplicationConfiguration created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, apiv1.ResourcePersistentVolumeClaims.String(), pvcResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, apiv1.ResourcePersistentVolumeClaims, pvcResult.Name))
 		}
+		return pvcResult, nil
 	}
-	return nil
-
-	pvcResult, err := pvcsClient.Create(&pvc)
-	if err != nil {
-		handlerLog.Info("Pvc create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Pvc", pvc.Name, "Error", err)
-		addResourceStatus(&applicationConfiguration.Status.Resources, pvc.Name, PvcApiVersion, "PersistentVolumeClaim", pvc.Annotations["instance"], pvc.Annotations["role"], "Create Failed")
-		s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-		return err
-	} else {
-		handlerLog.Info("Pvc created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Pvc", pvcResult.Name)
-		s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, apiv1.ResourcePersistentVolumeClaims, pvcResult.Name))
-	}
-	return nil
 }
 
-func createOrUpdateDeployment(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, deployment *appsv1.Deployment) error {
+func createOrUpdateDeployment(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
 	if deployment == nil {
-		return nil
+		return nil, nil
 	}
 	deploymentsClient := s.K8sclient.AppsV1().Deployments(applicationConfiguration.Namespace)
 	tmpDeploy, _ := deploymentsClient.Get(deployment.Name, v1.GetOptions{})
 	if v1.IsControlledBy(tmpDeploy, applicationConfiguration.GetObjectMeta()) {
-		for index, c := range applicationConfiguration.Spec.Components {
-			if c.ComponentName == component {
-				for _, t := range applicationConfiguration.Spec.Components[index].Traits {
-					if t.Name == "better-auto-scaler" || t.Name == "auto-scaler" {
-						deployment.Spec.Replicas = tmpDeploy.Spec.Replicas
-						break
-					}
-				}
-				break
-			}
+		// keep the replicas managed by autoscalers, which scale the Deployment of the instance but not its canary
+		if deployment.Name == deployment.Annotations[Instance] && controlsReplicas(applicationConfiguration, deployment.Annotations[Instance]) {
+			deployment.Spec.Replicas = tmpDeploy.Spec.Replicas
 		}
 		patchData, _ := json.Marshal(deployment)
 		deployResult, err := deploymentsClient.Patch(deployment.Name, types.MergePatchType, patchData)
 		if err != nil {
 			handlerLog.Info("Deployment patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Deployment", deployment.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, deployment.Name, DeploymentApiVersion, DeploymentKind, deployment.Annotations[Instance], deployment.Annotations[Role], PatchFailed)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return nil
+			return nil, err
 		} else if deployResult.ResourceVersion != tmpDeploy.ResourceVersion {
 			handlerLog.Info("Deployment patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Deployment", deployResult.Name)
-			addResourceStatus(&applicationConfiguration.Status.Resources, deployment.Name, DeploymentApiVersion, "Deployment", deployment.Annotations["instance"], deployment.Annotations["role"], "Patch Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "deployments", deployResult.Name))
 		}
+		return deployResult, nil
 	} else {
 		deployResult, err := deploymentsClient.Create(deployment)
 		if err != nil {
 			handlerLog.Info("Deployment create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Deployment", deployment.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, deployment.Name, DeploymentApiVersion, "Deployment", deployment.Annotations["instance"], deployment.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("Deployment created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Deployment", deployResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "deployments", deployResult.Name))
 		}
+		return deployResult, nil
 	}
-	return nil
 }
 
-func createOrUpdateJob(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, job *batchv1.Job) error {
+func createOrUpdateDaemonSet(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, daemonSet *appsv1.DaemonSet) (*appsv1.DaemonSet, error) {
+	if daemonSet == nil {
+		return nil, nil
+	}
+	daemonSetsClient := s.K8sclient.AppsV1().DaemonSets(applicationConfiguration.Namespace)
+	tmpDaemonSet, _ := daemonSetsClient.Get(daemonSet.Name, v1.GetOptions{})
+	if v1.IsControlledBy(tmpDaemonSet, applicationConfiguration.GetObjectMeta()) {
+		patchData, _ := json.Marshal(daemonSet)
+		daemonSetResult, err := daemonSetsClient.Patch(daemonSet.Name, types.MergePatchType, patchData)
+		if err != nil {
+			handlerLog.Info("DaemonSet patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "DaemonSet", daemonSet.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, daemonSet.Name, DaemonSetApiVersion, DaemonSetKind, daemonSet.Annotations[Instance], daemonSet.Annotations[Role], PatchFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else if daemonSetResult.ResourceVersion != tmpDaemonSet.ResourceVersion {
+			handlerLog.Info("DaemonSet patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "DaemonSet", daemonSetResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "daemonsets", daemonSetResult.Name))
+		}
+		return daemonSetResult, nil
+	} else {
+		daemonSetResult, err := daemonSetsClient.Create(daemonSet)
+		if err != nil {
+			handlerLog.Info("DaemonSet create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "DaemonSet", daemonSet.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, daemonSet.Name, DaemonSetApiVersion, DaemonSetKind, daemonSet.Annotations[Instance], daemonSet.Annotations[Role], CreateFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else {
+			handlerLog.Info("DaemonSet created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "DaemonSet", daemonSetResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "daemonsets", daemonSetResult.Name))
+		}
+		return daemonSetResult, nil
+	}
+}
+
+func createOrUpdateStatefulSet(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, statefulSet *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+	if statefulSet == nil {
+		return nil, nil
+	}
+	statefulSetsClient := s.K8sclient.AppsV1().StatefulSets(applicationConfiguration.Namespace)
+	tmpStatefulSet, _ := statefulSetsClient.Get(statefulSet.Name, v1.GetOptions{})
+	if v1.IsControlledBy(tmpStatefulSet, applicationConfiguration.GetObjectMeta()) {
+		// keep the replicas managed by autoscalers
+		if controlsReplicas(applicationConfiguration, statefulSet.Annotations[Instance]) {
+			statefulSet.Spec.Replicas = tmpStatefulSet.Spec.Replicas
+		}
+		// the claim templates of a StatefulSet can not be updated
+		statefulSet.Spec.VolumeClaimTemplates = tmpStatefulSet.Spec.VolumeClaimTemplates
+		patchData, _ := json.Marshal(statefulSet)
+		statefulSetResult, err := statefulSetsClient.Patch(statefulSet.Name, types.MergePatchType, patchData)
+		if err != nil {
+			handlerLog.Info("StatefulSet patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "StatefulSet", statefulSet.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, statefulSet.Name, StatefulSetApiVersion, StatefulSetKind, statefulSet.Annotations[Instance], statefulSet.Annotations[Role], PatchFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else if statefulSetResult.ResourceVersion != tmpStatefulSet.ResourceVersion {
+			handlerLog.Info("StatefulSet patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "StatefulSet", statefulSetResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "statefulsets", statefulSetResult.Name))
+		}
+		return statefulSetResult, nil
+	} else {
+		statefulSetResult, err := statefulSetsClient.Create(statefulSet)
+		if err != nil {
+			handlerLog.Info("StatefulSet create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "StatefulSet", statefulSet.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, statefulSet.Name, StatefulSetApiVersion, StatefulSetKind, statefulSet.Annotations[Instance], statefulSet.Annotations[Role], CreateFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else {
+			handlerLog.Info("StatefulSet created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "StatefulSet", statefulSetResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "statefulsets", statefulSetResult.Name))
+		}
+		return statefulSetResult, nil
+	}
+}
+
+func createOrUpdateJob(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, job *batchv1.Job) (*batchv1.Job, error) {
 	if job == nil {
-		return nil
+		return nil, nil
 	}
 	jobsClient := s.K8sclient.BatchV1().Jobs(applicationConfiguration.Namespace)
 
 	tmpJob, _ := jobsClient.Get(job.Name, v1.GetOptions{})
+	liveHash, hashed := tmpJob.Spec.Template.Annotations[ConfigChecksumAnnotation]
+	if v1.IsControlledBy(tmpJob, applicationConfiguration.GetObjectMeta()) && !hashed {
+		// Jobs created before their config was hashed are not run again
+		delete(job.Spec.Template.Annotations, ConfigChecksumAnnotation)
+	} else if v1.IsControlledBy(tmpJob, applicationConfiguration.GetObjectMeta()) && liveHash != job.Spec.Template.Annotations[ConfigChecksumAnnotation] {
+		// the pod template of a Job is immutable, the Job is recreated to run with the changed config
+		propagation := v1.DeletePropagationBackground
+		if err := jobsClient.Delete(job.Name, &v1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+			handlerLog.Info("Job delete failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Job", job.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, job.Name, JobApiVersion, "Job", job.Annotations["instance"], job.Annotations["role"], PatchFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		}
+		handlerLog.Info("Job deleted for its changed config.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Job", job.Name)
+		s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Deleted, fmt.Sprintf(MessageResourceDeleted, "jobs", job.Name))
+		tmpJob = &batchv1.Job{}
+	}
 	if v1.IsControlledBy(tmpJob, applicationConfiguration.GetObjectMeta()) {
-		for index, c := range applicationConfiguration.Spec.Components {
-			if c.ComponentName == component {
-				for _, t := range applicationConfiguration.Spec.Components[index].Traits {
-					if t.Name == "better-auto-scaler" || t.Name == "auto-scaler" {
-						job.Spec.Parallelism = tmpJob.Spec.Parallelism
-						break
-					}
-				}
-				break
-			}
+		// keep the replicas managed by autoscalers
+		if controlsReplicas(applicationConfiguration, job.Annotations[Instance]) {
+			job.Spec.Parallelism = tmpJob.Spec.Parallelism
 		}
 		patchData, _ := json.Marshal(job)
 		jobResult, err := jobsClient.Patch(job.Name, types.MergePatchType, patchData)
@@ -387,62 +555,201 @@ func createOrUpdateJob(s *ApplicationConfigurationHandler, applicationConfigurat
 			handlerLog.Info("Job patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Job", job.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, job.Name, JobApiVersion, "Job", job.Annotations["instance"], job.Annotations["role"], "Patch Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if jobResult.ResourceVersion != tmpJob.ResourceVersion {
 			handlerLog.Info("Job patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Job", jobResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "jobs", jobResult.Name))
 		}
+		return jobResult, nil
 	} else {
 		jobResult, err := jobsClient.Create(job)
 		if err != nil {
 			handlerLog.Info("Job create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Job", job.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, job.Name, JobApiVersion, "Job", job.Annotations["instance"], job.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("Job created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Job", jobResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "jobs", jobResult.Name))
 		}
+		return jobResult, nil
+	}
+}
+
+func createOrUpdateCronJob(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, cronJob *batchv1beta1.CronJob) (*batchv1beta1.CronJob, error) {
+	if cronJob == nil {
+		return nil, nil
+	}
+	cronJobsClient := s.K8sclient.BatchV1beta1().CronJobs(applicationConfiguration.Namespace)
+
+	tmpCronJob, _ := cronJobsClient.Get(cronJob.Name, v1.GetOptions{})
+	if v1.IsControlledBy(tmpCronJob, applicationConfiguration.GetObjectMeta()) {
+		patchData, _ := json.Marshal(cronJob)
+		cronJobResult, err := cronJobsClient.Patch(cronJob.Name, types.MergePatchType, patchData)
+		if err != nil {
+			handlerLog.Info("CronJob patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "CronJob", cronJob.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, cronJob.Name, CronJobApiVersion, CronJobKind, cronJob.Annotations[Instance], cronJob.Annotations[Role], PatchFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else if cronJobResult.ResourceVersion != tmpCronJob.ResourceVersion {
+			handlerLog.Info("CronJob patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "CronJob", cronJobResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "cronjobs", cronJobResult.Name))
+		}
+		return cronJobResult, nil
+	} else {
+		cronJobResult, err := cronJobsClient.Create(cronJob)
+		if err != nil {
+			handlerLog.Info("CronJob create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "CronJob", cronJob.Name, "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, cronJob.Name, CronJobApiVersion, CronJobKind, cronJob.Annotations[Instance], cronJob.Annotations[Role], CreateFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else {
+			handlerLog.Info("CronJob created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "CronJob", cronJobResult.Name)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "cronjobs", cronJobResult.Name))
+		}
+		return cronJobResult, nil
+	}
+}
+
+func createOrUpdateTemplateObject(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	kind := obj.GetKind()
+	client, err := templateObjectClient(s, applicationConfiguration.Namespace, obj)
+	if err != nil {
+		addResourceStatus(&applicationConfiguration.Status.Resources, obj.GetName(), obj.GetAPIVersion(), kind, obj.GetAnnotations()[Instance], obj.GetAnnotations()[Role], CreateFailed)
+		s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+		return nil, err
+	}
+	tmpObj, err := client.Get(obj.GetName(), v1.GetOptions{})
+	if err == nil && v1.IsControlledBy(tmpObj, applicationConfiguration.GetObjectMeta()) {
+		patchData, _ := json.Marshal(obj)
+		result, err := client.Patch(obj.GetName(), types.MergePatchType, patchData, v1.PatchOptions{})
+		if err != nil {
+			handlerLog.Info(kind+" patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, kind, obj.GetName(), "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, obj.GetName(), obj.GetAPIVersion(), kind, obj.GetAnnotations()[Instance], obj.GetAnnotations()[Role], PatchFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else if result.GetResourceVersion() != tmpObj.GetResourceVersion() {
+			handlerLog.Info(kind+" patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, kind, result.GetName())
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, strings.ToLower(kind), result.GetName()))
+		}
+		return result, nil
+	} else {
+		result, err := client.Create(obj, v1.CreateOptions{})
+		if err != nil {
+			handlerLog.Info(kind+" create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, kind, obj.GetName(), "Error", err)
+			addResourceStatus(&applicationConfiguration.Status.Resources, obj.GetName(), obj.GetAPIVersion(), kind, obj.GetAnnotations()[Instance], obj.GetAnnotations()[Role], CreateFailed)
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
+			return nil, err
+		} else {
+			handlerLog.Info(kind+" created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, kind, result.GetName())
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, strings.ToLower(kind), result.GetName()))
+		}
+		return result, nil
 	}
-	return nil
 }
 
-func createOrUpdateMysqlCluster(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, mysqlCluster *hcv1alpha1.MysqlCluster) error {
+func createOrUpdateMysqlCluster(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, mysqlCluster *hcv1alpha1.MysqlCluster) (*hcv1alpha1.MysqlCluster, error) {
 	if mysqlCluster == nil {
-		return nil
+		return nil, nil
 	}
-	mysqlClustersClient := s.Hcclient.HarmonycloudV1alpha1().MysqlClusters(applicationConfiguration.Namespace)
+	mysqlClustersClient := s.Hcclient.MysqlV1alpha1().MysqlClusters(applicationConfiguration.Namespace)
 	tmpMysqlCluster, _ := mysqlClustersClient.Get(nil, mysqlCluster.Name, v1.GetOptions{})
 	if v1.IsControlledBy(tmpMysqlCluster, applicationConfiguration.GetObjectMeta()) {
-		patchData, _ := json.Marshal(mysqlCluster)
+		operations := preserveMysqlOperations(mysqlCluster, tmpMysqlCluster)
+		patchData, _ := mysqlClusterPatch(mysqlCluster)
 		mysqlClusterResult, err := mysqlClustersClient.Patch(nil, mysqlCluster.Name, types.MergePatchType, patchData, v1.PatchOptions{})
 		if err != nil {
 			handlerLog.Info("MysqlCluster patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "MysqlCluster", mysqlCluster.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, mysqlCluster.Name, MysqlClusterApiVersion, "MysqlCluster", mysqlCluster.Annotations["instance"], mysqlCluster.Annotations["role"], "Patch Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if mysqlClusterResult.ResourceVersion != tmpMysqlCluster.ResourceVersion {
 			handlerLog.Info("MysqlCluster patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Deployment", mysqlClusterResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "mysqlclusters", mysqlClusterResult.Name))
 		}
+		for _, key := range operations {
+			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Operation, fmt.Sprintf(MessageOperation, key, mysqlCluster.Annotations[key], mysqlCluster.Name))
+		}
+		return mysqlClusterResult, nil
 	} else {
 		mysqlClusterResult, err := mysqlClustersClient.Create(nil, mysqlCluster, v1.CreateOptions{})
 		if err != nil {
 			handlerLog.Info("MysqlCluster create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "MysqlCluster", mysqlCluster.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, mysqlCluster.Name, MysqlClusterApiVersion, "MysqlCluster", mysqlCluster.Annotations["instance"], mysqlCluster.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("MysqlCluster created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "MysqlCluster", mysqlClusterResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "mysqlclusters", mysqlClusterResult.Name))
 		}
+		return mysqlClusterResult, nil
 	}
-	return nil
 }
 
-func createOrUpdateService(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, service *apiv1.Service) error {
+// preserveMysqlOperations keeps the fields of the live MysqlCluster which are driven by operations and
+// by the mysql operator, unless the rendered MysqlCluster requests a new operation. It returns the
+// annotations of the requested operations.
+func preserveMysqlOperations(mysqlCluster *hcv1alpha1.MysqlCluster, live *hcv1alpha1.MysqlCluster) []string {
+	var operations []string
+	requested := func(key string) bool {
+		value := mysqlCluster.Annotations[key]
+		if value != "" && value != live.Annotations[key] {
+			operations = append(operations, key)
+			return true
+		}
+		return false
+	}
+
+	if !requested(SwitchoverAnnotation) && !requested(ForceSwitchoverAnnotation) {
+		mysqlCluster.Spec.SwitchMaster = live.Spec.SwitchMaster
+	}
+	if mysqlCluster.Annotations[PauseAnnotation] == "" {
+		mysqlCluster.Spec.Paused = live.Spec.Paused
+	} else if mysqlCluster.Spec.Paused != live.Spec.Paused {
+		operations = append(operations, PauseAnnotation)
+	}
+	if !requested(MigrateAnnotation) {
+		mysqlCluster.Spec.ProblemPodName = live.Spec.ProblemPodName
+		mysqlCluster.Spec.TargetNodeName = live.Spec.TargetNodeName
+	}
+	mysqlCluster.Spec.ForceSwitched = live.Spec.ForceSwitched
+	mysqlCluster.Spec.MigratePolicy.Started = live.Spec.MigratePolicy.Started
+	mysqlCluster.Spec.MigratePolicy.Backed = live.Spec.MigratePolicy.Backed
+	mysqlCluster.Spec.MigratePolicy.BackFinished = live.Spec.MigratePolicy.BackFinished
+	return operations
+}
+
+// mysqlClusterPatch marshals the MysqlCluster into a merge patch. The boolean fields driven by operations
+// are always set, so that e.g. a resume or a new switchover is not dropped by omitempty.
+func mysqlClusterPatch(mysqlCluster *hcv1alpha1.MysqlCluster) ([]byte, error) {
+	data, err := json.Marshal(mysqlCluster)
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	spec, _ := patch["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = map[string]interface{}{}
+		patch["spec"] = spec
+	}
+	switchMaster := mysqlCluster.Spec.SwitchMaster
+	spec["paused"] = mysqlCluster.Spec.Paused
+	spec["clusterSwitch"] = map[string]interface{}{
+		"switched":      switchMaster.Switched,
+		"finished":      switchMaster.Finished,
+		"forceSwitched": switchMaster.ForceSwitched,
+		"master":        switchMaster.Master,
+		"switchPod":     switchMaster.SwitchPod,
+	}
+	return json.Marshal(patch)
+}
+
+func createOrUpdateService(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, service *apiv1.Service) (*apiv1.Service, error) {
 	if service == nil {
-		return nil
+		return nil, nil
 	}
 	serviceClient := s.K8sclient.CoreV1().Services(applicationConfiguration.Namespace)
 	tmpsvc, _ := serviceClient.Get(service.Name, v1.GetOptions{})
@@ -453,29 +760,30 @@ func createOrUpdateService(s *ApplicationConfigurationHandler, applicationConfig
 			handlerLog.Info("Service patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Service", service.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, service.Name, MysqlClusterApiVersion, "Service", service.Annotations["instance"], service.Annotations["role"], "Patch Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if svcResult.ResourceVersion != tmpsvc.ResourceVersion {
 			handlerLog.Info("Service patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Service", svcResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, apiv1.ResourceServices, svcResult.Name))
 		}
+		return svcResult, nil
 	} else {
 		svcResult, err := serviceClient.Create(service)
 		if err != nil {
 			handlerLog.Info("Service create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Service", service.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, service.Name, MysqlClusterApiVersion, "Service", service.Annotations["instance"], service.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("Service created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Service", svcResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, apiv1.ResourceServices, svcResult.Name))
 		}
+		return svcResult, nil
 	}
-	return nil
 }
 
-func createOrUpdateIngress(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, ingress *extensionsv1beta1.Ingress) error {
+func createOrUpdateIngress(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, ingress *extensionsv1beta1.Ingress) (*extensionsv1beta1.Ingress, error) {
 	if ingress == nil {
-		return nil
+		return nil, nil
 	}
 	//ingressClient := s.K8sclient.NetworkingV1beta1().Ingresses(namespace)
 	ingressClient := s.K8sclient.ExtensionsV1beta1().Ingresses(applicationConfiguration.Namespace)
@@ -487,30 +795,30 @@ func createOrUpdateIngress(s *ApplicationConfigurationHandler, applicationConfig
 			handlerLog.Info("Ingress patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Ingress", ingress.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, ingress.Name, IngressApiVersion, "Ingress", ingress.Annotations["instance"], ingress.Annotations["role"], "Patch Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if ingResult.ResourceVersion != tmpIng.ResourceVersion {
 			handlerLog.Info("Ingress patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Ingress", ingResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "ingresses", ingResult.Name))
 		}
+		return ingResult, nil
 	} else {
 		ingResult, err := ingressClient.Create(ingress)
 		if err != nil {
 			handlerLog.Info("Ingress create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Ingress", ingress.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, ingress.Name, IngressApiVersion, "Ingress", ingress.Annotations["instance"], ingress.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("Ingress created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Ingress", ingResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "ingresses", ingResult.Name))
 		}
+		return ingResult, nil
 	}
-	return nil
-
 }
 
-func createOrUpdateHpa(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, hpa *v2beta2.HorizontalPodAutoscaler) error {
+func createOrUpdateHpa(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, hpa *v2beta2.HorizontalPodAutoscaler) (*v2beta2.HorizontalPodAutoscaler, error) {
 	if hpa == nil {
-		return nil
+		return nil, nil
 	}
 	hpaClient := s.K8sclient.AutoscalingV2beta2().HorizontalPodAutoscalers(applicationConfiguration.Namespace)
 	tmpHpa, _ := hpaClient.Get(hpa.Name, v1.GetOptions{})
@@ -522,31 +830,32 @@ func createOrUpdateHpa(s *ApplicationConfigurationHandler, applicationConfigurat
 			addResourceStatus(&applicationConfiguration.Status.Resources, hpa.Name, HpaApiVersion, "HorizontalPodAutoscaler", hpa.Annotations["instance"], hpa.Annotations["role"], "Patch Failed")
 
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if hpaResult.ResourceVersion != tmpHpa.ResourceVersion {
 			handlerLog.Info("Hpa patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Hpa", hpaResult.Name)
 			addResourceStatus(&applicationConfiguration.Status.Resources, hpa.Name, HpaApiVersion, "HorizontalPodAutoscaler", hpa.Annotations["instance"], hpa.Annotations["role"], "Patch Succeeded")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "hpas", hpaResult.Name))
 		}
+		return hpaResult, nil
 	} else {
 		hpaResult, err := hpaClient.Create(hpa)
 		if err != nil {
 			handlerLog.Info("Hpa create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Hpa", hpa.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, hpa.Name, HpaApiVersion, "HorizontalPodAutoscaler", hpa.Annotations["instance"], hpa.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("Hpa created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "Hpa", hpaResult.Name)
 			addResourceStatus(&applicationConfiguration.Status.Resources, hpa.Name, HpaApiVersion, "HorizontalPodAutoscaler", hpa.Annotations["instance"], hpa.Annotations["role"], "Create Succeeded")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "hpas", hpaResult.Name))
 		}
+		return hpaResult, nil
 	}
-	return nil
 }
 
-func createOrUpdateHcHpa(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, hcHpa *hcv1beta1.HorizontalPodAutoscaler) error {
+func createOrUpdateHcHpa(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, hcHpa *hcv1beta1.HorizontalPodAutoscaler) (*hcv1beta1.HorizontalPodAutoscaler, error) {
 	if hcHpa == nil {
-		return nil
+		return nil, nil
 	}
 	hcHpaClient := s.Hcclient.HarmonycloudV1beta1().HorizontalPodAutoscalers(applicationConfiguration.Namespace)
 	tmpHcHpa, _ := hcHpaClient.Get(nil, hcHpa.Name, v1.GetOptions{})
@@ -557,116 +866,178 @@ func createOrUpdateHcHpa(s *ApplicationConfigurationHandler, applicationConfigur
 			handlerLog.Info("HcHpa patch failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "HcHpa", hcHpa.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, hcHpa.Name, HcHpaApiVersion, "HorizontalPodAutoscaler", hcHpa.Annotations["instance"], hcHpa.Annotations["role"], "Patch Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else if hcHpaResult.ResourceVersion != tmpHcHpa.ResourceVersion {
 			handlerLog.Info("HcHpa patched.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "HcHpa", hcHpaResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Patched, fmt.Sprintf(MessageResourcePatched, "hchpas", hcHpaResult.Name))
 		}
+		return hcHpaResult, nil
 	} else {
 		hcHpaResult, err := hcHpaClient.Create(nil, hcHpa, v1.CreateOptions{})
 		if err != nil {
 			handlerLog.Info("HcHpa create failed.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "HcHpa", hcHpa.Name, "Error", err)
 			addResourceStatus(&applicationConfiguration.Status.Resources, hcHpa.Name, HcHpaApiVersion, "HorizontalPodAutoscaler", hcHpa.Annotations["instance"], hcHpa.Annotations["role"], "Create Failed")
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeWarning, Failed, err.Error())
-			return err
+			return nil, err
 		} else {
 			handlerLog.Info("HcHpa created.", "Namespace", applicationConfiguration.Namespace, "ApplicationConfiguration", applicationConfiguration.Name, "Component", component, "HcHpa", hcHpaResult.Name)
 			s.Recorder.Event(applicationConfiguration, apiv1.EventTypeNormal, Created, fmt.Sprintf(MessageResourceCreated, "hchpas", hcHpaResult.Name))
 		}
+		return hcHpaResult, nil
 	}
-	return nil
 }
 
-func updateModuleStatus(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+// createOrUpdateObject creates or updates an object rendered by a WorkloadRenderer and returns the object written.
+func createOrUpdateObject(s *ApplicationConfigurationHandler, applicationConfiguration *v1alpha1.ApplicationConfiguration, component string, obj runtime.Object) (runtime.Object, error) {
+	switch o := obj.(type) {
+	case *apiv1.ConfigMap:
+		return createOrUpdateConfigMap(s, applicationConfiguration, component, *o)
+	case *apiv1.Secret:
+		return createOrUpdateSecret(s, applicationConfiguration, component, o)
+	case *apiv1.PersistentVolumeClaim:
+		return createOrUpdatePvc(s, applicationConfiguration, component, *o)
+	case *appsv1.Deployment:
+		return createOrUpdateDeployment(s, applicationConfiguration, component, o)
+	case *appsv1.DaemonSet:
+		return createOrUpdateDaemonSet(s, applicationConfiguration, component, o)
+	case *appsv1.StatefulSet:
+		return createOrUpdateStatefulSet(s, applicationConfiguration, component, o)
+	case *batchv1.Job:
+		return createOrUpdateJob(s, applicationConfiguration, component, o)
+	case *batchv1beta1.CronJob:
+		return createOrUpdateCronJob(s, applicationConfiguration, component, o)
+	case *hcv1alpha1.MysqlCluster:
+		return createOrUpdateMysqlCluster(s, applicationConfiguration, component, o)
+	case *apiv1.Service:
+		return createOrUpdateService(s, applicationConfiguration, component, o)
+	case *extensionsv1beta1.Ingress:
+		return createOrUpdateIngress(s, applicationConfiguration, component, o)
+	case *v2beta2.HorizontalPodAutoscaler:
+		return createOrUpdateHpa(s, applicationConfiguration, component, o)
+	case *hcv1beta1.HorizontalPodAutoscaler:
+		return createOrUpdateHcHpa(s, applicationConfiguration, component, o)
+	case *unstructured.Unstructured:
+		return createOrUpdateTemplateObject(s, applicationConfiguration, component, o)
+	default:
+		return nil, fmt.Errorf("unsupported object %T", obj)
+	}
+}
+
+func getResourceKey(obj runtime.Object) (resourceKey, error) {
+	switch o := obj.(type) {
+	case *apiv1.ConfigMap:
+		return resourceKey{ApiVersion: ConfigMapApiVersion, Kind: ConfigMapKind, Name: o.Name}, nil
+	case *apiv1.Secret:
+		return resourceKey{ApiVersion: SecretApiVersion, Kind: SecretKind, Name: o.Name}, nil
+	case *apiv1.PersistentVolumeClaim:
+		return resourceKey{ApiVersion: PvcApiVersion, Kind: PvcKind, Name: o.Name}, nil
+	case *appsv1.Deployment:
+		return resourceKey{ApiVersion: DeploymentApiVersion, Kind: DeploymentKind, Name: o.Name}, nil
+	case *appsv1.DaemonSet:
+		return resourceKey{ApiVersion: DaemonSetApiVersion, Kind: DaemonSetKind, Name: o.Name}, nil
+	case *appsv1.StatefulSet:
+		return resourceKey{ApiVersion: StatefulSetApiVersion, Kind: StatefulSetKind, Name: o.Name}, nil
+	case *batchv1.Job:
+		return resourceKey{ApiVersion: JobApiVersion, Kind: JobKind, Name: o.Name}, nil
+	case *batchv1beta1.CronJob:
+		return resourceKey{ApiVersion: CronJobApiVersion, Kind: CronJobKind, Name: o.Name}, nil
+	case *hcv1alpha1.MysqlCluster:
+		return resourceKey{ApiVersion: MysqlClusterApiVersion, Kind: MysqlClusterKind, Name: o.Name}, nil
+	case *apiv1.Service:
+		return resourceKey{ApiVersion: ServiceApiVersion, Kind: ServiceKind, Name: o.Name}, nil
+	case *extensionsv1beta1.Ingress:
+		return resourceKey{ApiVersion: IngressApiVersion, Kind: IngressKind, Name: o.Name}, nil
+	case *v2beta2.HorizontalPodAutoscaler:
+		return resourceKey{ApiVersion: HpaApiVersion, Kind: HpaKind, Name: o.Name}, nil
+	case *hcv1beta1.HorizontalPodAutoscaler:
+		return resourceKey{ApiVersion: HcHpaApiVersion, Kind: HcHpaKind, Name: o.Name}, nil
+	case *unstructured.Unstructured:
+		return resourceKey{ApiVersion: o.GetAPIVersion(), Kind: o.GetKind(), Name: o.GetName()}, nil
+	default:
+		return resourceKey{}, fmt.Errorf("unsupported object %T", obj)
+	}
+}
+
+// updateModuleStatus evaluates the health of the instances from their objects and writes the status.
+func updateModuleStatus(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, objects map[string][]runtime.Object) error {
+	previous, err := getModuleConditions(s.Oamclient, ac)
+	if err != nil {
+		return err
+	}
+	moduleConditions := map[string][]acstatus.Condition{}
+	moduleRevisions := map[string]string{}
+	var notReady, progressingMessages, degradedMessages []string
 	for _, compConf := range ac.Spec.Components {
-		status := Unhealthy
-		comp, err := s.Oamclient.CoreV1alpha1().ComponentSchematics(ac.Namespace).Get(compConf.ComponentName, v1.GetOptions{})
+		comp, err := s.getComponentSchematic(ac.Namespace, compConf.ComponentName)
 		if err != nil {
 			return err
 		}
-		var kind string
-		var groupVersion string
-		switch comp.Spec.WorkloadType {
-		case WorkloadTypeServer:
-			kind = ServerKind
-			groupVersion = OamV1alpha1GroupVersion
-		case WorkloadTypeSingletonServer:
-			kind = SingletonServerKind
-			groupVersion = OamV1alpha1GroupVersion
-		case WorkloadTypeWorker:
-			kind = WorkerKind
-			groupVersion = OamV1alpha1GroupVersion
-		case WorkloadTypeSingletonWorker:
-			kind = SingletonWorkerKind
-			groupVersion = OamV1alpha1GroupVersion
-		case WorkloadTypeTask:
-			kind = TaskKind
-			groupVersion = OamV1alpha1GroupVersion
-		case WorkloadTypeSingletonTask:
-			kind = SingletonTaskKind
-			groupVersion = OamV1alpha1GroupVersion
-		case WorkloadTypeMysqlCluster:
-			kind = MysqlClusterKind
-			groupVersion = MysqlClusterGroupVersion
-		default:
+		renderer, err := resolveWorkloadRenderer(&clusterResolver{Oamclient: s.Oamclient, Reader: s.Reader}, comp.Spec.WorkloadType)
+		if err != nil {
+			return err
+		}
+		if renderer == nil {
 			return errors.New("WorkloadType " + comp.Spec.WorkloadType + " is undefined")
 		}
-	resourceLoop:
-		for _, r := range ac.Status.Resources {
-			if compConf.InstanceName != r.Component {
-				continue
-			}
-			if r.Status == PatchFailed || r.Status == CreateFailed {
-				status = Unhealthy
-				break
-			}
-			switch r.Kind {
-			case DeploymentKind:
-				readyStatus := MiddleString(r.Status, "Ready:", " Up-to-date: ")
-				readyCount := MiddleString(readyStatus, " ", "/")
-				replicaCount := MiddleString(readyStatus, "/", ",")
-				if readyCount == "0" || readyCount != replicaCount {
-					status = Unhealthy
-					break resourceLoop
-				} else {
-					status = Healthy
-				}
-			case JobKind:
-				failedCount := MiddleString(r.Status, ", Failed: ", ".")
-				if failedCount != "0" || failedCount != "" {
-					status = Unhealthy
-					break resourceLoop
-				} else {
-					status = Healthy
-				}
-			case HpaKind:
-				currentCount := MiddleString(r.Status, "CurrentReplicas: ", ", DesiredReplicas: ")
-				desiredCount := MiddleString(r.Status, ", DesiredReplicas: ", ".")
-				if currentCount != desiredCount {
-					status = Unhealthy
-					break resourceLoop
-				} else {
-					status = Healthy
-				}
-			case PvcKind:
-				if !strings.HasSuffix(r.Status, "Bound.") {
-					status = Unhealthy
-					break resourceLoop
-				} else {
-					status = Healthy
-				}
-			case ServiceKind, ConfigMapKind, IngressKind:
-				status = Healthy
-			default:
-				return errors.New("Kind " + kind + " is undefined")
-			}
+		kind := renderer.Kind()
+		groupVersion := renderer.GroupVersion()
+		health, err := renderer.Health(ac, compConf.InstanceName, objects[compConf.InstanceName])
+		if err != nil {
+			return err
+		}
+		addModuleStatus(&ac.Status.Modules, compConf.InstanceName, kind, groupVersion, health.String())
+		moduleConditions[compConf.InstanceName] = healthConditions(previous[compConf.InstanceName], health, ac.Generation)
+		moduleRevisions[compConf.InstanceName] = comp.Name + ComponentVersionSeparator + schematicVersion(comp)
+		if !health.Ready {
+			notReady = append(notReady, compConf.InstanceName)
+		}
+		if health.Degraded {
+			degradedMessages = append(degradedMessages, health.Message)
+		} else if health.Progressing {
+			progressingMessages = append(progressingMessages, health.Message)
 		}
-		addModuleStatus(&ac.Status.Modules, compConf.InstanceName, kind, groupVersion, status)
+	}
+
+	if len(notReady) > 0 {
+		reason := ResourcesProgressing
+		if len(degradedMessages) > 0 {
+			reason = ResourcesDegraded
+		}
+		ac.Status.SetConditionFalse(acstatus.Ready, reason, fmt.Sprintf(ComponentsNotReadyMessage, strings.Join(notReady, ", ")))
+	} else {
+		ac.Status.SetConditionTrue(acstatus.Ready, ResourcesReady, "")
+	}
+	if len(progressingMessages) > 0 {
+		ac.Status.SetConditionTrue(acstatus.Progressing, ResourcesProgressing, strings.Join(progressingMessages, "; "))
+	} else {
+		ac.Status.SetConditionFalse(acstatus.Progressing, "", "")
+	}
+	if len(degradedMessages) > 0 {
+		ac.Status.SetConditionTrue(acstatus.Degraded, ResourcesDegraded, strings.Join(degradedMessages, "; "))
+	} else {
+		ac.Status.SetConditionFalse(acstatus.Degraded, "", "")
 	}
 	ac.Status.Phase = Synced
-	ac, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).UpdateStatus(ac)
-	if err != nil {
-		return err
+	return patchStatus(s.Oamclient, ac, moduleConditions, moduleRevisions)
+}
+
+// healthConditions returns the Ready, Progressing and Degraded conditions of a module.
+func healthConditions(conditions []acstatus.Condition, health Health, generation int64) []acstatus.Condition {
+	condition := func(t v1alpha1.ApplicationConditionType, value bool) acstatus.Condition {
+		c := acstatus.Condition{Type: t, Status: apiv1.ConditionFalse, ObservedGeneration: generation}
+		if value {
+			c.Status = apiv1.ConditionTrue
+		}
+		// the reason is given by the true conditions and by Ready
+		if value || t == acstatus.Ready {
+			c.Reason = health.Reason
+			c.Message = health.Message
+		}
+		return c
 	}
-	return nil
+	conditions = append([]acstatus.Condition(nil), conditions...)
+	conditions = acstatus.SetCondition(conditions, condition(acstatus.Ready, health.Ready))
+	conditions = acstatus.SetCondition(conditions, condition(acstatus.Progressing, health.Progressing))
+	conditions = acstatus.SetCondition(conditions, condition(acstatus.Degraded, health.Degraded))
+	return conditions
 }
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPatchFailed(t *testing.T) {
+	tests := []struct {
+		kind     string
+		existing func(meta v1.ObjectMeta) runtime.Object
+		write    func(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error
+	}{
+		{
+			kind:     DeploymentKind,
+			existing: func(meta v1.ObjectMeta) runtime.Object { return &appsv1.Deployment{ObjectMeta: meta} },
+			write: func(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+				_, err := createOrUpdateDeployment(s, ac, "web", &appsv1.Deployment{ObjectMeta: ownedObjectMeta(ac, "web", "web")})
+				return err
+			},
+		},
+		{
+			kind:     DaemonSetKind,
+			existing: func(meta v1.ObjectMeta) runtime.Object { return &appsv1.DaemonSet{ObjectMeta: meta} },
+			write: func(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+				_, err := createOrUpdateDaemonSet(s, ac, "web", &appsv1.DaemonSet{ObjectMeta: ownedObjectMeta(ac, "web", "web")})
+				return err
+			},
+		},
+		{
+			kind:     StatefulSetKind,
+			existing: func(meta v1.ObjectMeta) runtime.Object { return &appsv1.StatefulSet{ObjectMeta: meta} },
+			write: func(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+				_, err := createOrUpdateStatefulSet(s, ac, "web", &appsv1.StatefulSet{ObjectMeta: ownedObjectMeta(ac, "web", "web")})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+			s, k8sclient, _ := newTestHandler(nil, []runtime.Object{tt.existing(ownedObjectMeta(ac, "web", "web"))}, nil)
+			k8sclient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.New("admission denied")
+			})
+
+			if err := tt.write(s, ac); err == nil {
+				t.Fatal("error = nil, want the error of the patch")
+			}
+			var status string
+			for _, r := range ac.Status.Resources {
+				if r.Kind == tt.kind && r.NamespacedName == "web" {
+					status = r.Status
+				}
+			}
+			if status != PatchFailed {
+				t.Errorf("status of %s web = %q, want %q", tt.kind, status, PatchFailed)
+			}
+		})
+	}
+}
+
+func TestJobConfigChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		liveHash   string
+		hash       string
+		wantVerbs  []string
+		wantHashed bool
+	}{
+		{name: "same config", liveHash: "a", hash: "a", wantVerbs: []string{"get", "patch"}, wantHashed: true},
+		{name: "changed config", liveHash: "a", hash: "b", wantVerbs: []string{"get", "delete", "create"}, wantHashed: true},
+		// Jobs created before their config was hashed are not run again
+		{name: "not hashed", hash: "b", wantVerbs: []string{"get", "patch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "migrate", InstanceName: "migrate"})
+			live := &batchv1.Job{ObjectMeta: ownedObjectMeta(ac, "migrate", "migrate")}
+			if tt.liveHash != "" {
+				live.Spec.Template.Annotations = map[string]string{ConfigChecksumAnnotation: tt.liveHash}
+			}
+			s, k8sclient, _ := newTestHandler(nil, []runtime.Object{live}, nil)
+			k8sclient.ClearActions()
+
+			job := &batchv1.Job{ObjectMeta: ownedObjectMeta(ac, "migrate", "migrate")}
+			job.Spec.Template.Annotations = map[string]string{ConfigChecksumAnnotation: tt.hash}
+			if _, err := createOrUpdateJob(s, ac, "migrate", job); err != nil {
+				t.Fatalf("createOrUpdateJob() error = %v", err)
+			}
+			var verbs []string
+			for _, action := range k8sclient.Actions() {
+				verbs = append(verbs, action.GetVerb())
+			}
+			if !reflect.DeepEqual(verbs, tt.wantVerbs) {
+				t.Errorf("verbs = %v, want %v", verbs, tt.wantVerbs)
+			}
+			if _, hashed := job.Spec.Template.Annotations[ConfigChecksumAnnotation]; hashed != tt.wantHashed {
+				t.Errorf("pod template annotations = %v, want the hash %v", job.Spec.Template.Annotations, tt.wantHashed)
+			}
+		})
+	}
+}
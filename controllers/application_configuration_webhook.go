@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	ValidateApplicationConfigurationPath = "/validate-applicationconfiguration"
+	MutateApplicationConfigurationPath   = "/mutate-applicationconfiguration"
+)
+
+var (
+	webhookLog = ctrl.Log.WithName("webhook")
+)
+
+// ApplicationConfigurationValidator rejects ApplicationConfigurations which can not be rendered.
+type ApplicationConfigurationValidator struct {
+	Oamclient *versioned.Clientset
+	// K8sclient reads the pinned versions of ComponentSchematics
+	K8sclient *kubernetes.Clientset
+	decoder   *admission.Decoder
+}
+
+// ApplicationConfigurationDefaulter applies the defaults of the parameters of the ComponentSchematics
+// and of the properties of the Traits to ApplicationConfigurations.
+type ApplicationConfigurationDefaulter struct {
+	Oamclient *versioned.Clientset
+	K8sclient *kubernetes.Clientset
+	decoder   *admission.Decoder
+}
+
+func (v *ApplicationConfigurationValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ac := new(v1alpha1.ApplicationConfiguration)
+	if err := v.decoder.Decode(req, ac); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	errs, err := validateApplicationConfiguration(v.Oamclient, v.K8sclient, ac)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(errs) > 0 {
+		webhookLog.Info("ApplicationConfiguration denied.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Errors", errs)
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+	return admission.Allowed("")
+}
+
+func (v *ApplicationConfigurationValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (d *ApplicationConfigurationDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ac := new(v1alpha1.ApplicationConfiguration)
+	if err := d.decoder.Decode(req, ac); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := defaultApplicationConfiguration(d.Oamclient, d.K8sclient, ac); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	marshaled, err := json.Marshal(ac)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+func (d *ApplicationConfigurationDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// validateApplicationConfiguration returns the reasons why the ApplicationConfiguration can not be rendered.
+func validateApplicationConfiguration(oamclient versioned.Interface, k8sclient kubernetes.Interface, ac *v1alpha1.ApplicationConfiguration) ([]string, error) {
+	var errs []string
+	instances := map[string]bool{}
+	_, dependencyErrs := orderComponents(ac.Spec.Components)
+	for _, compConf := range ac.Spec.Components {
+		if e, ok := dependencyErrs[compConf.InstanceName]; ok {
+			errs = append(errs, e.Message)
+		}
+		if msgs := validation.IsDNS1123Label(compConf.InstanceName); len(msgs) > 0 {
+			errs = append(errs, fmt.Sprintf("instanceName %q is invalid: %s", compConf.InstanceName, strings.Join(msgs, ", ")))
+		}
+		if instances[compConf.InstanceName] {
+			errs = append(errs, fmt.Sprintf("instanceName %q is duplicated", compConf.InstanceName))
+		}
+		instances[compConf.InstanceName] = true
+
+		comp, err := resolveComponentSchematic(&clusterResolver{Oamclient: oamclient}, k8sclient, ac.Namespace, compConf.ComponentName)
+		if apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Sprintf(ComponentNotFound, compConf.ComponentName))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		declared := map[string]bool{}
+		for _, p := range comp.Spec.Parameters {
+			declared[p.Name] = true
+		}
+		for _, p := range compConf.ParameterValues {
+			if !declared[p.Name] {
+				errs = append(errs, fmt.Sprintf("parameter %s is not declared by ComponentSchematic %s", p.Name, comp.Name))
+			}
+		}
+		parameterMap, err := parseParameters(comp.Spec.Parameters, compConf.ParameterValues, ac.Spec.Variables)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf(ParametersInvalidMessage, compConf.InstanceName, err.Error()))
+		} else if msgs, err := validateConnectionReferences(oamclient, k8sclient, ac, parameterMap); err != nil {
+			return nil, err
+		} else {
+			for _, msg := range msgs {
+				errs = append(errs, fmt.Sprintf(ParametersInvalidMessage, compConf.InstanceName, msg))
+			}
+		}
+
+		for _, tr := range compConf.Traits {
+			if getTraitHandler(tr.Name) == nil {
+				errs = append(errs, fmt.Sprintf(TraitUndefined, tr.Name, compConf.InstanceName))
+				continue
+			}
+			trait, err := oamclient.CoreV1alpha1().Traits("").Get(getTraitName(tr.Name), v1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Sprintf(TraitNotFound, tr.Name, compConf.InstanceName))
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			if !traitAppliesTo(trait, comp.Spec.WorkloadType) {
+				errs = append(errs, fmt.Sprintf(TraitNotApplicableMessage, tr.Name, comp.Spec.WorkloadType, compConf.InstanceName))
+				continue
+			}
+			if _, err := validateTraitProperties(trait, tr); err != nil {
+				errs = append(errs, fmt.Sprintf(TraitInvalidMessage, tr.Name, compConf.InstanceName, err.Error()))
+			}
+		}
+	}
+	if policy, ok := ac.Annotations[DriftPolicyAnnotation]; ok && !validDriftPolicy(policy) {
+		errs = append(errs, fmt.Sprintf(DriftPolicyInvalidMessage, policy))
+	}
+	return errs, nil
+}
+
+// validateConnectionReferences checks that the connection references of the parameters refer to
+// MysqlCluster instances of the ApplicationConfiguration, which publish the connection Secrets.
+func validateConnectionReferences(oamclient versioned.Interface, k8sclient kubernetes.Interface, ac *v1alpha1.ApplicationConfiguration, parameterMap map[string]string) ([]string, error) {
+	var errs []string
+	for name, value := range parameterMap {
+		if !isConnectionReference(value) {
+			continue
+		}
+		instance, _, err := parseConnectionReference(value)
+		if err != nil {
+			continue
+		}
+		var found *v1alpha1.ComponentConfiguration
+		for i := range ac.Spec.Components {
+			if ac.Spec.Components[i].InstanceName == instance {
+				found = &ac.Spec.Components[i]
+			}
+		}
+		if found == nil {
+			errs = append(errs, fmt.Sprintf("parameter %s refers to instance %s which is not in the ApplicationConfiguration", name, instance))
+			continue
+		}
+		comp, err := resolveComponentSchematic(&clusterResolver{Oamclient: oamclient}, k8sclient, ac.Namespace, found.ComponentName)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if comp.Spec.WorkloadType != WorkloadTypeMysqlCluster {
+			errs = append(errs, fmt.Sprintf("parameter %s refers to instance %s which publishes no connection, only %s does", name, instance, WorkloadTypeMysqlCluster))
+		}
+	}
+	sort.Strings(errs)
+	return errs, nil
+}
+
+// defaultApplicationConfiguration sets the missing parameter values to the defaults of the ComponentSchematics,
+// and the missing trait properties to the defaults of the Traits.
+func defaultApplicationConfiguration(oamclient versioned.Interface, k8sclient kubernetes.Interface, ac *v1alpha1.ApplicationConfiguration) error {
+	for i := range ac.Spec.Components {
+		compConf := &ac.Spec.Components[i]
+		comp, err := resolveComponentSchematic(&clusterResolver{Oamclient: oamclient}, k8sclient, ac.Namespace, compConf.ComponentName)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		values := map[string]bool{}
+		for _, p := range compConf.ParameterValues {
+			values[p.Name] = true
+		}
+		for _, p := range comp.Spec.Parameters {
+			if !values[p.Name] && p.Default != "" {
+				compConf.ParameterValues = append(compConf.ParameterValues, v1alpha1.ParameterValue{Name: p.Name, Value: p.Default})
+			}
+		}
+
+		for j, tr := range compConf.Traits {
+			trait, err := oamclient.CoreV1alpha1().Traits("").Get(getTraitName(tr.Name), v1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			// invalid properties are rejected by the validating webhook
+			if defaulted, err := validateTraitProperties(trait, tr); err == nil {
+				compConf.Traits[j] = defaulted
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+// newWebhookClients returns the fake clients of the webhooks, seeded with a web ComponentSchematic of a Server,
+// a db ComponentSchematic of a MysqlCluster and the manual-scaler Trait, which applies to Servers.
+func newWebhookClients() (*oamfake.Clientset, *k8sfake.Clientset) {
+	web := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeServer,
+			Parameters: []v1alpha1.Parameter{
+				{Name: "port", ParameterType: v1alpha1.Number, Default: "80"},
+				{Name: "database", ParameterType: v1alpha1.String},
+			},
+			Containers: []v1alpha1.Container{{Name: "web", Image: "nginx:1"}},
+		},
+	}
+	db := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       v1alpha1.ComponentSpec{WorkloadType: WorkloadTypeMysqlCluster},
+	}
+	scaler := &v1alpha1.Trait{
+		ObjectMeta: v1.ObjectMeta{Name: TraitManualScaler},
+		Spec: v1alpha1.TraitSpec{
+			AppliesTo:  []string{WorkloadTypeServer},
+			Properties: `{"type": "object", "properties": {"replicaCount": {"type": "integer", "minimum": 0, "default": 1}}}`,
+		},
+	}
+	return oamfake.NewSimpleClientset(web, db, scaler), k8sfake.NewSimpleClientset()
+}
+
+func TestValidateApplicationConfiguration(t *testing.T) {
+	RegisterBuiltins()
+	web := func(instance string, params ...v1alpha1.ParameterValue) v1alpha1.ComponentConfiguration {
+		return v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: instance, ParameterValues: params}
+	}
+	trait := func(name, properties string) v1alpha1.TraitBinding {
+		return v1alpha1.TraitBinding{Name: name, Properties: runtime.RawExtension{Raw: []byte(properties)}}
+	}
+	tests := []struct {
+		name        string
+		components  []v1alpha1.ComponentConfiguration
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:       "valid",
+			components: []v1alpha1.ComponentConfiguration{web("web", v1alpha1.ParameterValue{Name: "port", Value: "8080"})},
+		},
+		{
+			name:       "invalid and duplicated instance names",
+			components: []v1alpha1.ComponentConfiguration{web("Web"), web("web"), web("web")},
+			want:       []string{`instanceName "Web" is invalid`, `instanceName "web" is duplicated`},
+		},
+		{
+			name:       "component not found",
+			components: []v1alpha1.ComponentConfiguration{{ComponentName: "api", InstanceName: "api"}},
+			want:       []string{"ComponentSchematic api not found"},
+		},
+		{
+			name:       "undeclared and invalid parameters",
+			components: []v1alpha1.ComponentConfiguration{web("web", v1alpha1.ParameterValue{Name: "host", Value: "a"}, v1alpha1.ParameterValue{Name: "port", Value: "http"})},
+			want:       []string{"parameter host is not declared by ComponentSchematic web", "Parameters of component web are invalid"},
+		},
+		{
+			name: "connection reference to an instance without connection",
+			components: []v1alpha1.ComponentConfiguration{
+				web("web", v1alpha1.ParameterValue{Name: "database", Value: "[fromConnection(api,host)]"}),
+				web("api"),
+			},
+			want: []string{"parameter database refers to instance api which publishes no connection"},
+		},
+		{
+			name: "connection reference to a MysqlCluster",
+			components: []v1alpha1.ComponentConfiguration{
+				web("web", v1alpha1.ParameterValue{Name: "database", Value: "[fromConnection(db,host)]"}),
+				{ComponentName: "db", InstanceName: "db"},
+			},
+		},
+		{
+			name: "undefined, missing, inapplicable and invalid traits",
+			components: []v1alpha1.ComponentConfiguration{{
+				ComponentName: "web",
+				InstanceName:  "web",
+				Traits: []v1alpha1.TraitBinding{
+					trait("unknown", `{}`),
+					trait(TraitAutoScaler, `{}`),
+					trait(TraitManualScaler, `{"replicaCount": -1}`),
+				},
+			}, {
+				ComponentName: "db",
+				InstanceName:  "db",
+				Traits:        []v1alpha1.TraitBinding{trait(TraitManualScaler, `{}`)},
+			}},
+			want: []string{
+				"Trait unknown of component web is undefined",
+				"Trait " + TraitAutoScaler + " of component web not found",
+				"Properties of trait " + TraitManualScaler + " of component web are invalid",
+				"Trait " + TraitManualScaler + " does not apply to workload type " + WorkloadTypeMysqlCluster + " of component db",
+			},
+		},
+		{
+			name: "missing dependency",
+			components: []v1alpha1.ComponentConfiguration{{
+				ComponentName: "web",
+				InstanceName:  "web",
+				Traits:        []v1alpha1.TraitBinding{trait(TraitDependsOn, `{"instances": ["api"]}`)},
+			}},
+			want: []string{"web depends on api", "Trait " + TraitDependsOn + " of component web not found"},
+		},
+		{
+			name:        "invalid drift policy",
+			components:  []v1alpha1.ComponentConfiguration{web("web")},
+			annotations: map[string]string{DriftPolicyAnnotation: "Revert"},
+			want:        []string{`drift policy "Revert" is invalid`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oamclient, k8sclient := newWebhookClients()
+			ac := newTestApplicationConfiguration(tt.components...)
+			for k, v := range tt.annotations {
+				ac.Annotations[k] = v
+			}
+			errs, err := validateApplicationConfiguration(oamclient, k8sclient, ac)
+			if err != nil {
+				t.Fatalf("validateApplicationConfiguration() error = %v", err)
+			}
+			if len(errs) != len(tt.want) {
+				t.Fatalf("validateApplicationConfiguration() = %q, want %q", errs, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(errs[i], want) {
+					t.Errorf("validateApplicationConfiguration()[%d] = %q, want %q", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultApplicationConfiguration(t *testing.T) {
+	RegisterBuiltins()
+	oamclient, k8sclient := newWebhookClients()
+	ac := newTestApplicationConfiguration(
+		v1alpha1.ComponentConfiguration{
+			ComponentName:   "web",
+			InstanceName:    "web",
+			ParameterValues: []v1alpha1.ParameterValue{{Name: "database", Value: "db"}},
+			Traits:          []v1alpha1.TraitBinding{{Name: TraitManualScaler, Properties: runtime.RawExtension{Raw: []byte(`{}`)}}},
+		},
+		v1alpha1.ComponentConfiguration{ComponentName: "api", InstanceName: "api"},
+	)
+	if err := defaultApplicationConfiguration(oamclient, k8sclient, ac); err != nil {
+		t.Fatalf("defaultApplicationConfiguration() error = %v", err)
+	}
+
+	wantValues := []v1alpha1.ParameterValue{{Name: "database", Value: "db"}, {Name: "port", Value: "80"}}
+	if got := ac.Spec.Components[0].ParameterValues; !reflect.DeepEqual(got, wantValues) {
+		t.Errorf("parameter values = %v, want %v", got, wantValues)
+	}
+	if got := string(ac.Spec.Components[0].Traits[0].Properties.Raw); got != `{"replicaCount":1}` {
+		t.Errorf("trait properties = %s, want the default replicaCount", got)
+	}
+	// components not found are left to the validating webhook
+	if got := ac.Spec.Components[1].ParameterValues; got != nil {
+		t.Errorf("parameter values of a missing component = %v, want none", got)
+	}
+}
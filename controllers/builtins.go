@@ -0,0 +1,31 @@
+package controllers
+
+// RegisterBuiltins registers the renderers of the supported workload types and the handlers of the supported traits.
+// Register your own renderer or handler to support a new workload type or trait.
+func RegisterBuiltins() {
+	RegisterWorkload(WorkloadTypeServer, &DeploymentRenderer{WorkloadKind: ServerKind, Service: true})
+	RegisterWorkload(WorkloadTypeSingletonServer, &DeploymentRenderer{WorkloadKind: SingletonServerKind, Singleton: true, Service: true})
+	RegisterWorkload(WorkloadTypeWorker, &DeploymentRenderer{WorkloadKind: WorkerKind})
+	RegisterWorkload(WorkloadTypeSingletonWorker, &DeploymentRenderer{WorkloadKind: SingletonWorkerKind, Singleton: true})
+	RegisterWorkload(WorkloadTypeTask, &JobRenderer{WorkloadKind: TaskKind})
+	RegisterWorkload(WorkloadTypeSingletonTask, &JobRenderer{WorkloadKind: SingletonTaskKind, Singleton: true})
+	RegisterWorkload(WorkloadTypeMysqlCluster, &MysqlClusterRenderer{})
+	RegisterWorkload(WorkloadTypeDaemonWorker, &DaemonSetRenderer{})
+	RegisterWorkload(WorkloadTypeStatefulServer, &StatefulSetRenderer{})
+	RegisterWorkload(WorkloadTypeScheduledTask, &CronJobRenderer{})
+
+	// the workload types a trait applies to are read from spec.appliesTo of the Trait
+	RegisterTrait(TraitManualScaler, &ManualScalerTrait{})
+	RegisterTrait(TraitVolumeMounter, &VolumeMounterTrait{})
+	RegisterTrait(TraitAutoScaler, &AutoScalerTrait{})
+	RegisterTrait(TraitBetterAutoScaler, &BetterAutoScalerTrait{})
+	RegisterTrait(TraitIngress, &IngressTrait{}, TraitNginxIngress)
+	RegisterTrait(TraitLogPilot, &LogPilotTrait{})
+	RegisterTrait(TraitHostPolicy, &HostPolicyTrait{})
+	RegisterTrait(TraitResourcesPolicy, &ResourcesPolicyTrait{})
+	RegisterTrait(TraitSchedulePolicy, &SchedulePolicyTrait{})
+	RegisterTrait(TraitDependsOn, &DependsOnTrait{})
+	RegisterTrait(TraitRetentionPolicy, &RetentionPolicyTrait{})
+	RegisterTrait(TraitMysqlBackup, &MysqlBackupTrait{})
+	RegisterTrait(TraitRollout, &RolloutTrait{})
+}
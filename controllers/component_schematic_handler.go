@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var (
+	schematicLog = ctrl.Log.WithName("component-schematic-handler")
+)
+
+// Handle records a revision of every version of the ComponentSchematic, and enqueues the ApplicationConfigurations
+// which refer to it without a version so that they render the new version.
+func (s *ComponentSchematicHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
+	comp, ok := obj.(*v1alpha1.ComponentSchematic)
+	if !ok {
+		return errors.New("type mismatch")
+	}
+	if eType == oam.Delete {
+		// the revisions are deleted with the ComponentSchematic by the garbage collector
+		return nil
+	}
+	version := schematicVersion(comp)
+	acs, err := referringApplicationConfigurations(s.Reader, s.Oamclient, comp.Namespace, ComponentSchematicIndex, comp.Name)
+	if err != nil {
+		return err
+	}
+	pinned := map[string]bool{}
+	var floating []v1alpha1.ApplicationConfiguration
+	for _, ac := range acs {
+		float := false
+		for _, compConf := range ac.Spec.Components {
+			name, v := splitComponentName(compConf.ComponentName)
+			if name != comp.Name {
+				continue
+			}
+			if v != "" {
+				pinned[v] = true
+			} else {
+				float = true
+			}
+		}
+		if float {
+			floating = append(floating, ac)
+		}
+	}
+
+	if err := recordSchematicRevision(s, comp, version, pinned); err != nil {
+		schematicLog.Info("Record revision failed.", "Namespace", comp.Namespace, "ComponentSchematic", comp.Name, "Error", err)
+		return err
+	}
+
+	// the annotation is written once per version, the update reconciles the ApplicationConfiguration
+	changed := comp.Name + ComponentVersionSeparator + version
+	for _, ac := range floating {
+		if ac.Annotations[SchematicChangedAnnotation] == changed || ac.DeletionTimestamp != nil {
+			continue
+		}
+		s.Enqueuer.Enqueue(EnqueueRequest{
+			Namespace:  ac.Namespace,
+			Name:       ac.Name,
+			Annotation: SchematicChangedAnnotation,
+			Value:      changed,
+			Reason:     SchematicChanged,
+			Message:    fmt.Sprintf(MessageSchematicChanged, comp.Name, version),
+		})
+	}
+	return nil
+}
+
+// splitComponentName splits the componentName <name>@<version> of a component configuration,
+// the version is empty if the ComponentSchematic is not pinned.
+func splitComponentName(componentName string) (string, string) {
+	i := strings.LastIndex(componentName, ComponentVersionSeparator)
+	if i < 0 {
+		return componentName, ""
+	}
+	return componentName[:i], componentName[i+1:]
+}
+
+// schematicVersion returns the version of the ComponentSchematic, the hash of its spec and annotations.
+func schematicVersion(comp *v1alpha1.ComponentSchematic) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"annotations": schematicAnnotations(comp),
+		"spec":        comp.Spec,
+	})
+	return contentHash(string(data))
+}
+
+// schematicAnnotations returns the annotations of the ComponentSchematic without the one written by kubectl apply.
+func schematicAnnotations(comp *v1alpha1.ComponentSchematic) map[string]string {
+	var annotations map[string]string
+	for k, v := range comp.Annotations {
+		if k == apiv1.LastAppliedConfigAnnotation {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// resolveComponentSchematic returns the ComponentSchematic a componentName refers to. A pinned version is read
+// from the ComponentSchematic if it is current, or from its revision <name>-<version>, which needs the k8sclient.
+func resolveComponentSchematic(resolver Resolver, k8sclient kubernetes.Interface, namespace, componentName string) (*v1alpha1.ComponentSchematic, error) {
+	name, version := splitComponentName(componentName)
+	comp, err := resolver.GetComponentSchematic(namespace, name)
+	if version == "" {
+		return comp, err
+	}
+	if err == nil && schematicVersion(comp) == version {
+		return comp, nil
+	} else if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	notFound := apierrors.NewNotFound(v1alpha1.Resource("componentschematics"), componentName)
+	if k8sclient == nil {
+		return nil, notFound
+	}
+	revision, err := k8sclient.AppsV1().ControllerRevisions(namespace).Get(name+"-"+version, v1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && revision.Labels[SchematicLabel] != name) {
+		return nil, notFound
+	} else if err != nil {
+		return nil, err
+	}
+	comp = new(v1alpha1.ComponentSchematic)
+	if err := json.Unmarshal(revision.Data.Raw, comp); err != nil {
+		return nil, fmt.Errorf("invalid revision %s of ComponentSchematic %s: %v", version, name, err)
+	}
+	comp.Namespace = namespace
+	return comp, nil
+}
+
+// recordSchematicRevision records the version of the ComponentSchematic by a ControllerRevision <name>-<version>.
+// Revisions beyond the history limit are deleted, the oldest first, except the pinned versions.
+func recordSchematicRevision(s *ComponentSchematicHandler, comp *v1alpha1.ComponentSchematic, version string, pinned map[string]bool) error {
+	client := s.K8sclient.AppsV1().ControllerRevisions(comp.Namespace)
+	selector := labels.SelectorFromSet(labels.Set{SchematicLabel: comp.Name}).String()
+	list, err := client.List(v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+	var revisions []appsv1.ControllerRevision
+	for _, r := range list.Items {
+		if v1.IsControlledBy(&r, comp.GetObjectMeta()) {
+			revisions = append(revisions, r)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+
+	name := comp.Name + "-" + version
+	var next int64 = 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+	var recorded *appsv1.ControllerRevision
+	for i := range revisions {
+		if revisions[i].Name == name {
+			recorded = &revisions[i]
+		}
+	}
+	if recorded != nil && recorded.Revision != next-1 {
+		recorded.Revision = next
+		if _, err := client.Update(recorded); err != nil {
+			return err
+		}
+	} else if recorded == nil {
+		data, err := json.Marshal(v1alpha1.ComponentSchematic{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        comp.Name,
+				Annotations: schematicAnnotations(comp),
+			},
+			Spec: comp.Spec,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = client.Create(&appsv1.ControllerRevision{
+			ObjectMeta: v1.ObjectMeta{
+				Name:            name,
+				Labels:          map[string]string{SchematicLabel: comp.Name},
+				OwnerReferences: []v1.OwnerReference{*v1.NewControllerRef(comp, v1alpha1.SchemeGroupVersion.WithKind("ComponentSchematic"))},
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: next,
+		})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		revisions = append(revisions, appsv1.ControllerRevision{ObjectMeta: v1.ObjectMeta{Name: name}, Revision: next})
+	}
+
+	limit := s.RevisionHistoryLimit
+	if limit <= 0 {
+		limit = DefaultRevisionHistoryLimit
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	for i := 0; len(revisions)-i > limit; i++ {
+		if revisions[i].Name == name || pinned[strings.TrimPrefix(revisions[i].Name, comp.Name+"-")] {
+			continue
+		}
+		if err := client.Delete(revisions[i].Name, &v1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const ValidateComponentSchematicPath = "/validate-componentschematic"
+
+// ComponentSchematicValidator rejects ComponentSchematics which can not be rendered.
+type ComponentSchematicValidator struct {
+	Oamclient *versioned.Clientset
+	decoder   *admission.Decoder
+}
+
+func (v *ComponentSchematicValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	comp := new(v1alpha1.ComponentSchematic)
+	if err := v.decoder.Decode(req, comp); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if errs := validateComponentSchematic(&clusterResolver{Oamclient: v.Oamclient}, comp); len(errs) > 0 {
+		webhookLog.Info("ComponentSchematic denied.", "Namespace", comp.Namespace, "ComponentSchematic", comp.Name, "Errors", errs)
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+	return admission.Allowed("")
+}
+
+func (v *ComponentSchematicValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// validateComponentSchematic returns the reasons why the ComponentSchematic can not be rendered.
+func validateComponentSchematic(resolver Resolver, comp *v1alpha1.ComponentSchematic) []string {
+	var errs []string
+	if renderer, err := resolveWorkloadRenderer(resolver, comp.Spec.WorkloadType); err != nil {
+		errs = append(errs, err.Error())
+	} else if renderer == nil {
+		errs = append(errs, fmt.Sprintf("workloadType %s is undefined", comp.Spec.WorkloadType))
+	}
+
+	declared := map[string]bool{}
+	for _, p := range comp.Spec.Parameters {
+		if declared[p.Name] {
+			errs = append(errs, fmt.Sprintf("parameter %s is duplicated", p.Name))
+		}
+		declared[p.Name] = true
+		if p.Default != "" && !matchesParameterType(p.ParameterType, p.Default) {
+			errs = append(errs, fmt.Sprintf("default of parameter %s must be of type %s", p.Name, p.ParameterType))
+		}
+	}
+
+	containers := map[string]bool{}
+	for _, c := range comp.Spec.Containers {
+		if containers[c.Name] {
+			errs = append(errs, fmt.Sprintf("container %s is duplicated", c.Name))
+		}
+		containers[c.Name] = true
+		for _, e := range c.Env {
+			if e.FromParam != "" && !declared[e.FromParam] {
+				errs = append(errs, fmt.Sprintf("env %s of container %s refers to undeclared parameter %s", e.Name, c.Name, e.FromParam))
+			}
+		}
+		for _, f := range c.Config {
+			if f.FromParam != "" && !declared[f.FromParam] {
+				errs = append(errs, fmt.Sprintf("config %s of container %s refers to undeclared parameter %s", f.Path, c.Name, f.FromParam))
+			}
+		}
+	}
+	return errs
+}
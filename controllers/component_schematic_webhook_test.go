@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateComponentSchematic(t *testing.T) {
+	RegisterBuiltins()
+	container := func(name string) v1alpha1.Container {
+		return v1alpha1.Container{Name: name, Image: "nginx:1"}
+	}
+	tests := []struct {
+		name string
+		spec v1alpha1.ComponentSpec
+		want []string
+	}{
+		{
+			name: "valid",
+			spec: v1alpha1.ComponentSpec{
+				WorkloadType: WorkloadTypeServer,
+				Parameters:   []v1alpha1.Parameter{{Name: "port", ParameterType: v1alpha1.Number, Default: "80"}},
+				Containers: []v1alpha1.Container{{
+					Name:   "web",
+					Image:  "nginx:1",
+					Env:    []v1alpha1.Env{{Name: "PORT", FromParam: "port"}},
+					Config: []v1alpha1.ConfigFile{{Path: "/etc/port", FromParam: "port"}},
+				}},
+			},
+		},
+		{
+			name: "undefined workload type",
+			spec: v1alpha1.ComponentSpec{WorkloadType: "example.com/v1.Unknown"},
+			want: []string{"workloadType example.com/v1.Unknown is undefined"},
+		},
+		{
+			name: "duplicated parameters and invalid defaults",
+			spec: v1alpha1.ComponentSpec{
+				WorkloadType: WorkloadTypeServer,
+				Parameters: []v1alpha1.Parameter{
+					{Name: "port", ParameterType: v1alpha1.Number, Default: "http"},
+					{Name: "port", ParameterType: v1alpha1.String},
+					{Name: "debug", ParameterType: v1alpha1.Boolean, Default: "yes"},
+				},
+			},
+			want: []string{
+				"default of parameter port must be of type number",
+				"parameter port is duplicated",
+				"default of parameter debug must be of type boolean",
+			},
+		},
+		{
+			name: "duplicated containers and undeclared parameters",
+			spec: v1alpha1.ComponentSpec{
+				WorkloadType: WorkloadTypeServer,
+				Containers: []v1alpha1.Container{
+					container("web"),
+					{
+						Name:   "web",
+						Image:  "nginx:1",
+						Env:    []v1alpha1.Env{{Name: "PORT", FromParam: "port"}},
+						Config: []v1alpha1.ConfigFile{{Path: "/etc/port", FromParam: "port"}},
+					},
+				},
+			},
+			want: []string{
+				"container web is duplicated",
+				"env PORT of container web refers to undeclared parameter port",
+				"config /etc/port of container web refers to undeclared parameter port",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comp := &v1alpha1.ComponentSchematic{ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: tt.spec}
+			errs := validateComponentSchematic(&clusterResolver{Oamclient: oamfake.NewSimpleClientset()}, comp)
+			if !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("validateComponentSchematic() = %q, want %q", errs, tt.want)
+			}
+		})
+	}
+}
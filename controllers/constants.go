@@ -9,43 +9,235 @@ const (
 	WorkloadTypeTask            = "core.oam.dev/v1alpha1.Task"
 	WorkloadTypeSingletonTask   = "core.oam.dev/v1alpha1.SingletonTask"
 	WorkloadTypeMysqlCluster    = "harmonycloud.cn/v1alpha1.MysqlCluster"
+	WorkloadTypeDaemonWorker    = "harmonycloud.cn/v1alpha1.DaemonWorker"
+	WorkloadTypeStatefulServer  = "harmonycloud.cn/v1alpha1.StatefulServer"
+	WorkloadTypeScheduledTask   = "harmonycloud.cn/v1alpha1.ScheduledTask"
+
+	// traits
+	TraitManualScaler     = "manual-scaler"
+	TraitVolumeMounter    = "volume-mounter"
+	TraitAutoScaler       = "auto-scaler"
+	TraitBetterAutoScaler = "better-auto-scaler"
+	TraitIngress          = "ingress"
+	TraitNginxIngress     = "nginx-ingress"
+	TraitLogPilot         = "log-pilot"
+	TraitHostPolicy       = "host-policy"
+	TraitResourcesPolicy  = "resources-policy"
+	TraitSchedulePolicy   = "schedule-policy"
+	TraitDependsOn        = "depends-on"
+	TraitRetentionPolicy  = "retention-policy"
+	TraitMysqlBackup      = "mysql-backup"
+	TraitRollout          = "rollout"
 
 	// event reasons
-	Created         = "Created"
-	Updated         = "Updated"
-	Patched         = "Patched"
-	Failed          = "Failed"
-	Synced          = "Synced"
-	SyncFailed      = "Sync Failed"
-	SyncSuccessfuly = "Sync Successfully"
-	Undefined       = "Undefined"
-	NotFound        = "Not Found"
+	Created          = "Created"
+	Updated          = "Updated"
+	Patched          = "Patched"
+	Pruned           = "Pruned"
+	Failed           = "Failed"
+	Synced           = "Synced"
+	SyncFailed       = "Sync Failed"
+	SyncSuccessfuly  = "Sync Successfully"
+	Undefined        = "Undefined"
+	NotFound         = "Not Found"
+	Waiting          = "Waiting"
+	Deleted          = "Deleted"
+	Retained         = "Retained"
+	TornDown         = "TornDown"
+	Operation        = "Operation"
+	Failover         = "Failover"
+	Rollout          = "Rollout"
+	Promoted         = "Promoted"
+	RolledBack       = "RolledBack"
+	SchematicChanged = "SchematicChanged"
+	TraitChanged     = "TraitChanged"
+	DriftReverted    = "DriftReverted"
+
+	// condition types and reasons
+	TraitsApplied      = "TraitsApplied"
+	TraitNotApplicable = "TraitNotApplicable"
+	InvalidProperties  = "InvalidProperties"
+	InvalidParameters  = "InvalidParameters"
+	DependenciesReady  = "DependenciesReady"
+	DependencyCycle    = "DependencyCycle"
+	DependencyNotFound = "DependencyNotFound"
+	Drifted            = "Drifted"
+	DriftDetected      = "DriftDetected"
+
+	// reasons of the Ready, Progressing and Degraded conditions
+	ResourcesReady       = "ResourcesReady"
+	ResourcesProgressing = "ResourcesProgressing"
+	ResourcesDegraded    = "ResourcesDegraded"
+	ResourcesNotFound    = "ResourcesNotFound"
+
+	// reasons of the Cleanup condition
+	TeardownInProgress = "TeardownInProgress"
+	TeardownFailed     = "TeardownFailed"
+
+	// phase of ApplicationConfigurations being deleted
+	Terminating = "Terminating"
+
+	// finalizer of ApplicationConfigurations, removed after the teardown
+	Finalizer = "hc-oam-controller.harmonycloud.cn/teardown"
+
+	// annotation of ApplicationConfigurations, the number of the recorded revision rendered instead of the spec
+	RollbackAnnotation = "hc-oam-controller.harmonycloud.cn/rollback-revision"
+
+	// componentNames <name>@<version> pin a version of a ComponentSchematic, which is recorded by the
+	// ControllerRevision <name>-<version> labeled with the name
+	ComponentVersionSeparator = "@"
+	SchematicLabel            = "component-schematic"
+	// annotation of ApplicationConfigurations, <name>@<version> of the last ComponentSchematic changed which they do not pin
+	SchematicChangedAnnotation = "hc-oam-controller.harmonycloud.cn/schematic-changed"
+	// annotation of ApplicationConfigurations, <name>@<version> of the last Trait changed which they refer to
+	TraitChangedAnnotation = "hc-oam-controller.harmonycloud.cn/trait-changed"
+	// annotation of ApplicationConfigurations, the time they are reconciled again while they wait
+	RequeueAnnotation = "hc-oam-controller.harmonycloud.cn/requeue-at"
+
+	// annotation of ApplicationConfigurations, how drift of their resources from the last applied rendering is handled
+	DriftPolicyAnnotation = "hc-oam-controller.harmonycloud.cn/drift-policy"
+	DriftEnforce          = "Enforce"
+	DriftWarn             = "Warn"
+	DriftIgnore           = "Ignore"
+	// annotation of the resources of ApplicationConfigurations, the JSON of their last applied rendering
+	LastAppliedAnnotation = "hc-oam-controller.harmonycloud.cn/last-applied"
+	// long strings of the last applied rendering, e.g. the files of ConfigMaps, are recorded by <prefix><hash>
+	LastAppliedHashPrefix = "hash:"
+	// label of the resources of ApplicationConfigurations, the name of their ApplicationConfiguration
+	ApplicationLabel = "application"
+	// label of the job templates of CronJobs, the name of the CronJob, by which the Jobs it ran are listed
+	CronJobLabel = "cronjob"
+
+	// annotations of WorkloadTypes rendered from a template
+	TemplateAnnotation         = "workload.harmonycloud.cn/template"
+	TargetApiVersionAnnotation = "workload.harmonycloud.cn/apiVersion"
+	TargetKindAnnotation       = "workload.harmonycloud.cn/kind"
+	TargetResourceAnnotation   = "workload.harmonycloud.cn/resource"
+	HealthAnnotation           = "workload.harmonycloud.cn/health"
+	DegradedAnnotation         = "workload.harmonycloud.cn/degraded"
+
+	// annotation of pod templates, the hash of the ConfigMaps and Secrets rendered for the component which the pods mount
+	ConfigChecksumAnnotation = "workload.harmonycloud.cn/config-hash"
+
+	// connection Secret published by MysqlCluster workloads, referenced by [fromConnection(instance,key)]
+	ConnectionSecretSuffix = "-connection"
+	ConnectionHost         = "host"
+	ConnectionPort         = "port"
+	ConnectionUser         = "user"
+	ConnectionPassword     = "password"
+	ConnectionDatabase     = "database"
+
+	// annotation of ComponentSchematics, <parameter>[,<parameter>] whose values are rendered into the Secret
+	// <instance>-parameters and referenced by [fromSecret(name,key)] instead of plain env values and ConfigMaps
+	SensitiveParametersAnnotation = "component.harmonycloud.cn/sensitive-parameters"
+	ParametersSecretSuffix        = "-parameters"
+	// replaces the values of sensitive parameters in events and status
+	MaskedValue = "***"
+
+	// annotation of ApplicationConfigurations requesting restores, <instance>=<backup>[,<instance>=<backup>]
+	RestoreAnnotation = "mysql-backup.harmonycloud.cn/restore"
+	// mount path of the backup volume, backups are named <instance>-<time>.sql.gz
+	BackupMountPath = "/backup"
+
+	// annotations of ApplicationConfigurations requesting operations of MysqlCluster instances, the requested
+	// operations are recorded by the same annotations of the MysqlCluster so that each is requested once
+	SwitchoverAnnotation      = "mysql.harmonycloud.cn/switchover"       // <instance>=<pod>[,...]
+	ForceSwitchoverAnnotation = "mysql.harmonycloud.cn/force-switchover" // <instance>=<pod>[,...]
+	PauseAnnotation           = "mysql.harmonycloud.cn/pause"            // <instance>=true|false[,...]
+	MigrateAnnotation         = "mysql.harmonycloud.cn/migrate"          // <instance>=<pod>:<node>[,...]
+	// workload settings config.<section>.<key> of MysqlClusters set the keys of my.cnf
+	MysqlConfigSettingPrefix = "config."
+	// annotation of the pods of MysqlClusters, the hash of the rendered my.cnf
+	ConfigHashAnnotation = "mysql.harmonycloud.cn/config-hash"
+	// annotation of MysqlClusters, the number of master switches already reported by a Failover event
+	SwitchedNumAnnotation = "mysql.harmonycloud.cn/reported-switched-num"
+
+	// strategies of the rollout trait
+	RolloutRollingUpdate = "RollingUpdate"
+	RolloutCanary        = "Canary"
+	RolloutBlueGreen     = "BlueGreen"
+	// annotations of ApplicationConfigurations, <instance>=<revision>[,...], promoting or aborting the rollout of a revision
+	RolloutPromoteAnnotation = "rollout.harmonycloud.cn/promote"
+	RolloutAbortAnnotation   = "rollout.harmonycloud.cn/abort"
+	// label of the pods, the hash of the pod template rolled out
+	RevisionLabel = "rollout.harmonycloud.cn/revision"
+	// label of the pods of the canary and preview Deployments
+	TrackLabel = "rollout.harmonycloud.cn/track"
+	// annotations of the canary and preview Deployments, the current step and when it started
+	RolloutStepAnnotation        = "rollout.harmonycloud.cn/step"
+	RolloutStepStartedAnnotation = "rollout.harmonycloud.cn/step-started"
+	// annotation of Deployments, <revision>:<stable revision> of the last revision rolled back
+	RolledBackAnnotation = "rollout.harmonycloud.cn/rolled-back"
+	CanarySuffix         = "-canary"
+	PreviewSuffix        = "-preview"
+
+	// retention policies of resources
+	RetentionDelete = "Delete"
+	RetentionRetain = "Retain"
+	RetentionOrphan = "Orphan"
 
 	// status
 	PatchFailed  = "Patch Failed"
 	CreateFailed = "Create Failed"
 	Healthy      = "Healthy"
 	Unhealthy    = "Unhealthy"
+
+	// status of invalid traits and components
+	InvalidStatus = "Invalid: %s"
+
 	// event messages
 	MessageResourceExists  = "Resource %s/%s already exists and is not managed by Foo"
 	MessageResourceCreated = "Resource %s/%s created successfully"
 	MessageResourceUpdated = "Resource %s/%s updated successfully"
 	MessageResourcePatched = "Resource %s/%s patched successfully"
+	MessageResourcePruned  = "Resource %s/%s pruned successfully"
 	WorkeloadTypeUndefined = "Workload type %s is undefined"
 	ComponentNotFound      = "ComponentSchematic %s not found"
 
+	TraitUndefined            = "Trait %s of component %s is undefined"
+	TraitNotFound             = "Trait %s of component %s not found"
+	TraitNotApplicableMessage = "Trait %s does not apply to workload type %s of component %s"
+	TraitInvalidMessage       = "Properties of trait %s of component %s are invalid: %s"
+	ParametersInvalidMessage  = "Parameters of component %s are invalid: %s"
+	WaitingMessage            = "Component %s waits for %s to be healthy"
+	DependencyCycleMessage    = "Components %s are in a dependency cycle"
+	DependencyNotFoundMessage = "Component %s depends on %s which is not in the ApplicationConfiguration"
+	ResourcesNotFoundMessage  = "Resources of component %s are not created yet"
+	ComponentsNotReadyMessage = "Components %s are not ready"
+	TeardownMessage           = "Waiting for the resources of component %s to be deleted: %s"
+	MessageResourceDeleted    = "Resource %s/%s deleted successfully"
+	MessageResourceRetained   = "Resource %s/%s retained"
+	MessageTornDown           = "Resources of ApplicationConfiguration %s torn down"
+	MessageOperation          = "Operation %s=%s requested on mysqlclusters %s"
+	MessageFailover           = "MysqlCluster %s switched its master %v times, the master is %s"
+	MessageRolloutStep        = "Rollout of revision %s of %s at step %d/%d with %d of %d replicas"
+	MessagePromoted           = "Revision %s of %s promoted"
+	MessageRolledBack         = "Revision %s of %s rolled back: %s"
+	MessageRevisionRendered   = "Revision %d of ApplicationConfiguration %s rendered"
+	RevisionNotFoundMessage   = "Revision %s of ApplicationConfiguration %s not found, the spec is rendered: %v"
+	MessageSchematicChanged   = "ComponentSchematic %s changed to version %s"
+	MessageTraitChanged       = "Trait %s changed to version %s"
+	MessageDrifted            = "%s %s drifted from its last applied rendering: %s"
+	MessageDriftReverted      = "%s %s reverted to its last applied rendering: %s"
+	DriftPolicyInvalidMessage = "drift policy %q is invalid, it is one of Enforce, Warn and Ignore"
+
 	MessageResourceSynced = "ApplicationConfiguration synced successfully"
 
 	//kind
 	DeploymentKind   = "Deployment"
+	DaemonSetKind    = "DaemonSet"
+	StatefulSetKind  = "StatefulSet"
+	CronJobKind      = "CronJob"
 	ServiceKind      = "Service"
 	IngressKind      = "Ingress"
 	JobKind          = "Job"
 	ConfigMapKind    = "ConfigMap"
+	SecretKind       = "Secret"
 	HpaKind          = "HorizontalPodAutoscaler"
 	HcHpaKind        = "HorizontalPodAutoscaler"
 	PvcKind          = "PersistentVolumeClaim"
 	MysqlClusterKind = "MysqlCluster"
+	TraitKind        = "Trait"
 
 	ServerKind          = "Server"
 	SingletonServerKind = "SingletonServer"
@@ -53,17 +245,25 @@ const (
 	SingletonWorkerKind = "SingletonWorker"
 	TaskKind            = "Task"
 	SingletonTaskKind   = "SingletonTask"
+	DaemonWorkerKind    = "DaemonWorker"
+	StatefulServerKind  = "StatefulServer"
+	ScheduledTaskKind   = "ScheduledTask"
 
 	// group version
 	OamV1alpha1GroupVersion  = "core.oam.dev/v1alpha1"
 	MysqlClusterGroupVersion = "mysql.middleware.harmonycloud.cn/v1alpha1"
+	HcV1alpha1GroupVersion   = "harmonycloud.cn/v1alpha1"
 
 	// api version
 	DeploymentApiVersion         = "apps/v1"
+	DaemonSetApiVersion          = "apps/v1"
+	StatefulSetApiVersion        = "apps/v1"
 	ServiceApiVersion            = "v1"
 	IngressApiVersion            = "extensions/v1beta1"
 	JobApiVersion                = "batch/v1"
+	CronJobApiVersion            = "batch/v1beta1"
 	ConfigMapApiVersion          = "v1"
+	SecretApiVersion             = "v1"
 	HpaApiVersion                = "autoscaling/v1"
 	HcHpaApiVersion              = "harmonycloud.cn/v1beta1"
 	PvcApiVersion                = "v1"
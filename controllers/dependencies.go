@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+)
+
+// dependencyError is the reason why a component instance can not be ordered.
+type dependencyError struct {
+	Reason  string
+	Message string
+}
+
+// requeueInterval returns the interval of the reconciles of the handler while waiting.
+func requeueInterval(s *ApplicationConfigurationHandler) time.Duration {
+	if s.RequeueInterval > 0 {
+		return s.RequeueInterval
+	}
+	return DefaultRequeueInterval
+}
+
+// requeue reconciles the ApplicationConfiguration again after the delay, while component instances wait for
+// their dependencies, rollouts or teardown. Waiting is not a failure, the reconcile succeeds.
+func requeue(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, delay time.Duration, messages []string) {
+	handlerLog.Info("ApplicationConfiguration requeued.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "After", delay.String(), "Waiting", strings.Join(messages, "; "))
+	if s.Enqueuer != nil {
+		s.Enqueuer.RequeueAfter(ac.Namespace, ac.Name, delay)
+	}
+}
+
+// getDependencies returns the instances the component instance depends on, declared by its depends-on traits.
+func getDependencies(compConf v1alpha1.ComponentConfiguration) []string {
+	var dependencies []string
+	for _, tr := range compConf.Traits {
+		if getTraitName(tr.Name) != TraitDependsOn {
+			continue
+		}
+		dependsOn := new(traits2.DependsOn)
+		if err := parsePropertiesOfTrait(tr, dependsOn); err != nil {
+			// invalid properties are reported by applicableTraits
+			continue
+		}
+		dependencies = append(dependencies, dependsOn.Instances...)
+	}
+	return dependencies
+}
+
+// orderComponents orders the component instances so that every instance comes after the instances it depends on,
+// and keeps the order of the spec otherwise. Instances depending on a missing instance or in a dependency cycle
+// are returned with their error.
+func orderComponents(components []v1alpha1.ComponentConfiguration) ([]v1alpha1.ComponentConfiguration, map[string]dependencyError) {
+	errs := map[string]dependencyError{}
+	instances := map[string]bool{}
+	for _, compConf := range components {
+		instances[compConf.InstanceName] = true
+	}
+	dependencies := map[string][]string{}
+	for _, compConf := range components {
+		for _, d := range getDependencies(compConf) {
+			if !instances[d] {
+				errs[compConf.InstanceName] = dependencyError{DependencyNotFound, fmt.Sprintf(DependencyNotFoundMessage, compConf.InstanceName, d)}
+				continue
+			}
+			dependencies[compConf.InstanceName] = append(dependencies[compConf.InstanceName], d)
+		}
+	}
+
+	var ordered []v1alpha1.ComponentConfiguration
+	placed := map[string]bool{}
+	remaining := append([]v1alpha1.ComponentConfiguration(nil), components...)
+	place := func(i int) {
+		placed[remaining[i].InstanceName] = true
+		ordered = append(ordered, remaining[i])
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	for len(remaining) > 0 {
+		next := -1
+		for i, compConf := range remaining {
+			ready := true
+			for _, d := range dependencies[compConf.InstanceName] {
+				if !placed[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next >= 0 {
+			place(next)
+			continue
+		}
+		// every remaining instance depends on another remaining one, place the instances of the cycles
+		var cycle []string
+		for _, compConf := range remaining {
+			if dependsOn(dependencies, compConf.InstanceName, compConf.InstanceName, placed, map[string]bool{}) {
+				cycle = append(cycle, compConf.InstanceName)
+			}
+		}
+		sort.Strings(cycle)
+		msg := fmt.Sprintf(DependencyCycleMessage, strings.Join(cycle, ", "))
+		for _, instance := range cycle {
+			errs[instance] = dependencyError{DependencyCycle, msg}
+			for i := range remaining {
+				if remaining[i].InstanceName == instance {
+					place(i)
+					break
+				}
+			}
+		}
+	}
+	return ordered, errs
+}
+
+// dependsOn returns true if from depends on to, directly or transitively, through instances which are not placed.
+func dependsOn(dependencies map[string][]string, from, to string, placed, visited map[string]bool) bool {
+	for _, d := range dependencies[from] {
+		if placed[d] {
+			continue
+		}
+		if d == to {
+			return true
+		}
+		if visited[d] {
+			continue
+		}
+		visited[d] = true
+		if dependsOn(dependencies, d, to, placed, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// unhealthyDependencies returns the dependencies of the component instance which are not Healthy in the module status.
+func unhealthyDependencies(ac *v1alpha1.ApplicationConfiguration, compConf v1alpha1.ComponentConfiguration) []string {
+	var unhealthy []string
+	for _, d := range getDependencies(compConf) {
+		healthy := false
+		for _, m := range ac.Status.Modules {
+			if m.NamespacedName == d && m.Status == Healthy {
+				healthy = true
+				break
+			}
+		}
+		if !healthy {
+			unhealthy = append(unhealthy, d)
+		}
+	}
+	return unhealthy
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestOrderComponents(t *testing.T) {
+	component := func(instance string, dependencies ...string) v1alpha1.ComponentConfiguration {
+		compConf := v1alpha1.ComponentConfiguration{InstanceName: instance}
+		if len(dependencies) > 0 {
+			properties, _ := json.Marshal(traits2.DependsOn{Instances: dependencies})
+			compConf.Traits = []v1alpha1.TraitBinding{{Name: TraitDependsOn, Properties: runtime.RawExtension{Raw: properties}}}
+		}
+		return compConf
+	}
+	tests := []struct {
+		name       string
+		components []v1alpha1.ComponentConfiguration
+		wantOrder  []string
+		wantErrs   map[string]dependencyError
+	}{
+		{
+			name:       "no dependencies keep the order of the spec",
+			components: []v1alpha1.ComponentConfiguration{component("web"), component("db"), component("cache")},
+			wantOrder:  []string{"web", "db", "cache"},
+			wantErrs:   map[string]dependencyError{},
+		},
+		{
+			name:       "dependencies come first",
+			components: []v1alpha1.ComponentConfiguration{component("web", "db", "cache"), component("db"), component("cache", "db")},
+			wantOrder:  []string{"db", "cache", "web"},
+			wantErrs:   map[string]dependencyError{},
+		},
+		{
+			name:       "missing dependency",
+			components: []v1alpha1.ComponentConfiguration{component("web", "db"), component("cache")},
+			wantOrder:  []string{"web", "cache"},
+			wantErrs: map[string]dependencyError{
+				"web": {DependencyNotFound, fmt.Sprintf(DependencyNotFoundMessage, "web", "db")},
+			},
+		},
+		{
+			name:       "cycle",
+			components: []v1alpha1.ComponentConfiguration{component("web", "db"), component("db", "cache"), component("cache", "web"), component("worker", "web"), component("cron")},
+			wantOrder:  []string{"cron", "cache", "db", "web", "worker"},
+			wantErrs: map[string]dependencyError{
+				"cache": {DependencyCycle, fmt.Sprintf(DependencyCycleMessage, "cache, db, web")},
+				"db":    {DependencyCycle, fmt.Sprintf(DependencyCycleMessage, "cache, db, web")},
+				"web":   {DependencyCycle, fmt.Sprintf(DependencyCycleMessage, "cache, db, web")},
+			},
+		},
+		{
+			name:       "self dependency",
+			components: []v1alpha1.ComponentConfiguration{component("web", "web"), component("db")},
+			wantOrder:  []string{"db", "web"},
+			wantErrs: map[string]dependencyError{
+				"web": {DependencyCycle, fmt.Sprintf(DependencyCycleMessage, "web")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, errs := orderComponents(tt.components)
+			var order []string
+			for _, compConf := range ordered {
+				order = append(order, compConf.InstanceName)
+			}
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("orderComponents() order = %v, want %v", order, tt.wantOrder)
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Errorf("orderComponents() errs = %v, want %v", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestRequeueWhileWaiting(t *testing.T) {
+	RegisterBuiltins()
+	worker := func(name string) *v1alpha1.ComponentSchematic {
+		return &v1alpha1.ComponentSchematic{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1alpha1.ComponentSpec{
+				WorkloadType: WorkloadTypeWorker,
+				Containers:   []v1alpha1.Container{{Name: name, Image: "busybox"}},
+			},
+		}
+	}
+	properties, _ := json.Marshal(traits2.DependsOn{Instances: []string{"db"}})
+	ac := newTestApplicationConfiguration(
+		v1alpha1.ComponentConfiguration{ComponentName: "db", InstanceName: "db"},
+		v1alpha1.ComponentConfiguration{
+			ComponentName: "web",
+			InstanceName:  "web",
+			Traits:        []v1alpha1.TraitBinding{{Name: TraitDependsOn, Properties: runtime.RawExtension{Raw: properties}}},
+		},
+	)
+	s, _, _ := newTestHandler([]runtime.Object{worker("db"), worker("web"), ac}, nil, nil)
+	recorder := record.NewFakeRecorder(10)
+	s.Enqueuer = NewApplicationEnqueuer(s.Oamclient, recorder, DefaultEnqueueQPS, DefaultEnqueueBurst)
+	s.RequeueInterval = time.Millisecond
+
+	// waiting for db is not a failure of the reconcile
+	if err := s.Handle(nil, ac.DeepCopy(), oam.CreateOrUpdate); err != nil {
+		t.Fatalf("Handle() error = %v, want none while waiting", err)
+	}
+	if !s.Enqueuer.processNextRequest() {
+		t.Fatal("no requeue request")
+	}
+	live, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse(time.RFC3339, live.Annotations[RequeueAnnotation]); err != nil {
+		t.Errorf("annotation %s = %q, want the time of the requeue", RequeueAnnotation, live.Annotations[RequeueAnnotation])
+	}
+	if len(recorder.Events) > 0 {
+		t.Errorf("requeue recorded event %q, want none", <-recorder.Events)
+	}
+}
@@ -0,0 +1,553 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	hcversioned "hc-oam-controller/client/clientset/versioned"
+	hcfake "hc-oam-controller/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	DiffCreate = "Create"
+	DiffPatch  = "Patch"
+	DiffPrune  = "Prune"
+)
+
+// ResourceDiff is the change a reconcile of an ApplicationConfiguration would make to a resource.
+type ResourceDiff struct {
+	Action     string        `json:"action"`
+	ApiVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Name       string        `json:"name"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is the change of a field, Old is nil for added fields and New is nil for removed fields.
+type FieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+type diffResource struct {
+	kind      string
+	newObject func() runtime.Object
+}
+
+// resources written by ApplicationConfigurationHandler
+var diffResources = map[schema.GroupVersionResource]diffResource{
+	apiv1.SchemeGroupVersion.WithResource("configmaps"):                   {ConfigMapKind, func() runtime.Object { return new(apiv1.ConfigMap) }},
+	apiv1.SchemeGroupVersion.WithResource("secrets"):                      {SecretKind, func() runtime.Object { return new(apiv1.Secret) }},
+	apiv1.SchemeGroupVersion.WithResource("persistentvolumeclaims"):       {PvcKind, func() runtime.Object { return new(apiv1.PersistentVolumeClaim) }},
+	apiv1.SchemeGroupVersion.WithResource("services"):                     {ServiceKind, func() runtime.Object { return new(apiv1.Service) }},
+	appsv1.SchemeGroupVersion.WithResource("deployments"):                 {DeploymentKind, func() runtime.Object { return new(appsv1.Deployment) }},
+	appsv1.SchemeGroupVersion.WithResource("daemonsets"):                  {DaemonSetKind, func() runtime.Object { return new(appsv1.DaemonSet) }},
+	appsv1.SchemeGroupVersion.WithResource("statefulsets"):                {StatefulSetKind, func() runtime.Object { return new(appsv1.StatefulSet) }},
+	batchv1.SchemeGroupVersion.WithResource("jobs"):                       {JobKind, func() runtime.Object { return new(batchv1.Job) }},
+	batchv1beta1.SchemeGroupVersion.WithResource("cronjobs"):              {CronJobKind, func() runtime.Object { return new(batchv1beta1.CronJob) }},
+	extensionsv1beta1.SchemeGroupVersion.WithResource("ingresses"):        {IngressKind, func() runtime.Object { return new(extensionsv1beta1.Ingress) }},
+	v2beta2.SchemeGroupVersion.WithResource("horizontalpodautoscalers"):   {HpaKind, func() runtime.Object { return new(v2beta2.HorizontalPodAutoscaler) }},
+	hcv1beta1.SchemeGroupVersion.WithResource("horizontalpodautoscalers"): {HcHpaKind, func() runtime.Object { return new(hcv1beta1.HorizontalPodAutoscaler) }},
+	hcv1alpha1.SchemeGroupVersion.WithResource("mysqlclusters"):           {MysqlClusterKind, func() runtime.Object { return new(hcv1alpha1.MysqlCluster) }},
+}
+
+// fields which are set by the api server or controllers, ignored in the changes of patches
+var ignoredFields = []string{
+	"metadata.creationTimestamp",
+	"metadata.generation",
+	"metadata.managedFields",
+	"metadata.resourceVersion",
+	"metadata.selfLink",
+	"metadata.uid",
+	"status",
+	// the rendering is compared field by field
+	"metadata.annotations." + LastAppliedAnnotation,
+}
+
+// DiffApplicationConfiguration returns the resources a reconcile of the ApplicationConfiguration would create,
+// patch or prune. It runs ApplicationConfigurationHandler against fake clients seeded with the live objects
+// of the namespace and records their writes, nothing is written to the cluster. The problems the handler
+// reports by warning events are returned as warnings. The ComponentSchematics, Traits and WorkloadTypes in objects
+// are seeded ahead of the live ones and replace those of the same name.
+func DiffApplicationConfiguration(ac *v1alpha1.ApplicationConfiguration, objects []runtime.Object, oamclient versioned.Interface, k8sclient kubernetes.Interface, hcclient hcversioned.Interface, dynamicclient dynamic.Interface) ([]ResourceDiff, []string, error) {
+	namespace := ac.Namespace
+	ac = ac.DeepCopy()
+	live, err := oamclient.CoreV1alpha1().ApplicationConfigurations(namespace).Get(ac.Name, v1.GetOptions{})
+	if err == nil {
+		// owner references and the status of the live ApplicationConfiguration decide what is patched and pruned
+		ac.UID = live.UID
+		ac.ResourceVersion = live.ResourceVersion
+		ac.Status = live.Status
+	} else if !apierrors.IsNotFound(err) {
+		return nil, nil, err
+	}
+
+	oamObjects, err := liveOamObjects(oamclient, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	k8sObjects, err := liveK8sObjects(k8sclient, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	hcObjects, err := liveHcObjects(hcclient, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	fakeOam := oamfake.NewSimpleClientset(append(mergeObjects(objects, oamObjects), ac)...)
+	// the live template objects are listed by the live WorkloadTypes, the rendered ones are written by the seeded ones
+	templateResources := map[schema.GroupVersionKind]schema.GroupVersionResource{}
+	templateKinds := map[schema.GroupVersionResource]string{}
+	for _, client := range []versioned.Interface{oamclient, fakeOam} {
+		workloads, err := GetTemplateWorkloads(nil, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, w := range workloads {
+			templateResources[w.GVK] = w.Resource
+			templateKinds[w.Resource] = w.GVK.Kind
+		}
+	}
+	templateObjects, err := listTemplateObjects(&ApplicationConfigurationHandler{Oamclient: oamclient, Dynamicclient: dynamicclient}, namespace, v1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fakeK8s := k8sfake.NewSimpleClientset(k8sObjects...)
+	fakeHc := hcfake.NewSimpleClientset(hcObjects...)
+	// the handler expects an empty object with NotFound, as returned by the real clients
+	fakeK8s.PrependReactor("get", "*", getOrEmpty(fakeK8s.Tracker()))
+	fakeHc.PrependReactor("get", "*", getOrEmpty(fakeHc.Tracker()))
+	// the resources of template objects are named by their WorkloadTypes, they are tracked by resource
+	// rather than by the resource guessed from their kind
+	scheme := runtime.NewScheme()
+	fakeDynamic := dynamicfake.NewSimpleDynamicClient(scheme)
+	currentDynamic := k8stesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+	liveDynamic := k8stesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+	fakeDynamic.PrependReactor("*", "*", k8stesting.ObjectReaction(currentDynamic))
+	for _, obj := range templateObjects {
+		gvr := templateResources[obj.GroupVersionKind()]
+		if err := currentDynamic.Create(gvr, obj.DeepCopy(), obj.GetNamespace()); err != nil {
+			return nil, nil, err
+		}
+		if err := liveDynamic.Create(gvr, obj.DeepCopy(), obj.GetNamespace()); err != nil {
+			return nil, nil, err
+		}
+	}
+	recorder := &warningRecorder{EventRecorder: &record.FakeRecorder{}}
+	s := &ApplicationConfigurationHandler{
+		Name:          "diff",
+		Oamclient:     fakeOam,
+		K8sclient:     fakeK8s,
+		Hcclient:      fakeHc,
+		Dynamicclient: fakeDynamic,
+		Recorder:      recorder,
+	}
+	// instances waiting for their dependencies are not rendered, as in the cluster
+	if err := s.Handle(nil, ac, oam.CreateOrUpdate); err != nil {
+		return nil, nil, err
+	}
+
+	// trackers which keep the live objects, to compare the patched objects with
+	liveK8s := k8sfake.NewSimpleClientset(k8sObjects...).Tracker()
+	liveHc := hcfake.NewSimpleClientset(hcObjects...).Tracker()
+	var diffs []ResourceDiff
+	seen := map[resourceKey]int{}
+	for _, c := range []struct {
+		actions []k8stesting.Action
+		live    k8stesting.ObjectTracker
+		current k8stesting.ObjectTracker
+	}{
+		{fakeK8s.Actions(), liveK8s, fakeK8s.Tracker()},
+		{fakeHc.Actions(), liveHc, fakeHc.Tracker()},
+		{fakeDynamic.Actions(), liveDynamic, currentDynamic},
+	} {
+		for _, action := range c.actions {
+			diff, ok, err := diffAction(action, c.live, c.current, templateKinds)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				continue
+			}
+			// a resource written twice is reported by its last change
+			key := resourceKey{ApiVersion: diff.ApiVersion, Kind: diff.Kind, Name: diff.Name}
+			if i, ok := seen[key]; ok {
+				diffs[i] = diff
+				continue
+			}
+			seen[key] = len(diffs)
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, recorder.warnings, nil
+}
+
+// diffAction returns the change made by a write action of the handler, ok is false for reads
+// and for patches which change nothing. templateKinds are the kinds of the resources of template objects.
+func diffAction(action k8stesting.Action, live, current k8stesting.ObjectTracker, templateKinds map[schema.GroupVersionResource]string) (ResourceDiff, bool, error) {
+	gvr := action.GetResource()
+	kind := templateKinds[gvr]
+	if r, known := diffResources[gvr]; known {
+		kind = r.kind
+	}
+	if kind == "" {
+		return ResourceDiff{}, false, nil
+	}
+	diff := ResourceDiff{ApiVersion: gvr.GroupVersion().String(), Kind: kind}
+	switch action.GetVerb() {
+	case "create":
+		a := action.(k8stesting.CreateAction)
+		obj, err := v1Object(a.GetObject())
+		if err != nil {
+			return diff, false, err
+		}
+		changes, err := diffObjects(nil, a.GetObject())
+		if err != nil {
+			return diff, false, err
+		}
+		diff.Action, diff.Name, diff.Changes = DiffCreate, obj.GetName(), changes
+	case "patch":
+		a := action.(k8stesting.PatchAction)
+		before, err := live.Get(gvr, a.GetNamespace(), a.GetName())
+		if err != nil {
+			return diff, false, err
+		}
+		after, err := current.Get(gvr, a.GetNamespace(), a.GetName())
+		if err != nil {
+			return diff, false, err
+		}
+		changes, err := diffObjects(before, after)
+		if err != nil {
+			return diff, false, err
+		}
+		if len(changes) == 0 {
+			return diff, false, nil
+		}
+		diff.Action, diff.Name, diff.Changes = DiffPatch, a.GetName(), changes
+	case "delete":
+		diff.Action, diff.Name = DiffPrune, action.(k8stesting.DeleteAction).GetName()
+	default:
+		return diff, false, nil
+	}
+	if kind == SecretKind {
+		maskSecretData(diff.Changes)
+	}
+	return diff, true, nil
+}
+
+// maskSecretData hides the values of Secrets in the changes.
+func maskSecretData(changes []FieldChange) {
+	for i := range changes {
+		c := &changes[i]
+		if !strings.HasPrefix(c.Path, "data") && !strings.HasPrefix(c.Path, "stringData") {
+			continue
+		}
+		if c.Old != nil {
+			c.Old = MaskedValue
+		}
+		if c.New != nil {
+			c.New = MaskedValue
+		}
+	}
+}
+
+// warningRecorder collects the messages of the warning events of a reconcile.
+type warningRecorder struct {
+	record.EventRecorder
+	warnings []string
+}
+
+func (r *warningRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if eventtype == apiv1.EventTypeWarning {
+		r.warnings = append(r.warnings, message)
+	}
+}
+
+func (r *warningRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func v1Object(obj runtime.Object) (v1.Object, error) {
+	o, ok := obj.(v1.Object)
+	if !ok {
+		return nil, fmt.Errorf("object %T has no metadata", obj)
+	}
+	return o, nil
+}
+
+// getOrEmpty returns a reactor which gets the object from the tracker, or an empty object of the resource
+// with a NotFound error.
+func getOrEmpty(tracker k8stesting.ObjectTracker) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		obj, err := tracker.Get(get.GetResource(), get.GetNamespace(), get.GetName())
+		if apierrors.IsNotFound(err) {
+			if r, ok := diffResources[get.GetResource()]; ok {
+				return true, r.newObject(), err
+			}
+		}
+		return true, obj, err
+	}
+}
+
+// diffObjects returns the changed fields between two objects, before is nil for created objects.
+func diffObjects(before, after runtime.Object) ([]FieldChange, error) {
+	old, err := flattenObject(before)
+	if err != nil {
+		return nil, err
+	}
+	current, err := flattenObject(after)
+	if err != nil {
+		return nil, err
+	}
+	var changes []FieldChange
+	for path, v := range current {
+		if o, ok := old[path]; !ok || !reflect.DeepEqual(o, v) {
+			changes = append(changes, FieldChange{Path: path, Old: o, New: v})
+		}
+	}
+	for path, o := range old {
+		if _, ok := current[path]; !ok {
+			changes = append(changes, FieldChange{Path: path, Old: o})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// flattenObject returns the leaf fields of the object by their paths, e.g. spec.template.spec.containers[0].image.
+func flattenObject(obj runtime.Object) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if obj == nil || reflect.ValueOf(obj).IsNil() {
+		return fields, nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	flatten("", value, fields)
+	return fields, nil
+}
+
+func flatten(path string, value interface{}, fields map[string]interface{}) {
+	for _, ignored := range ignoredFields {
+		if path == ignored || strings.HasPrefix(path, ignored+".") {
+			return
+		}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return
+		}
+		for k, e := range v {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			flatten(p, e, fields)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return
+		}
+		for i, e := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), e, fields)
+		}
+	case nil:
+	default:
+		fields[path] = v
+	}
+}
+
+func liveOamObjects(oamclient versioned.Interface, namespace string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	comps, err := oamclient.CoreV1alpha1().ComponentSchematics(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range comps.Items {
+		objects = append(objects, &comps.Items[i])
+	}
+	traits, err := oamclient.CoreV1alpha1().Traits("").List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range traits.Items {
+		objects = append(objects, &traits.Items[i])
+	}
+	workloadTypes, err := listWorkloadTypes(nil, oamclient)
+	if err != nil {
+		return nil, err
+	}
+	for i := range workloadTypes {
+		objects = append(objects, &workloadTypes[i])
+	}
+	return objects, nil
+}
+
+// mergeObjects returns the objects followed by the live objects of other types or names.
+func mergeObjects(objects, live []runtime.Object) []runtime.Object {
+	key := func(obj runtime.Object) string {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%T %s/%s", obj, o.GetNamespace(), o.GetName())
+	}
+	merged := append([]runtime.Object{}, objects...)
+	seen := map[string]bool{}
+	for _, obj := range objects {
+		seen[key(obj)] = true
+	}
+	for _, obj := range live {
+		if !seen[key(obj)] {
+			merged = append(merged, obj)
+		}
+	}
+	return merged
+}
+
+func liveK8sObjects(k8sclient kubernetes.Interface, namespace string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	configMaps, err := k8sclient.CoreV1().ConfigMaps(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		objects = append(objects, &configMaps.Items[i])
+	}
+	secrets, err := k8sclient.CoreV1().Secrets(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range secrets.Items {
+		objects = append(objects, &secrets.Items[i])
+	}
+	// revisions are rendered by rollbacks
+	revisions, err := k8sclient.AppsV1().ControllerRevisions(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions.Items {
+		objects = append(objects, &revisions.Items[i])
+	}
+	pvcs, err := k8sclient.CoreV1().PersistentVolumeClaims(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pvcs.Items {
+		objects = append(objects, &pvcs.Items[i])
+	}
+	services, err := k8sclient.CoreV1().Services(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		objects = append(objects, &services.Items[i])
+	}
+	deployments, err := k8sclient.AppsV1().Deployments(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		objects = append(objects, &deployments.Items[i])
+	}
+	daemonSets, err := k8sclient.AppsV1().DaemonSets(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		objects = append(objects, &daemonSets.Items[i])
+	}
+	statefulSets, err := k8sclient.AppsV1().StatefulSets(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		objects = append(objects, &statefulSets.Items[i])
+	}
+	jobs, err := k8sclient.BatchV1().Jobs(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		objects = append(objects, &jobs.Items[i])
+	}
+	ingresses, err := k8sclient.ExtensionsV1beta1().Ingresses(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range ingresses.Items {
+		objects = append(objects, &ingresses.Items[i])
+	}
+	cronJobs, err := k8sclient.BatchV1beta1().CronJobs(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronJobs.Items {
+		objects = append(objects, &cronJobs.Items[i])
+	}
+	hpas, err := k8sclient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range hpas.Items {
+		objects = append(objects, &hpas.Items[i])
+	}
+	return objects, nil
+}
+
+func liveHcObjects(hcclient hcversioned.Interface, namespace string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	mysqlClusters, err := hcclient.MysqlV1alpha1().MysqlClusters(namespace).List(nil, v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range mysqlClusters.Items {
+		objects = append(objects, &mysqlClusters.Items[i])
+	}
+	hcHpas, err := hcclient.HarmonycloudV1beta1().HorizontalPodAutoscalers(namespace).List(nil, v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range hcHpas.Items {
+		objects = append(objects, &hcHpas.Items[i])
+	}
+	return objects, nil
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	hcfake "hc-oam-controller/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiffObjects(t *testing.T) {
+	deployment := func(image string, labels map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "web", Labels: labels, ResourceVersion: image},
+			Spec: appsv1.DeploymentSpec{Template: apiv1.PodTemplateSpec{Spec: apiv1.PodSpec{
+				Containers: []apiv1.Container{{Name: "web", Image: image}},
+			}}},
+		}
+	}
+	var none *appsv1.Deployment
+	tests := []struct {
+		name   string
+		before runtime.Object
+		after  runtime.Object
+		want   []FieldChange
+	}{
+		{
+			name:   "created",
+			before: none,
+			after:  deployment("nginx:1", nil),
+			want: []FieldChange{
+				{Path: "metadata.name", New: "web"},
+				{Path: "spec.template.spec.containers[0].image", New: "nginx:1"},
+				{Path: "spec.template.spec.containers[0].name", New: "web"},
+			},
+		},
+		{
+			name:   "changed, added and removed",
+			before: deployment("nginx:1", map[string]string{"tier": "web"}),
+			after:  deployment("nginx:2", map[string]string{"app": "web"}),
+			want: []FieldChange{
+				{Path: "metadata.labels.app", New: "web"},
+				{Path: "metadata.labels.tier", Old: "web"},
+				{Path: "spec.template.spec.containers[0].image", Old: "nginx:1", New: "nginx:2"},
+			},
+		},
+		{
+			name:   "unchanged but the ignored fields",
+			before: deployment("nginx:1", nil),
+			after:  func() runtime.Object { d := deployment("nginx:1", nil); d.ResourceVersion = "2"; return d }(),
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffObjects(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("diffObjects() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffObjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskSecretData(t *testing.T) {
+	changes := []FieldChange{
+		{Path: "data.password", Old: "b2xk", New: "bmV3"},
+		{Path: "stringData.user", New: "root"},
+		{Path: "metadata.name", New: "db"},
+	}
+	maskSecretData(changes)
+	want := []FieldChange{
+		{Path: "data.password", Old: MaskedValue, New: MaskedValue},
+		{Path: "stringData.user", New: MaskedValue},
+		{Path: "metadata.name", New: "db"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("maskSecretData() = %v, want %v", changes, want)
+	}
+}
+
+func TestDiffApplicationConfiguration(t *testing.T) {
+	RegisterBuiltins()
+	comp := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeWorker,
+			Containers:   []v1alpha1.Container{{Name: "web", Image: "nginx:1"}},
+		},
+	}
+	cacheComp := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "cache", Namespace: "default"},
+		Spec:       v1alpha1.ComponentSpec{WorkloadType: "example.com/v1.Cache"},
+	}
+	cacheType := &v1alpha1.WorkloadType{
+		ObjectMeta: v1.ObjectMeta{Name: "cache", Annotations: map[string]string{
+			TargetApiVersionAnnotation: "example.com/v1",
+			TargetKindAnnotation:       "Cache",
+			TargetResourceAnnotation:   "caches",
+			TemplateAnnotation:         "apiVersion: example.com/v1\nkind: Cache\nmetadata:\n  name: {{ .Name }}\nspec:\n  size: 1\n",
+		}},
+		Spec: v1alpha1.WorkloadTypeSpec{Group: "example.com", Version: "v1", Names: v1alpha1.Names{Kind: "Cache"}},
+	}
+	ac := newTestApplicationConfiguration(
+		v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web", Traits: []v1alpha1.TraitBinding{{Name: "undefined"}}},
+		v1alpha1.ComponentConfiguration{ComponentName: "cache", InstanceName: "cache"},
+	)
+	stale := &appsv1.Deployment{ObjectMeta: ownedObjectMeta(ac, "old", "old")}
+	cacheMeta := ownedObjectMeta(ac, "cache", "cache")
+	cache := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Cache",
+		"spec":       map[string]interface{}{"size": int64(3)},
+	}}
+	cache.SetName(cacheMeta.Name)
+	cache.SetNamespace(cacheMeta.Namespace)
+	cache.SetOwnerReferences(cacheMeta.OwnerReferences)
+	cache.SetLabels(cacheMeta.Labels)
+	cache.SetAnnotations(cacheMeta.Annotations)
+
+	// the ComponentSchematic given replaces the live one, which runs a Server
+	liveComp := comp.DeepCopy()
+	liveComp.Spec.WorkloadType = WorkloadTypeServer
+	diffs, warnings, err := DiffApplicationConfiguration(ac, []runtime.Object{comp}, oamfake.NewSimpleClientset(liveComp, cacheComp, cacheType, ac),
+		k8sfake.NewSimpleClientset(stale), hcfake.NewSimpleClientset(), dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), cache))
+	if err != nil {
+		t.Fatalf("DiffApplicationConfiguration() error = %v", err)
+	}
+	actions := map[string]string{}
+	for _, d := range diffs {
+		actions[d.Kind+"/"+d.Name] = d.Action
+		if d.Kind == "Cache" && !changed(d.Changes, "spec.size", float64(3), float64(1)) {
+			t.Errorf("DiffApplicationConfiguration() changes of Cache/cache = %v, want spec.size 3 -> 1", d.Changes)
+		}
+	}
+	want := map[string]string{"Deployment/web": DiffCreate, "Deployment/old": DiffPrune, "Cache/cache": DiffPatch}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("DiffApplicationConfiguration() = %v, want %v", actions, want)
+	}
+	if wantWarnings := []string{fmt.Sprintf(TraitUndefined, "undefined", "web")}; !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("DiffApplicationConfiguration() warnings = %v, want %v", warnings, wantWarnings)
+	}
+}
+
+func changed(changes []FieldChange, path string, old, new interface{}) bool {
+	for _, c := range changes {
+		if c.Path == path {
+			return reflect.DeepEqual(c.Old, old) && reflect.DeepEqual(c.New, new)
+		}
+	}
+	return false
+}
@@ -0,0 +1,521 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	acstatus "hc-oam-controller/api/core.oam.dev/v1alpha1/status"
+	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/client-go/dynamic"
+)
+
+// lastAppliedStringLimit is the length of the longest string the last applied rendering records as it is.
+const lastAppliedStringLimit = 256
+
+// driftDetector compares the live resources of an ApplicationConfiguration with the rendering last applied to them,
+// recorded by their last-applied annotation, and collects the drift found by a reconcile.
+type driftDetector struct {
+	policy string
+	live   map[resourceKey]runtime.Object
+	drift  []acstatus.DriftStatus
+	// the patches removing the fields which the merge patches of the renderings can not remove
+	removals map[resourceKey][]byte
+}
+
+// newDriftDetector returns the detector of the live objects of the ApplicationConfiguration, listed before the
+// reconcile writes them.
+func newDriftDetector(ac *v1alpha1.ApplicationConfiguration, objects map[string][]runtime.Object) *driftDetector {
+	d := &driftDetector{policy: getDriftPolicy(ac), live: map[resourceKey]runtime.Object{}, removals: map[resourceKey][]byte{}}
+	for _, instanceObjects := range objects {
+		for _, obj := range instanceObjects {
+			if key, err := getResourceKey(obj); err == nil {
+				d.live[key] = obj
+			}
+		}
+	}
+	return d
+}
+
+// getDriftPolicy returns the drift policy of the ApplicationConfiguration, Enforce unless it is annotated.
+func getDriftPolicy(ac *v1alpha1.ApplicationConfiguration) string {
+	for _, policy := range []string{DriftWarn, DriftIgnore} {
+		if strings.EqualFold(ac.Annotations[DriftPolicyAnnotation], policy) {
+			return policy
+		}
+	}
+	return DriftEnforce
+}
+
+func validDriftPolicy(policy string) bool {
+	for _, p := range []string{DriftEnforce, DriftWarn, DriftIgnore} {
+		if strings.EqualFold(policy, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply annotates the rendered object with its rendering and compares the live object with the rendering last
+// applied to it. It returns false if the object is not to be written: the live object drifted, its rendering
+// did not change and the policy is not to revert drift.
+func (d *driftDetector) apply(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, instance string, obj runtime.Object) bool {
+	switch obj.(type) {
+	case *apiv1.Secret, *hcv1alpha1.MysqlCluster:
+		// the data of Secrets is not copied to an annotation, and the fields of MysqlClusters are driven by operations
+		return true
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return true
+	}
+	rendering, err := lastAppliedRendering(ac, instance, obj)
+	if err != nil {
+		handlerLog.Info("Record rendering failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Name", o.GetName(), "Error", err)
+		return true
+	}
+	// the annotations of the objects of an instance are shared by the converters
+	annotations := map[string]string{}
+	for k, v := range o.GetAnnotations() {
+		annotations[k] = v
+	}
+	annotations[LastAppliedAnnotation] = rendering
+	o.SetAnnotations(annotations)
+
+	key, err := getResourceKey(obj)
+	if err != nil {
+		return true
+	}
+	live, ok := d.live[key]
+	if !ok {
+		return true
+	}
+	liveMeta, err := meta.Accessor(live)
+	if err != nil {
+		return true
+	}
+	// resources applied before drift was detected have no rendering to compare with
+	applied := liveMeta.GetAnnotations()[LastAppliedAnnotation]
+	if applied == "" {
+		return true
+	}
+	fields, err := driftedFields(applied, live)
+	if err != nil {
+		handlerLog.Info("Detect drift failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Kind", key.Kind, "Name", key.Name, "Error", err)
+		return true
+	}
+	// a changed rendering is applied whatever the policy
+	write := len(fields) == 0 || d.policy == DriftEnforce || applied != rendering
+	if len(fields) > 0 && d.policy != DriftIgnore {
+		if d.policy == DriftWarn {
+			s.Recorder.Event(ac, apiv1.EventTypeWarning, Drifted, fmt.Sprintf(MessageDrifted, key.Kind, key.Name, strings.Join(fields, ", ")))
+		} else {
+			s.Recorder.Event(ac, apiv1.EventTypeNormal, DriftReverted, fmt.Sprintf(MessageDriftReverted, key.Kind, key.Name, strings.Join(fields, ", ")))
+		}
+		handlerLog.Info("Resource drifted.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Kind", key.Kind, "Name", key.Name, "Fields", fields, "Reverted", write)
+		d.drift = append(d.drift, acstatus.DriftStatus{
+			ApiVersion: key.ApiVersion,
+			Kind:       key.Kind,
+			Name:       key.Name,
+			Component:  instance,
+			Fields:     fields,
+			Reverted:   write,
+		})
+	}
+	if !write {
+		return false
+	}
+
+	// the keys the live object added to the maps of the rendering are removed when drift is reverted
+	var added runtime.Object
+	if d.policy == DriftEnforce {
+		added = live
+	}
+	patch, err := removalPatch(ac, instance, obj, applied, rendering, added)
+	if err != nil {
+		handlerLog.Info("Compute removal patch failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Kind", key.Kind, "Name", key.Name, "Error", err)
+	} else if patch != nil {
+		d.removals[key] = patch
+	}
+	return true
+}
+
+// remove patches the live object to remove the fields recorded by apply, after its rendering was written.
+func (d *driftDetector) remove(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, obj runtime.Object) error {
+	key, err := getResourceKey(obj)
+	if err != nil {
+		return nil
+	}
+	patch, ok := d.removals[key]
+	if !ok {
+		return nil
+	}
+	delete(d.removals, key)
+	if err := patchOwnedObject(s, ac.Namespace, obj, patch); err != nil {
+		s.Recorder.Event(ac, apiv1.EventTypeWarning, Failed, err.Error())
+		return err
+	}
+	return nil
+}
+
+// lastAppliedRendering returns the JSON of the rendered object, without its status and the fields which
+// are not written from the rendering: the replicas managed by autoscalers and the claim templates of StatefulSets.
+// Strings longer than lastAppliedStringLimit are recorded by their hash, which keeps the annotation of e.g.
+// ConfigMaps of large files far below the size limit of annotations.
+func lastAppliedRendering(ac *v1alpha1.ApplicationConfiguration, instance string, obj runtime.Object) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	var rendering map[string]interface{}
+	if err := json.Unmarshal(data, &rendering); err != nil {
+		return "", err
+	}
+	// the live objects listed have no type meta
+	delete(rendering, "apiVersion")
+	delete(rendering, "kind")
+	delete(rendering, "status")
+	if metadata, ok := rendering["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, LastAppliedAnnotation)
+		}
+	}
+	removeUnwrittenFields(ac, instance, obj, rendering)
+	data, err = json.Marshal(hashLongStrings(rendering))
+	return string(data), err
+}
+
+// removeUnwrittenFields removes the fields of the rendering of the object which are kept as they are live.
+func removeUnwrittenFields(ac *v1alpha1.ApplicationConfiguration, instance string, obj runtime.Object, rendering map[string]interface{}) {
+	spec, _ := rendering["spec"].(map[string]interface{})
+	autoscaled := controlsReplicas(ac, instance)
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		if autoscaled && o.Name == o.Annotations[Instance] {
+			delete(spec, "replicas")
+		}
+	case *appsv1.StatefulSet:
+		if autoscaled {
+			delete(spec, "replicas")
+		}
+		delete(spec, "volumeClaimTemplates")
+	case *batchv1.Job:
+		if autoscaled {
+			delete(spec, "parallelism")
+		}
+	}
+}
+
+// hashLongStrings replaces the strings of the value longer than lastAppliedStringLimit by their recorded value.
+func hashLongStrings(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = hashLongStrings(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = hashLongStrings(e)
+		}
+	case string:
+		return recordedString(v)
+	}
+	return value
+}
+
+// recordedString returns the string as the last applied rendering records it.
+func recordedString(s string) string {
+	if len(s) <= lastAppliedStringLimit {
+		return s
+	}
+	return LastAppliedHashPrefix + contentHash(s)
+}
+
+// driftedFields returns the paths of the fields of the last applied rendering which the live object changed or
+// removed, of the items it added to the lists of the rendering and of the keys it added to its maps. Fields the
+// rendering does not set, defaulted by the API server or added by other controllers, are not compared.
+func driftedFields(applied string, live runtime.Object) ([]string, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(applied), &value); err != nil {
+		return nil, err
+	}
+	rendered := map[string]interface{}{}
+	flatten("", value, rendered)
+	fields, err := flattenObject(live)
+	if err != nil {
+		return nil, err
+	}
+	drifted := map[string]bool{}
+	for path, v := range rendered {
+		f, ok := fields[path]
+		if s, isString := f.(string); isString {
+			if recorded, _ := v.(string); strings.HasPrefix(recorded, LastAppliedHashPrefix) {
+				f = recordedString(s)
+			}
+		}
+		if !ok || !reflect.DeepEqual(f, v) {
+			drifted[path] = true
+		}
+	}
+	for path := range fields {
+		if _, ok := rendered[path]; !ok {
+			if item := addedListItem(path, rendered); item != "" {
+				drifted[item] = true
+			}
+		}
+	}
+	liveValue, err := jsonValue(live)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range addedMapKeys(reflect.TypeOf(live), "", value, liveValue) {
+		drifted[k.path] = true
+	}
+	var paths []string
+	for path := range drifted {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// addedKey is a key the live object added to a map of the last applied rendering.
+type addedKey struct {
+	path string
+	// the map of the rendering, the key and its live value
+	rendered map[string]interface{}
+	key      string
+	value    interface{}
+}
+
+// addedMapKeys returns the keys which the live value added to the maps of the rendered value, found by the Go
+// type of the object. The maps of template objects are not known, nor are the maps of metadata, which other
+// controllers and tools add to, and the requests of resources, which the API server defaults to the limits.
+func addedMapKeys(t reflect.Type, path string, rendered, live interface{}) []addedKey {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	var added []addedKey
+	switch t.Kind() {
+	case reflect.Struct:
+		r, _ := rendered.(map[string]interface{})
+		l, _ := live.(map[string]interface{})
+		for name, value := range r {
+			if field, ok := jsonField(t, name); ok && l[name] != nil {
+				added = append(added, addedMapKeys(field, joinPath(path, name), value, l[name])...)
+			}
+		}
+	case reflect.Map:
+		r, _ := rendered.(map[string]interface{})
+		l, _ := live.(map[string]interface{})
+		if r == nil || l == nil {
+			return nil
+		}
+		for name, value := range l {
+			if _, ok := r[name]; !ok && !unownedMap(path) {
+				added = append(added, addedKey{path: joinPath(path, name), rendered: r, key: name, value: value})
+			}
+		}
+		for name, value := range r {
+			added = append(added, addedMapKeys(t.Elem(), joinPath(path, name), value, l[name])...)
+		}
+	case reflect.Slice, reflect.Array:
+		r, _ := rendered.([]interface{})
+		l, _ := live.([]interface{})
+		for i := 0; i < len(r) && i < len(l); i++ {
+			added = append(added, addedMapKeys(t.Elem(), fmt.Sprintf("%s[%d]", path, i), r[i], l[i])...)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].path < added[j].path })
+	return added
+}
+
+// jsonField returns the type of the field of the struct with the JSON name, inlined structs included.
+func jsonField(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous && tag == "" {
+			if ft, ok := jsonField(f.Type, name); ok {
+				return ft, true
+			}
+			continue
+		}
+		if tag == name || tag == "" && f.Name == name {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+func unownedMap(path string) bool {
+	for _, suffix := range []string{"metadata.annotations", "metadata.labels", "resources.requests"} {
+		if path == suffix || strings.HasSuffix(path, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonValue returns the JSON value of the object.
+func jsonValue(obj runtime.Object) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// removalPatch returns the three-way JSON merge patch which removes the fields of the last applied rendering that
+// the rendering does not set anymore and, if the live object is given, the keys it added to the maps of the last
+// applied rendering. The merge patch of the rendering replaces lists, their items are not removed by the patch.
+// It returns nil if nothing is removed.
+func removalPatch(ac *v1alpha1.ApplicationConfiguration, instance string, obj runtime.Object, applied, rendering string, live runtime.Object) ([]byte, error) {
+	var original map[string]interface{}
+	if err := json.Unmarshal([]byte(applied), &original); err != nil {
+		return nil, err
+	}
+	// fields the reconcile keeps are not removed, e.g. the replicas of a workload which got an autoscaler
+	removeUnwrittenFields(ac, instance, obj, original)
+	if live != nil {
+		liveValue, err := jsonValue(live)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range addedMapKeys(reflect.TypeOf(live), "", original, liveValue) {
+			k.rendered[k.key] = k.value
+		}
+	}
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	// the patch of the rendering is written before, so it is the current object
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(data, []byte(rendering), []byte(rendering))
+	if err != nil || string(patch) == "{}" {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// patchOwnedObject patches the live object of the rendered one with a JSON merge patch.
+func patchOwnedObject(s *ApplicationConfigurationHandler, namespace string, obj runtime.Object, patch []byte) error {
+	var err error
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		_, err = s.K8sclient.AppsV1().Deployments(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *appsv1.DaemonSet:
+		_, err = s.K8sclient.AppsV1().DaemonSets(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *appsv1.StatefulSet:
+		_, err = s.K8sclient.AppsV1().StatefulSets(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *batchv1.Job:
+		_, err = s.K8sclient.BatchV1().Jobs(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *batchv1beta1.CronJob:
+		_, err = s.K8sclient.BatchV1beta1().CronJobs(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *apiv1.Service:
+		_, err = s.K8sclient.CoreV1().Services(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *apiv1.ConfigMap:
+		_, err = s.K8sclient.CoreV1().ConfigMaps(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *apiv1.PersistentVolumeClaim:
+		_, err = s.K8sclient.CoreV1().PersistentVolumeClaims(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *extensionsv1beta1.Ingress:
+		_, err = s.K8sclient.ExtensionsV1beta1().Ingresses(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *v2beta2.HorizontalPodAutoscaler:
+		_, err = s.K8sclient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Patch(o.Name, types.MergePatchType, patch)
+	case *hcv1beta1.HorizontalPodAutoscaler:
+		_, err = s.Hcclient.HarmonycloudV1beta1().HorizontalPodAutoscalers(namespace).Patch(nil, o.Name, types.MergePatchType, patch, v1.PatchOptions{})
+	case *unstructured.Unstructured:
+		var client dynamic.ResourceInterface
+		if client, err = templateObjectClient(s, namespace, o); err == nil {
+			_, err = client.Patch(o.GetName(), types.MergePatchType, patch, v1.PatchOptions{})
+		}
+	default:
+		err = fmt.Errorf("unsupported object %T", obj)
+	}
+	return err
+}
+
+// addedListItem returns the path of the list item of the field, if the list is rendered and the item is not.
+func addedListItem(path string, rendered map[string]interface{}) string {
+	for i := strings.Index(path, "["); i >= 0; {
+		end := i + strings.Index(path[i:], "]") + 1
+		list, item := path[:i], path[:end]
+		if !renderedPath(rendered, list+"[0]") {
+			return ""
+		}
+		if !renderedPath(rendered, item) {
+			return item
+		}
+		next := strings.Index(path[end:], "[")
+		if next < 0 {
+			return ""
+		}
+		i = end + next
+	}
+	return ""
+}
+
+// renderedPath returns true if the rendering sets the field of the path or a field within it.
+func renderedPath(rendered map[string]interface{}, path string) bool {
+	for p := range rendered {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// updateDriftStatus writes the drift found to the status and sets the Drifted condition, true while drift is not
+// reverted. Drift reverted is kept in the status until drift is found again.
+func updateDriftStatus(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, d *driftDetector) error {
+	if len(d.drift) == 0 {
+		for _, c := range ac.Status.Conditions {
+			if c.Type == Drifted && c.Status == apiv1.ConditionTrue {
+				ac.Status.SetConditionFalse(Drifted, "", "")
+				return patchStatusFields(s.Oamclient, ac, map[string]interface{}{"drift": nil, "conditions": statusConditions(ac)})
+			}
+		}
+		return nil
+	}
+	var messages []string
+	reverted := true
+	for _, drift := range d.drift {
+		messages = append(messages, fmt.Sprintf("%s %s: %s", drift.Kind, drift.Name, strings.Join(drift.Fields, ", ")))
+		reverted = reverted && drift.Reverted
+	}
+	if reverted {
+		ac.Status.SetConditionFalse(Drifted, DriftReverted, strings.Join(messages, "; "))
+	} else {
+		ac.Status.SetConditionTrue(Drifted, DriftDetected, strings.Join(messages, "; "))
+	}
+	return patchStatusFields(s.Oamclient, ac, map[string]interface{}{"drift": d.drift, "conditions": statusConditions(ac)})
+}
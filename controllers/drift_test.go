@@ -0,0 +1,397 @@
+package controllers
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	acstatus "hc-oam-controller/api/core.oam.dev/v1alpha1/status"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDriftedFields(t *testing.T) {
+	longFile := strings.Repeat("x", lastAppliedStringLimit+1)
+	rendered := func() *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "web", Annotations: map[string]string{Instance: "web"}},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Template: apiv1.PodTemplateSpec{
+					ObjectMeta: v1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+					Spec: apiv1.PodSpec{
+						NodeSelector: map[string]string{"disk": "ssd"},
+						Containers: []apiv1.Container{{
+							Name:    "web",
+							Image:   "nginx:1",
+							Command: []string{"sh", "-c", longFile},
+							Env:     []apiv1.EnvVar{{Name: "A", Value: "a"}},
+							Resources: apiv1.ResourceRequirements{
+								Limits:   apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("1")},
+								Requests: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("1")},
+							},
+						}},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		edit func(d *appsv1.Deployment)
+		want []string
+	}{
+		{
+			name: "not drifted",
+			edit: func(d *appsv1.Deployment) {},
+		},
+		{
+			name: "defaulted fields",
+			edit: func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.RestartPolicy = apiv1.RestartPolicyAlways
+				d.Spec.Template.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+				d.Spec.Template.Spec.Containers[0].Resources.Requests[apiv1.ResourceMemory] = resource.MustParse("1Gi")
+			},
+		},
+		{
+			name: "annotations of other controllers",
+			edit: func(d *appsv1.Deployment) {
+				d.Annotations["deployment.kubernetes.io/revision"] = "2"
+				d.Spec.Template.Annotations = map[string]string{"kubectl.kubernetes.io/restartedAt": "now"}
+			},
+		},
+		{
+			name: "changed and removed fields",
+			edit: func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers[0].Image = "nginx:2"
+				d.Spec.Template.Spec.Containers[0].Env = nil
+			},
+			want: []string{"spec.template.spec.containers[0].env[0].name", "spec.template.spec.containers[0].env[0].value", "spec.template.spec.containers[0].image"},
+		},
+		{
+			name: "changed long string",
+			edit: func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers[0].Command[2] = longFile + "y"
+			},
+			want: []string{"spec.template.spec.containers[0].command[2]"},
+		},
+		{
+			name: "added list items",
+			edit: func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers[0].Env = append(d.Spec.Template.Spec.Containers[0].Env, apiv1.EnvVar{Name: "B", Value: "b"})
+				d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, apiv1.Container{Name: "sidecar", Image: "envoy"})
+			},
+			want: []string{"spec.template.spec.containers[0].env[1]", "spec.template.spec.containers[1]"},
+		},
+		{
+			name: "added map keys",
+			edit: func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.NodeSelector["zone"] = "a"
+				d.Spec.Template.Spec.Containers[0].Resources.Limits[apiv1.ResourceMemory] = resource.MustParse("1Gi")
+			},
+			want: []string{"spec.template.spec.containers[0].resources.limits.memory", "spec.template.spec.nodeSelector.zone"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, err := lastAppliedRendering(newTestApplicationConfiguration(), "web", rendered())
+			if err != nil {
+				t.Fatal(err)
+			}
+			live := rendered()
+			tt.edit(live)
+			fields, err := driftedFields(applied, live)
+			if err != nil {
+				t.Fatalf("driftedFields() error = %v", err)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("driftedFields() = %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddedListItem(t *testing.T) {
+	rendered := map[string]interface{}{
+		"spec.ports[0].port":                       80,
+		"spec.containers[0].name":                  "web",
+		"spec.containers[0].env[0].name":           "A",
+		"spec.containers[0].volumeMounts[0].name":  "data",
+		"spec.containers[0].volumeMounts[1].name":  "logs",
+		"spec.containers[0].securityContext.user":  1000,
+		"metadata.annotations.instance":            "web",
+		"spec.template.spec.tolerations[0].effect": "NoSchedule",
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"spec.ports[0].protocol", ""},
+		{"spec.ports[1].port", "spec.ports[1]"},
+		{"spec.containers[0].env[1].name", "spec.containers[0].env[1]"},
+		{"spec.containers[0].volumeMounts[1].readOnly", ""},
+		{"spec.containers[1].name", "spec.containers[1]"},
+		{"spec.containers[0].args[0]", ""},
+		{"spec.volumes[0].name", ""},
+		{"metadata.annotations.revision", ""},
+		{"spec.template.spec.tolerations[1].key", "spec.template.spec.tolerations[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := addedListItem(tt.path, rendered); got != tt.want {
+				t.Errorf("addedListItem() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastAppliedRendering(t *testing.T) {
+	longFile := strings.Repeat("x", lastAppliedStringLimit+1)
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "web-web", Annotations: map[string]string{Instance: "web", LastAppliedAnnotation: "{}"}},
+		Data:       map[string]string{"nginx.conf": longFile, "mime.types": "text/html html"},
+	}
+	rendering, err := lastAppliedRendering(newTestApplicationConfiguration(), "web", configMap)
+	if err != nil {
+		t.Fatalf("lastAppliedRendering() error = %v", err)
+	}
+	want := `{"data":{"mime.types":"text/html html","nginx.conf":"` + LastAppliedHashPrefix + contentHash(longFile) + `"},"metadata":{"annotations":{"instance":"web"},"name":"web-web"}}`
+	if rendering != want {
+		t.Errorf("lastAppliedRendering() = %s, want %s", rendering, want)
+	}
+}
+
+func TestRemovalPatch(t *testing.T) {
+	RegisterBuiltins()
+	ac := newTestApplicationConfiguration()
+	deployment := func(data map[string]string, replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "web", Annotations: map[string]string{Instance: "web"}},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: int32Ptr(replicas),
+				Template: apiv1.PodTemplateSpec{Spec: apiv1.PodSpec{NodeSelector: data}},
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		applied    *appsv1.Deployment
+		rendered   *appsv1.Deployment
+		live       *appsv1.Deployment
+		autoscaled bool
+		want       string
+	}{
+		{
+			name:     "nothing removed",
+			applied:  deployment(map[string]string{"disk": "ssd"}, 1),
+			rendered: deployment(map[string]string{"disk": "hdd"}, 2),
+		},
+		{
+			name:     "key removed from the rendering",
+			applied:  deployment(map[string]string{"disk": "ssd", "zone": "a"}, 1),
+			rendered: deployment(map[string]string{"disk": "ssd"}, 1),
+			want:     `{"spec":{"template":{"spec":{"nodeSelector":{"zone":null}}}}}`,
+		},
+		{
+			name:     "key added to the live object",
+			applied:  deployment(map[string]string{"disk": "ssd"}, 1),
+			rendered: deployment(map[string]string{"disk": "ssd"}, 1),
+			live:     deployment(map[string]string{"disk": "ssd", "zone": "a"}, 1),
+			want:     `{"spec":{"template":{"spec":{"nodeSelector":{"zone":null}}}}}`,
+		},
+		{
+			name:     "key added to the live object and the rendering",
+			applied:  deployment(map[string]string{"disk": "ssd"}, 1),
+			rendered: deployment(map[string]string{"disk": "ssd", "zone": "b"}, 1),
+			live:     deployment(map[string]string{"disk": "ssd", "zone": "a"}, 1),
+		},
+		{
+			name:       "replicas of an autoscaler kept",
+			applied:    deployment(nil, 1),
+			rendered:   deployment(nil, 1),
+			autoscaled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := ac.DeepCopy()
+			applied, err := lastAppliedRendering(ac, "web", tt.applied)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.autoscaled {
+				ac.Spec.Components = []v1alpha1.ComponentConfiguration{{InstanceName: "web", Traits: []v1alpha1.TraitBinding{{Name: TraitAutoScaler}}}}
+			}
+			rendering, err := lastAppliedRendering(ac, "web", tt.rendered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var live runtime.Object
+			if tt.live != nil {
+				live = tt.live
+			}
+			patch, err := removalPatch(ac, "web", tt.rendered, applied, rendering, live)
+			if err != nil {
+				t.Fatalf("removalPatch() error = %v", err)
+			}
+			if string(patch) != tt.want {
+				t.Errorf("removalPatch() = %s, want %s", patch, tt.want)
+			}
+		})
+	}
+}
+
+func TestListInstanceObjects(t *testing.T) {
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+	unlabeled := ownedObjectMeta(ac, "web", "web")
+	unlabeled.Labels = nil
+	tests := []struct {
+		name      string
+		resources []v1alpha1.ResourceStatus
+		want      int
+	}{
+		{"labeled resources", nil, 1},
+		{"resources written before they were labeled", []v1alpha1.ResourceStatus{{NamespacedName: "web", Kind: ServiceKind}}, 2},
+		{"problems of components", []v1alpha1.ResourceStatus{{NamespacedName: "web", Kind: Component}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := ac.DeepCopy()
+			ac.Status.Resources = tt.resources
+			s, _, _ := newTestHandler(nil, []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: ownedObjectMeta(ac, "web", "web")},
+				&apiv1.Service{ObjectMeta: unlabeled},
+			}, nil)
+			objects, err := listInstanceObjects(s, ac)
+			if err != nil {
+				t.Fatalf("listInstanceObjects() error = %v", err)
+			}
+			if len(objects["web"]) != tt.want {
+				t.Errorf("listInstanceObjects() = %d objects, want %d", len(objects["web"]), tt.want)
+			}
+		})
+	}
+}
+
+func TestDriftPolicies(t *testing.T) {
+	RegisterBuiltins()
+	comp := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeWorker,
+			Containers: []v1alpha1.Container{{
+				Name:   "web",
+				Image:  "nginx:1",
+				Config: []v1alpha1.ConfigFile{{Path: "/etc/nginx/nginx.conf", Value: strings.Repeat("x", lastAppliedStringLimit+1)}},
+			}},
+		},
+	}
+	tests := []struct {
+		policy    string
+		wantData  []string
+		wantEvent string
+	}{
+		{DriftEnforce, []string{"nginx.conf"}, DriftReverted},
+		{DriftWarn, []string{"extra", "nginx.conf"}, Drifted},
+		{DriftIgnore, []string{"extra", "nginx.conf"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+			ac.Annotations[DriftPolicyAnnotation] = tt.policy
+			s, k8sclient, _ := newTestHandler([]runtime.Object{comp, ac}, nil, nil)
+			if err := s.Handle(nil, ac.DeepCopy(), oam.CreateOrUpdate); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			// the ConfigMap is edited
+			configMaps := k8sclient.CoreV1().ConfigMaps(ac.Namespace)
+			configMap, err := configMaps.Get("web-web", v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if configMap.Labels[ApplicationLabel] != ac.Name {
+				t.Errorf("ConfigMap labels = %v, want %s=%s", configMap.Labels, ApplicationLabel, ac.Name)
+			}
+			configMap.Data["extra"] = "added"
+			if _, err := configMaps.Update(configMap); err != nil {
+				t.Fatal(err)
+			}
+			recorder := record.NewFakeRecorder(100)
+			s.Recorder = recorder
+			live, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Handle(nil, live, oam.CreateOrUpdate); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			configMap, err = configMaps.Get("web-web", v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for k := range configMap.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantData) {
+				t.Errorf("ConfigMap data keys = %v, want %v", keys, tt.wantData)
+			}
+			var event string
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "data.extra") {
+					event = strings.Fields(e)[1]
+				}
+			}
+			if event != tt.wantEvent {
+				t.Errorf("drift event = %q, want %q", event, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestUpdateDriftStatus(t *testing.T) {
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+	s, _, _ := newTestHandler([]runtime.Object{ac}, nil, nil)
+	ac = ac.DeepCopy()
+	drifted := func() *v1alpha1.ApplicationConfiguration {
+		t.Helper()
+		live, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return live
+	}
+
+	// the condition is patched with the drift
+	d := &driftDetector{drift: []acstatus.DriftStatus{{Kind: ConfigMapKind, Name: "web-web", Fields: []string{"data.extra"}}}}
+	if err := updateDriftStatus(s, ac, d); err != nil {
+		t.Fatalf("updateDriftStatus() error = %v", err)
+	}
+	if live := drifted(); !hasCondition(live, Drifted, DriftDetected, "ConfigMap web-web: data.extra") {
+		t.Errorf("conditions = %+v, want Drifted %s", live.Status.Conditions, DriftDetected)
+	}
+
+	// and cleared once the drift is gone
+	if err := updateDriftStatus(s, ac, &driftDetector{}); err != nil {
+		t.Fatalf("updateDriftStatus() error = %v", err)
+	}
+	live := drifted()
+	for _, c := range live.Status.Conditions {
+		if c.Type == Drifted && c.Status != apiv1.ConditionFalse {
+			t.Errorf("condition Drifted = %+v, want it false", c)
+		}
+	}
+	if !hasCondition(live, Drifted, "", "") {
+		t.Errorf("conditions = %+v, want Drifted false", live.Status.Conditions)
+	}
+}
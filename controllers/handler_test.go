@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	hcfake "hc-oam-controller/client/clientset/versioned/fake"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// newTestApplicationConfiguration returns an ApplicationConfiguration of the namespace default with the components.
+func newTestApplicationConfiguration(components ...v1alpha1.ComponentConfiguration) *v1alpha1.ApplicationConfiguration {
+	return &v1alpha1.ApplicationConfiguration{
+		ObjectMeta: v1.ObjectMeta{Name: "app", Namespace: "default", UID: "app-uid", Annotations: map[string]string{}},
+		Spec:       v1alpha1.ApplicationConfigurationSpec{Components: components},
+	}
+}
+
+// newTestHandler returns an ApplicationConfigurationHandler of fake clients seeded with the objects.
+func newTestHandler(oamObjects, k8sObjects, hcObjects []runtime.Object) (*ApplicationConfigurationHandler, *k8sfake.Clientset, *hcfake.Clientset) {
+	k8sclient := k8sfake.NewSimpleClientset(k8sObjects...)
+	hcclient := hcfake.NewSimpleClientset(hcObjects...)
+	// the handler expects an empty object with NotFound, as returned by the real clients
+	k8sclient.PrependReactor("get", "*", getOrEmpty(k8sclient.Tracker()))
+	hcclient.PrependReactor("get", "*", getOrEmpty(hcclient.Tracker()))
+	return &ApplicationConfigurationHandler{
+		Name:      "test",
+		Oamclient: oamfake.NewSimpleClientset(oamObjects...),
+		K8sclient: k8sclient,
+		Hcclient:  hcclient,
+		Recorder:  record.NewFakeRecorder(100),
+	}, k8sclient, hcclient
+}
+
+// ownedObjectMeta returns the metadata of an object of the instance owned by the ApplicationConfiguration.
+func ownedObjectMeta(ac *v1alpha1.ApplicationConfiguration, name, instance string) v1.ObjectMeta {
+	return v1.ObjectMeta{
+		Name:            name,
+		Namespace:       ac.Namespace,
+		OwnerReferences: []v1.OwnerReference{*v1.NewControllerRef(ac, v1alpha1.SchemeGroupVersion.WithKind(ApplicationConfigurationKind))},
+		Labels:          map[string]string{ApplicationLabel: ac.Name},
+		Annotations:     map[string]string{"application": ac.Name, Instance: instance, Role: "workload"},
+	}
+}
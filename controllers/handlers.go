@@ -1,22 +1,68 @@
 package controllers
 
 import (
+	"time"
+
 	hcversioned "hc-oam-controller/client/clientset/versioned"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
 	"k8s.io/client-go/kubernetes"
 )
 
 type ApplicationConfigurationHandler struct {
+	Name      string
+	Oamclient versioned.Interface
+	K8sclient kubernetes.Interface
+	Hcclient  hcversioned.Interface
+	// Dynamicclient writes the objects of WorkloadTypes rendered from a template
+	Dynamicclient dynamic.Interface
+	// Reader reads the WorkloadTypes from the cache, they are listed by Oamclient without it
+	Reader   client.Reader
+	Recorder record.EventRecorder
+	// RevisionHistoryLimit is the number of revisions kept of each ApplicationConfiguration
+	RevisionHistoryLimit int
+	// Enqueuer reconciles the ApplicationConfigurations again while they wait, they are not requeued without it
+	Enqueuer *ApplicationEnqueuer
+	// RequeueInterval is the interval of the reconciles while waiting, DefaultRequeueInterval if not set
+	RequeueInterval time.Duration
+	// rollback is the revision rendered by this reconcile instead of the spec
+	rollback *applicationRevision
+}
+
+type ComponentSchematicHandler struct {
+	Name      string
+	Oamclient versioned.Interface
+	K8sclient kubernetes.Interface
+	// Reader reads the ApplicationConfigurations by the index of the cache, they are listed by Oamclient without it
+	Reader   client.Reader
+	Enqueuer *ApplicationEnqueuer
+	// RevisionHistoryLimit is the number of versions kept of each ComponentSchematic, besides the pinned ones
+	RevisionHistoryLimit int
+}
+
+type TraitDefinitionHandler struct {
+	Name      string
+	Oamclient versioned.Interface
+	Reader    client.Reader
+	Enqueuer  *ApplicationEnqueuer
+}
+
+type DeploymentHandler struct {
 	Name      string
 	Oamclient *versioned.Clientset
 	K8sclient *kubernetes.Clientset
-	Hcclient  *hcversioned.Clientset
-	Recorder  record.EventRecorder
 }
 
-type DeploymentHandler struct {
+type DaemonSetHandler struct {
+	Name      string
+	Oamclient *versioned.Clientset
+	K8sclient *kubernetes.Clientset
+}
+
+type StatefulSetHandler struct {
 	Name      string
 	Oamclient *versioned.Clientset
 	K8sclient *kubernetes.Clientset
@@ -46,10 +92,24 @@ type JobHandler struct {
 	K8sclient *kubernetes.Clientset
 }
 
+type CronJobHandler struct {
+	Name      string
+	Oamclient versioned.Interface
+	K8sclient kubernetes.Interface
+}
+
+type TemplateWorkloadHandler struct {
+	Name      string
+	Oamclient *versioned.Clientset
+	Renderer  *TemplateRenderer
+}
+
 type MysqlClusterHandler struct {
 	Name      string
 	Oamclient *versioned.Clientset
-	K8sclient *kubernetes.Clientset
+	K8sclient kubernetes.Interface
+	Hcclient  hcversioned.Interface
+	Recorder  record.EventRecorder
 }
 
 type IngressHandler struct {
@@ -74,10 +134,26 @@ func (s *ApplicationConfigurationHandler) Id() string {
 	return "application-configuration-handler"
 }
 
+func (s *ComponentSchematicHandler) Id() string {
+	return "component-schematic-handler"
+}
+
+func (s *TraitDefinitionHandler) Id() string {
+	return "trait-definition-handler"
+}
+
 func (s *DeploymentHandler) Id() string {
 	return "deployment-handler"
 }
 
+func (s *DaemonSetHandler) Id() string {
+	return "daemonset-handler"
+}
+
+func (s *StatefulSetHandler) Id() string {
+	return "statefulset-handler"
+}
+
 func (s *ServiceHandler) Id() string {
 	return "service-handler"
 }
@@ -94,6 +170,14 @@ func (s *JobHandler) Id() string {
 	return "job-handler"
 }
 
+func (s *CronJobHandler) Id() string {
+	return "cronjob-handler"
+}
+
+func (s *TemplateWorkloadHandler) Id() string {
+	return "template-workload-handler"
+}
+
 func (s *MysqlClusterHandler) Id() string {
 	return "mysqlcluster-handler"
 }
@@ -0,0 +1,400 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Health of a component instance or one of its objects, evaluated from the live objects.
+type Health struct {
+	Ready       bool
+	Progressing bool
+	Degraded    bool
+	Reason      string
+	Message     string
+}
+
+// String returns the display text of the module status, Healthy or Unhealthy.
+func (h Health) String() string {
+	if h.Ready && !h.Degraded {
+		return Healthy
+	}
+	return Unhealthy
+}
+
+func ready() Health {
+	return Health{Ready: true, Reason: ResourcesReady}
+}
+
+func progressing(format string, a ...interface{}) Health {
+	return Health{Progressing: true, Reason: ResourcesProgressing, Message: fmt.Sprintf(format, a...)}
+}
+
+func degraded(format string, a ...interface{}) Health {
+	return Health{Degraded: true, Reason: ResourcesDegraded, Message: fmt.Sprintf(format, a...)}
+}
+
+// objectHealth evaluates the health of one live object.
+func objectHealth(obj runtime.Object) (Health, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return deploymentHealth(o), nil
+	case *appsv1.DaemonSet:
+		return daemonSetHealth(o), nil
+	case *appsv1.StatefulSet:
+		return statefulSetHealth(o), nil
+	case *batchv1.Job:
+		return jobHealth(o), nil
+	case *batchv1beta1.CronJob:
+		// the jobs of a CronJob are owned by the CronJob and come and go with its schedule
+		return ready(), nil
+	case *hcv1alpha1.MysqlCluster:
+		return mysqlClusterHealth(o), nil
+	case *apiv1.PersistentVolumeClaim:
+		switch o.Status.Phase {
+		case apiv1.ClaimBound:
+			return ready(), nil
+		case apiv1.ClaimLost:
+			return degraded("PersistentVolumeClaim %s lost its volume", o.Name), nil
+		}
+		return progressing("PersistentVolumeClaim %s is %s", o.Name, phaseOrPending(string(o.Status.Phase))), nil
+	case *v2beta2.HorizontalPodAutoscaler:
+		if o.Status.CurrentReplicas != o.Status.DesiredReplicas {
+			return progressing("HorizontalPodAutoscaler %s scales from %v to %v replicas", o.Name, o.Status.CurrentReplicas, o.Status.DesiredReplicas), nil
+		}
+		return ready(), nil
+	case *hcv1beta1.HorizontalPodAutoscaler:
+		if o.Status.CurrentReplicas != o.Status.DesiredReplicas {
+			return progressing("HorizontalPodAutoscaler %s scales from %v to %v replicas", o.Name, o.Status.CurrentReplicas, o.Status.DesiredReplicas), nil
+		}
+		return ready(), nil
+	case *apiv1.Service, *apiv1.ConfigMap, *apiv1.Secret, *extensionsv1beta1.Ingress:
+		return ready(), nil
+	}
+	return Health{}, fmt.Errorf("unsupported object %T", obj)
+}
+
+func deploymentHealth(d *appsv1.Deployment) Health {
+	var replicas int32 = 1
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == apiv1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			return degraded("Deployment %s exceeded its progress deadline: %s", d.Name, c.Message)
+		}
+		if c.Type == appsv1.DeploymentReplicaFailure && c.Status == apiv1.ConditionTrue {
+			return degraded("Deployment %s failed to create replicas: %s", d.Name, c.Message)
+		}
+	}
+	if d.Status.ObservedGeneration < d.Generation {
+		return progressing("Deployment %s waits for its rollout to be observed", d.Name)
+	}
+	if d.Status.UpdatedReplicas < replicas {
+		return progressing("Deployment %s has %v/%v updated replicas", d.Name, d.Status.UpdatedReplicas, replicas)
+	}
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return progressing("Deployment %s has %v old replicas pending termination", d.Name, d.Status.Replicas-d.Status.UpdatedReplicas)
+	}
+	if d.Status.AvailableReplicas < replicas || d.Status.ReadyReplicas < replicas {
+		return progressing("Deployment %s has %v/%v ready replicas", d.Name, d.Status.ReadyReplicas, replicas)
+	}
+	return ready()
+}
+
+func daemonSetHealth(d *appsv1.DaemonSet) Health {
+	if d.Status.ObservedGeneration < d.Generation {
+		return progressing("DaemonSet %s waits for its rollout to be observed", d.Name)
+	}
+	if d.Status.UpdatedNumberScheduled < d.Status.DesiredNumberScheduled {
+		return progressing("DaemonSet %s has %v/%v updated pods", d.Name, d.Status.UpdatedNumberScheduled, d.Status.DesiredNumberScheduled)
+	}
+	if d.Status.NumberAvailable < d.Status.DesiredNumberScheduled {
+		return progressing("DaemonSet %s has %v/%v available pods", d.Name, d.Status.NumberAvailable, d.Status.DesiredNumberScheduled)
+	}
+	return ready()
+}
+
+func statefulSetHealth(sts *appsv1.StatefulSet) Health {
+	var replicas int32 = 1
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return progressing("StatefulSet %s waits for its rollout to be observed", sts.Name)
+	}
+	if sts.Status.UpdateRevision != "" && sts.Status.UpdatedReplicas < replicas {
+		return progressing("StatefulSet %s has %v/%v updated replicas", sts.Name, sts.Status.UpdatedReplicas, replicas)
+	}
+	if sts.Status.ReadyReplicas < replicas {
+		return progressing("StatefulSet %s has %v/%v ready replicas", sts.Name, sts.Status.ReadyReplicas, replicas)
+	}
+	return ready()
+}
+
+// mysqlClusterHealth evaluates a MysqlCluster from its phase, the conditions of its members and the
+// failures counted by the mysql operator. Members which are not ready are only degraded after a failure,
+// new members of a running cluster are progressing.
+func mysqlClusterHealth(m *hcv1alpha1.MysqlCluster) Health {
+	switch m.Status.Phase {
+	case hcv1alpha1.ClusterPhaseFailed, hcv1alpha1.ClusterPhaseError:
+		return degraded("MysqlCluster %s is %s: %s", m.Name, m.Status.Phase, m.Status.Reason)
+	case hcv1alpha1.ClusterPhaseRunning:
+	default:
+		if m.Status.FailedCount > 0 {
+			return progressing("MysqlCluster %s is %s after %v failures: %s", m.Name, phaseOrPending(string(m.Status.Phase)), m.Status.FailedCount, m.Status.Reason)
+		}
+		return progressing("MysqlCluster %s is %s", m.Name, phaseOrPending(string(m.Status.Phase)))
+	}
+
+	var replicas int32 = 1
+	if m.Spec.Replicas != nil {
+		replicas = *m.Spec.Replicas
+	}
+	var readyReplicas int32
+	var notReady []string
+	for _, c := range m.Status.Conditions {
+		if c.Status {
+			readyReplicas++
+		} else if c.Reason != "" {
+			notReady = append(notReady, fmt.Sprintf("%s (%s)", c.Name, c.Reason))
+		} else {
+			notReady = append(notReady, c.Name)
+		}
+	}
+	if len(m.Status.Conditions) == 0 && m.Status.Replicas != nil {
+		// members are not reported by every version of the mysql operator
+		readyReplicas = *m.Status.Replicas
+	}
+	if len(notReady) > 0 && m.Status.FailedCount > 0 {
+		return degraded("MysqlCluster %s has failed members after %v failures: %s", m.Name, m.Status.FailedCount, strings.Join(notReady, ", "))
+	}
+	if readyReplicas < replicas {
+		return progressing("MysqlCluster %s has %v/%v ready replicas", m.Name, readyReplicas, replicas)
+	}
+	return ready()
+}
+
+func jobHealth(job *batchv1.Job) Health {
+	for _, c := range job.Status.Conditions {
+		if c.Status != apiv1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobFailed:
+			return degraded("Job %s failed: %s", job.Name, c.Message)
+		case batchv1.JobComplete:
+			return ready()
+		}
+	}
+	// failed pods are retried until the backoff limit is reached and the job gets the Failed condition
+	return progressing("Job %s has %v active, %v succeeded and %v failed pods", job.Name, job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+}
+
+func phaseOrPending(phase string) string {
+	if phase == "" {
+		return "Pending"
+	}
+	return phase
+}
+
+// resourcesHealth evaluates the health of a component instance from its live objects and the resource
+// status recorded by the handler, which reports the objects that could not be rendered or written.
+func resourcesHealth(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return instanceHealth(ac, instanceName, objects, objectHealth)
+}
+
+// instanceHealth is resourcesHealth with the health of the objects evaluated by objectHealth.
+func instanceHealth(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object, objectHealth func(runtime.Object) (Health, error)) (Health, error) {
+	var failures []string
+	for _, r := range ac.Status.Resources {
+		if instanceName != r.Component {
+			continue
+		}
+		switch {
+		case r.Status == PatchFailed || r.Status == CreateFailed:
+			failures = append(failures, fmt.Sprintf("%s %s: %s", r.Kind, r.NamespacedName, r.Status))
+		case r.Kind == TraitKind || r.Kind == Component:
+			// invalid traits and parameters
+			failures = append(failures, fmt.Sprintf("%s %s: %s", r.Kind, r.NamespacedName, r.Status))
+		}
+	}
+	if len(failures) > 0 {
+		return degraded("%s", strings.Join(failures, "; ")), nil
+	}
+	if len(objects) == 0 {
+		return Health{Progressing: true, Reason: ResourcesNotFound, Message: fmt.Sprintf(ResourcesNotFoundMessage, instanceName)}, nil
+	}
+
+	health := ready()
+	var messages []string
+	for _, obj := range objects {
+		h, err := objectHealth(obj)
+		if err != nil {
+			return Health{}, err
+		}
+		if h.Ready {
+			continue
+		}
+		health.Ready = false
+		messages = append(messages, h.Message)
+		if h.Degraded {
+			health.Degraded = true
+			health.Reason = h.Reason
+		} else if !health.Degraded {
+			health.Progressing = true
+			health.Reason = h.Reason
+		}
+	}
+	health.Message = strings.Join(messages, "; ")
+	return health, nil
+}
+
+// listInstanceObjects returns the live objects owned by the ApplicationConfiguration, by instance, listed by their
+// application label. Kinds whose resource is not served, e.g. MysqlClusters without the mysql operator, are skipped.
+// Resources written before they were labeled are listed from the whole namespace, until a reconcile labels them.
+func listInstanceObjects(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) (map[string][]runtime.Object, error) {
+	selector := labels.SelectorFromSet(labels.Set{ApplicationLabel: ac.Name}).String()
+	objects, err := listOwnedObjects(s, ac, v1.ListOptions{LabelSelector: selector})
+	if err != nil || !missesResources(ac, objects) {
+		return objects, err
+	}
+	return listOwnedObjects(s, ac, v1.ListOptions{})
+}
+
+// missesResources returns true if a resource recorded by the status of the ApplicationConfiguration is not listed.
+func missesResources(ac *v1alpha1.ApplicationConfiguration, objects map[string][]runtime.Object) bool {
+	listed := map[string]bool{}
+	for _, instanceObjects := range objects {
+		for _, obj := range instanceObjects {
+			if key, err := getResourceKey(obj); err == nil {
+				listed[key.Kind+"/"+key.Name] = true
+			}
+		}
+	}
+	for _, r := range ac.Status.Resources {
+		// components and traits are recorded by their problems
+		if r.Kind != Component && r.Kind != TraitKind && !listed[r.Kind+"/"+r.NamespacedName] {
+			return true
+		}
+	}
+	return false
+}
+
+// listOwnedObjects lists the objects owned by the ApplicationConfiguration of each kind rendered, by instance.
+func listOwnedObjects(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, options v1.ListOptions) (map[string][]runtime.Object, error) {
+	objects := map[string][]runtime.Object{}
+	for _, list := range ownedObjectLists(s, ac.Namespace) {
+		items, err := list(options)
+		if kindNotServed(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, obj := range items {
+			o, err := meta.Accessor(obj)
+			if err != nil || !v1.IsControlledBy(o, ac.GetObjectMeta()) || o.GetAnnotations()["application"] != ac.Name {
+				continue
+			}
+			instance := o.GetAnnotations()[Instance]
+			objects[instance] = append(objects[instance], obj)
+		}
+	}
+	return objects, nil
+}
+
+// ownedObjectLists returns the list functions of the kinds rendered for ApplicationConfigurations in the namespace.
+func ownedObjectLists(s *ApplicationConfigurationHandler, namespace string) []func(v1.ListOptions) ([]runtime.Object, error) {
+	extract := func(list runtime.Object, err error) ([]runtime.Object, error) {
+		if err != nil {
+			return nil, err
+		}
+		return meta.ExtractList(list)
+	}
+	return []func(v1.ListOptions) ([]runtime.Object, error){
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.AppsV1().Deployments(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.AppsV1().DaemonSets(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.AppsV1().StatefulSets(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.CoreV1().Services(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.ExtensionsV1beta1().Ingresses(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.BatchV1().Jobs(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.BatchV1beta1().CronJobs(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.Hcclient.HarmonycloudV1beta1().HorizontalPodAutoscalers(namespace).List(nil, o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.Hcclient.MysqlV1alpha1().MysqlClusters(namespace).List(nil, o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.CoreV1().PersistentVolumeClaims(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			templateObjects, err := listTemplateObjects(s, namespace, o)
+			var objects []runtime.Object
+			for _, obj := range templateObjects {
+				objects = append(objects, obj)
+			}
+			return objects, err
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.CoreV1().Secrets(namespace).List(o))
+		},
+		func(o v1.ListOptions) ([]runtime.Object, error) {
+			return extract(s.K8sclient.CoreV1().ConfigMaps(namespace).List(o))
+		},
+	}
+}
+
+// replaceObject replaces the object of the instance with the key of obj by obj, so that the health of the instance
+// is evaluated from the objects written by the reconcile rather than from the objects listed before.
+func replaceObject(objects map[string][]runtime.Object, instance string, obj runtime.Object) {
+	key, err := getResourceKey(obj)
+	if objects == nil || err != nil {
+		return
+	}
+	for i, o := range objects[instance] {
+		if k, err := getResourceKey(o); err == nil && k == key {
+			objects[instance][i] = obj
+			return
+		}
+	}
+	objects[instance] = append(objects[instance], obj)
+}
+
+// kindNotServed returns true if a list failed because the API server does not serve the resource, as when its CRD
+// is not installed.
+func kindNotServed(err error) bool {
+	return apierrors.IsNotFound(err) || meta.IsNoMatchError(err)
+}
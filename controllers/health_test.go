@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	acstatus "hc-oam-controller/api/core.oam.dev/v1alpha1/status"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestMysqlClusterHealth(t *testing.T) {
+	member := func(name string, ready bool, reason string) hcv1alpha1.MysqlClusterCondition {
+		return hcv1alpha1.MysqlClusterCondition{Name: name, Type: hcv1alpha1.SlaveMysql, Status: ready, Reason: reason}
+	}
+	tests := []struct {
+		name     string
+		replicas int32
+		status   hcv1alpha1.MysqlClusterStatus
+		want     Health
+	}{
+		{
+			name:     "pending",
+			replicas: 2,
+			want:     progressing("MysqlCluster db is Pending"),
+		},
+		{
+			name:     "creating after failures",
+			replicas: 2,
+			status:   hcv1alpha1.MysqlClusterStatus{Phase: hcv1alpha1.ClusterPhaseCreating, FailedCount: 2, Reason: "init failed"},
+			want:     progressing("MysqlCluster db is Creating after 2 failures: init failed"),
+		},
+		{
+			name:     "failed",
+			replicas: 2,
+			status:   hcv1alpha1.MysqlClusterStatus{Phase: hcv1alpha1.ClusterPhaseFailed, Reason: "no master"},
+			want:     degraded("MysqlCluster db is Failed: no master"),
+		},
+		{
+			name:     "running with all members ready",
+			replicas: 2,
+			status: hcv1alpha1.MysqlClusterStatus{Phase: hcv1alpha1.ClusterPhaseRunning, Conditions: []hcv1alpha1.MysqlClusterCondition{
+				member("db-0", true, ""), member("db-1", true, ""),
+			}},
+			want: ready(),
+		},
+		{
+			name:     "running with a new member",
+			replicas: 2,
+			status: hcv1alpha1.MysqlClusterStatus{Phase: hcv1alpha1.ClusterPhaseRunning, Conditions: []hcv1alpha1.MysqlClusterCondition{
+				member("db-0", true, ""), member("db-1", false, ""),
+			}},
+			want: progressing("MysqlCluster db has 1/2 ready replicas"),
+		},
+		{
+			name:     "running with a failed member",
+			replicas: 2,
+			status: hcv1alpha1.MysqlClusterStatus{Phase: hcv1alpha1.ClusterPhaseRunning, FailedCount: 1, Conditions: []hcv1alpha1.MysqlClusterCondition{
+				member("db-0", true, ""), member("db-1", false, "replication stopped"),
+			}},
+			want: degraded("MysqlCluster db has failed members after 1 failures: db-1 (replication stopped)"),
+		},
+		{
+			name:     "running without members reported",
+			replicas: 2,
+			status:   hcv1alpha1.MysqlClusterStatus{Phase: hcv1alpha1.ClusterPhaseRunning, Replicas: int32Ptr(2)},
+			want:     ready(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &hcv1alpha1.MysqlCluster{
+				ObjectMeta: v1.ObjectMeta{Name: "db"},
+				Spec:       hcv1alpha1.MysqlClusterSpec{Replicas: int32Ptr(tt.replicas)},
+				Status:     tt.status,
+			}
+			if got := mysqlClusterHealth(m); got != tt.want {
+				t.Errorf("mysqlClusterHealth() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonSetHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		generation int64
+		status     appsv1.DaemonSetStatus
+		want       Health
+	}{
+		{
+			name:       "not observed",
+			generation: 2,
+			status:     appsv1.DaemonSetStatus{ObservedGeneration: 1},
+			want:       progressing("DaemonSet agent waits for its rollout to be observed"),
+		},
+		{
+			name:   "updating",
+			status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1},
+			want:   progressing("DaemonSet agent has 1/3 updated pods"),
+		},
+		{
+			name:   "starting",
+			status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+			want:   progressing("DaemonSet agent has 2/3 available pods"),
+		},
+		{
+			name:   "available",
+			status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+			want:   ready(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &appsv1.DaemonSet{ObjectMeta: v1.ObjectMeta{Name: "agent", Generation: tt.generation}, Status: tt.status}
+			if got := daemonSetHealth(d); got != tt.want {
+				t.Errorf("daemonSetHealth() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatefulSetHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		replicas   *int32
+		generation int64
+		status     appsv1.StatefulSetStatus
+		want       Health
+	}{
+		{
+			name:       "not observed",
+			replicas:   int32Ptr(2),
+			generation: 2,
+			status:     appsv1.StatefulSetStatus{ObservedGeneration: 1},
+			want:       progressing("StatefulSet db waits for its rollout to be observed"),
+		},
+		{
+			name:     "updating",
+			replicas: int32Ptr(2),
+			status:   appsv1.StatefulSetStatus{UpdateRevision: "db-2", UpdatedReplicas: 1, ReadyReplicas: 2},
+			want:     progressing("StatefulSet db has 1/2 updated replicas"),
+		},
+		{
+			name:     "starting",
+			replicas: int32Ptr(2),
+			status:   appsv1.StatefulSetStatus{UpdateRevision: "db-1", UpdatedReplicas: 2, ReadyReplicas: 1},
+			want:     progressing("StatefulSet db has 1/2 ready replicas"),
+		},
+		{
+			name:   "one replica by default",
+			status: appsv1.StatefulSetStatus{ReadyReplicas: 0},
+			want:   progressing("StatefulSet db has 0/1 ready replicas"),
+		},
+		{
+			name:     "ready",
+			replicas: int32Ptr(2),
+			status:   appsv1.StatefulSetStatus{UpdateRevision: "db-1", UpdatedReplicas: 2, ReadyReplicas: 2},
+			want:     ready(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sts := &appsv1.StatefulSet{
+				ObjectMeta: v1.ObjectMeta{Name: "db", Generation: tt.generation},
+				Spec:       appsv1.StatefulSetSpec{Replicas: tt.replicas},
+				Status:     tt.status,
+			}
+			if got := statefulSetHealth(sts); got != tt.want {
+				t.Errorf("statefulSetHealth() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleStatusOfWrittenObjects(t *testing.T) {
+	RegisterBuiltins()
+	comp := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeWorker,
+			Containers:   []v1alpha1.Container{{Name: "web", Image: "nginx:1"}},
+		},
+	}
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+	s, k8sclient, _ := newTestHandler([]runtime.Object{comp, ac}, nil, nil)
+	if err := s.Handle(nil, ac.DeepCopy(), oam.CreateOrUpdate); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	// the objects are listed once, the health is evaluated from the Deployment created
+	var lists int
+	for _, action := range k8sclient.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "deployments" {
+			lists++
+		}
+	}
+	if lists != 1 {
+		t.Errorf("Deployments listed %d times, want 1", lists)
+	}
+	live, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message string
+	for _, c := range live.Status.Conditions {
+		if c.Type == acstatus.Progressing && c.Status == apiv1.ConditionTrue {
+			message = c.Message
+		}
+	}
+	if !strings.Contains(message, "Deployment web has 0/1 updated replicas") {
+		t.Errorf("Progressing condition message = %q, want the progress of the Deployment created", message)
+	}
+}
@@ -1,6 +1,11 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -11,6 +16,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func convertDeployment(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) *appsv1.Deployment {
@@ -49,6 +55,156 @@ func convertDeployment(owner v1.OwnerReference, annotations map[string]string, c
 	return deployment
 }
 
+func convertDaemonSet(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) *appsv1.DaemonSet {
+	annotations["role"] = "workload"
+	containers := convertContainers(owner, compConf.InstanceName, comp.Spec.Containers, parameterMap)
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: v1.ObjectMeta{
+			Name: compConf.InstanceName,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+			Labels: map[string]string{
+				"app": compConf.InstanceName,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &v1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": compConf.InstanceName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						"app": compConf.InstanceName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: containers,
+				},
+			},
+		},
+	}
+	return daemonSet
+}
+
+func convertStatefulSet(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) (*appsv1.StatefulSet, error) {
+	annotations["role"] = "workload"
+	containers := convertContainers(owner, compConf.InstanceName, comp.Spec.Containers, parameterMap)
+	claimTemplates, volumes, err := convertVolumeClaimTemplates(comp.Spec.Containers)
+	if err != nil {
+		return nil, err
+	}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: v1.ObjectMeta{
+			Name: compConf.InstanceName,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+			Labels: map[string]string{
+				"app": compConf.InstanceName,
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: compConf.InstanceName,
+			Selector: &v1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": compConf.InstanceName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						"app": compConf.InstanceName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: containers,
+					Volumes:    volumes,
+				},
+			},
+			VolumeClaimTemplates: claimTemplates,
+		},
+	}
+	return statefulSet, nil
+}
+
+// convertVolumeClaimTemplates converts the volumes of the containers into claim templates, one claim per replica.
+// Volumes without a disk or with an ephemeral disk are empty dirs.
+func convertVolumeClaimTemplates(oamContainers []v1alpha1.Container) ([]apiv1.PersistentVolumeClaim, []apiv1.Volume, error) {
+	var claimTemplates []apiv1.PersistentVolumeClaim
+	var volumes []apiv1.Volume
+	converted := map[string]bool{}
+	for _, c := range oamContainers {
+		for _, v := range c.Resources.Volumes {
+			// containers sharing a volume mount the same claim
+			if converted[v.Name] {
+				continue
+			}
+			converted[v.Name] = true
+			if v.Disk == nil || v.Disk.Ephemeral {
+				volumes = append(volumes, apiv1.Volume{
+					Name:         v.Name,
+					VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}},
+				})
+				continue
+			}
+			size, err := resource.ParseQuantity(v.Disk.Required)
+			if err != nil {
+				return nil, nil, fmt.Errorf("disk of volume %s: %v", v.Name, err)
+			}
+			accessMode := apiv1.ReadWriteOnce
+			if getReadOnly(v.AccessMode) {
+				accessMode = apiv1.ReadOnlyMany
+			} else if v.SharingPolicy == v1alpha1.Shared {
+				accessMode = apiv1.ReadWriteMany
+			}
+			claimTemplates = append(claimTemplates, apiv1.PersistentVolumeClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name: v.Name,
+				},
+				Spec: apiv1.PersistentVolumeClaimSpec{
+					AccessModes: []apiv1.PersistentVolumeAccessMode{accessMode},
+					Resources: apiv1.ResourceRequirements{
+						Requests: apiv1.ResourceList{apiv1.ResourceStorage: size},
+					},
+				},
+			})
+		}
+	}
+	return claimTemplates, volumes, nil
+}
+
+// convertHeadlessService converts the governing Service of a StatefulSet, which gives its pods stable DNS names.
+func convertHeadlessService(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic) *apiv1.Service {
+	annotations["role"] = "workload"
+	service := &apiv1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name: compConf.InstanceName,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+			Labels: map[string]string{
+				"app": compConf.InstanceName,
+			},
+		},
+		Spec: apiv1.ServiceSpec{
+			Ports: convertsServicePorts(comp.Spec.Containers),
+			Selector: map[string]string{
+				"app": compConf.InstanceName,
+			},
+			Type:                     "ClusterIP",
+			ClusterIP:                apiv1.ClusterIPNone,
+			PublishNotReadyAddresses: true,
+		},
+	}
+	return service
+}
+
 func convertService(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic) *apiv1.Service {
 	annotations["role"] = "workload"
 	servicePorts := convertsServicePorts(comp.Spec.Containers)
@@ -122,7 +278,34 @@ func convertJob(owner v1.OwnerReference, annotations map[string]string, compConf
 	return job
 }
 
-func convertCornJob(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) *batchv1beta1.CronJob {
+func convertCronJob(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) (*batchv1beta1.CronJob, error) {
+	settings, err := getWorkloadSettings(comp, parameterMap)
+	if err != nil {
+		return nil, err
+	}
+	schedule := settings["schedule"]
+	if schedule == "" {
+		return nil, errors.New("workloadSettings schedule of ScheduledTask is required")
+	}
+	concurrencyPolicy := batchv1beta1.ConcurrencyPolicy(settings["concurrencyPolicy"])
+	switch concurrencyPolicy {
+	case "", batchv1beta1.AllowConcurrent, batchv1beta1.ForbidConcurrent, batchv1beta1.ReplaceConcurrent:
+	default:
+		return nil, fmt.Errorf("workloadSettings concurrencyPolicy %s of ScheduledTask must be one of Allow, Forbid and Replace", concurrencyPolicy)
+	}
+	startingDeadlineSeconds, err := getInt64Setting(settings, "startingDeadlineSeconds")
+	if err != nil {
+		return nil, err
+	}
+	successfulJobsHistoryLimit, err := getInt32Setting(settings, "successfulJobsHistoryLimit")
+	if err != nil {
+		return nil, err
+	}
+	failedJobsHistoryLimit, err := getInt32Setting(settings, "failedJobsHistoryLimit")
+	if err != nil {
+		return nil, err
+	}
+
 	annotations["role"] = "workload"
 	containers := convertContainers(owner, compConf.InstanceName, comp.Spec.Containers, parameterMap)
 	cronJob := &batchv1beta1.CronJob{
@@ -134,8 +317,17 @@ func convertCornJob(owner v1.OwnerReference, annotations map[string]string, comp
 			Annotations: annotations,
 		},
 		Spec: batchv1beta1.CronJobSpec{
-			Schedule: "",
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          concurrencyPolicy,
+			StartingDeadlineSeconds:    startingDeadlineSeconds,
+			SuccessfulJobsHistoryLimit: successfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     failedJobsHistoryLimit,
 			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						CronJobLabel: compConf.InstanceName,
+					},
+				},
 				Spec: batchv1.JobSpec{
 					Template: apiv1.PodTemplateSpec{
 						Spec: apiv1.PodSpec{
@@ -147,7 +339,7 @@ func convertCornJob(owner v1.OwnerReference, annotations map[string]string, comp
 			},
 		},
 	}
-	return cronJob
+	return cronJob, nil
 }
 
 func convertContainers(owner v1.OwnerReference, instanceName string, oamContainers []v1alpha1.Container, parameterMap map[string]string) []apiv1.Container {
@@ -161,7 +353,7 @@ func convertContainers(owner v1.OwnerReference, instanceName string, oamContaine
 			Ports:          convertContainerPorts(c),
 			Env:            convertEnvs(c.Env, parameterMap),
 			Resources:      convertResources(&c.Resources),
-			VolumeMounts:   convertVolumeMounts(instanceName, c.Name, c.Resources.Volumes, c.Config),
+			VolumeMounts:   convertVolumeMounts(instanceName, c.Name, c.Resources.Volumes, c.Config, parameterMap),
 			LivenessProbe:  convertProbe(c.LivenessProbe),
 			ReadinessProbe: convertProbe(c.ReadinessProbe),
 		}
@@ -209,21 +401,32 @@ func convertConfigMap(owner v1.OwnerReference, annotations map[string]string, oa
 		Data: map[string]string{},
 	}
 	for _, f := range oamConfigFile {
-		value := f.Value
-		if &f.FromParam != nil && f.FromParam != "" {
-			value = parameterMap[f.FromParam]
+		value := configFileValue(f, parameterMap)
+		if secretKeySelector(value) != nil {
+			// mounted from the Secret
+			continue
 		}
 		configmap.Data[getConfigFileName(f.Path)] = value
 	}
+	if len(configmap.Data) == 0 {
+		return nil
+	}
 	return configmap
 }
 
+func configFileValue(f v1alpha1.ConfigFile, parameterMap map[string]string) string {
+	if f.FromParam != "" {
+		return parameterMap[f.FromParam]
+	}
+	return f.Value
+}
+
 func getConfigFileName(path string) string {
 	ss := strings.Split(path, "/")
 	return ss[len(ss)-1]
 }
 
-func convertVolumeMounts(instanceName string, containerName string, oamVolumes []v1alpha1.Volume, oamConfigFile []v1alpha1.ConfigFile) []apiv1.VolumeMount {
+func convertVolumeMounts(instanceName string, containerName string, oamVolumes []v1alpha1.Volume, oamConfigFile []v1alpha1.ConfigFile, parameterMap map[string]string) []apiv1.VolumeMount {
 	var volumeMounts []apiv1.VolumeMount
 	for _, v := range oamVolumes {
 		volumeMount := apiv1.VolumeMount{
@@ -242,11 +445,76 @@ func convertVolumeMounts(instanceName string, containerName string, oamVolumes [
 			MountPath: f.Path,
 			SubPath:   getConfigFileName(f.Path),
 		}
+		if selector := secretKeySelector(configFileValue(f, parameterMap)); selector != nil {
+			volumeMount.Name = secretVolumeName(selector.Name)
+			volumeMount.SubPath = selector.Key
+			volumeMount.ReadOnly = true
+		}
 		volumeMounts = append(volumeMounts, volumeMount)
 	}
 	return volumeMounts
 }
 
+// convertVolumesFromSecrets returns a volume for every Secret the config files of the containers are mounted from.
+func convertVolumesFromSecrets(oamContainers []v1alpha1.Container, parameterMap map[string]string) []apiv1.Volume {
+	var volumes []apiv1.Volume
+	names := map[string]bool{}
+	for _, c := range oamContainers {
+		for _, f := range c.Config {
+			selector := secretKeySelector(configFileValue(f, parameterMap))
+			if selector == nil || names[selector.Name] {
+				continue
+			}
+			names[selector.Name] = true
+			volumes = append(volumes, apiv1.Volume{
+				Name: secretVolumeName(selector.Name),
+				VolumeSource: apiv1.VolumeSource{
+					Secret: &apiv1.SecretVolumeSource{SecretName: selector.Name},
+				},
+			})
+		}
+	}
+	return volumes
+}
+
+// secretVolumeName returns a volume name of the Secret, which is a DNS label whatever the length of the Secret name.
+func secretVolumeName(secretName string) string {
+	return "secret-" + contentHash(secretName)
+}
+
+// convertSensitiveParameters renders the values of the sensitive parameters into the Secret <instance>-parameters,
+// and returns the parameters with these values replaced by [fromSecret(name,key)]. References are kept as they are.
+func convertSensitiveParameters(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) (map[string]string, *apiv1.Secret) {
+	sensitive := getSensitiveParameters(comp)
+	if len(sensitive) == 0 {
+		return parameterMap, nil
+	}
+	name := compConf.InstanceName + ParametersSecretSuffix
+	secret := &apiv1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name: name,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+		},
+		Type: apiv1.SecretTypeOpaque,
+		Data: map[string][]byte{},
+	}
+	parameters := make(map[string]string, len(parameterMap))
+	for k, v := range parameterMap {
+		if sensitive[k] && secretKeySelector(v) == nil {
+			secret.Data[k] = []byte(v)
+			v = fmt.Sprintf("[fromSecret(%s,%s)]", name, k)
+		}
+		parameters[k] = v
+	}
+	if len(secret.Data) == 0 {
+		return parameters, nil
+	}
+	return parameters, secret
+}
+
 func convertVolumesFromConfig(configMaps []apiv1.ConfigMap) []apiv1.Volume {
 	var volumes []apiv1.Volume
 	for _, c := range configMaps {
@@ -353,6 +621,10 @@ func convertEnvs(oamEnvs []v1alpha1.Env, parameterMap map[string]string) []apiv1
 				env.Value = v
 			}
 		}
+		if selector := secretKeySelector(env.Value); selector != nil {
+			env.Value = ""
+			env.ValueFrom = &apiv1.EnvVarSource{SecretKeyRef: selector}
+		}
 		envs = append(envs, env)
 	}
 	return envs
@@ -384,3 +656,90 @@ func convertResourceList(oamResources *v1alpha1.Resources) apiv1.ResourceList {
 	}
 	return resourceList
 }
+
+// annotateConfigHashes sets the hash of the ConfigMaps and Secrets rendered for the component on the pod templates
+// which mount or reference them. Pods are not updated by changes of subPath mounts and env, so the changed hash
+// rolls them out.
+func annotateConfigHashes(objects []runtime.Object, configMaps []apiv1.ConfigMap) {
+	contents := map[string]interface{}{}
+	for i := range configMaps {
+		contents[ConfigMapKind+"/"+configMaps[i].Name] = []interface{}{configMaps[i].Data, configMaps[i].BinaryData}
+	}
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *apiv1.ConfigMap:
+			contents[ConfigMapKind+"/"+o.Name] = []interface{}{o.Data, o.BinaryData}
+		case *apiv1.Secret:
+			contents[SecretKind+"/"+o.Name] = []interface{}{o.Data, o.StringData}
+		}
+	}
+	for _, obj := range objects {
+		template := getPodTemplateSpec(obj)
+		if template == nil {
+			continue
+		}
+		refs := podConfigReferences(&template.Spec)
+		var content []interface{}
+		for _, ref := range refs {
+			if c, ok := contents[ref]; ok {
+				content = append(content, ref, c)
+			}
+		}
+		if len(content) == 0 {
+			continue
+		}
+		// maps are marshalled with sorted keys
+		data, _ := json.Marshal(content)
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[ConfigChecksumAnnotation] = contentHash(string(data))
+	}
+}
+
+// podConfigReferences returns the sorted ConfigMaps and Secrets, <kind>/<name>, mounted or referenced by env of the pods.
+func podConfigReferences(spec *apiv1.PodSpec) []string {
+	refs := map[string]bool{}
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			refs[ConfigMapKind+"/"+v.ConfigMap.Name] = true
+		}
+		if v.Secret != nil {
+			refs[SecretKind+"/"+v.Secret.SecretName] = true
+		}
+		if v.Projected != nil {
+			for _, source := range v.Projected.Sources {
+				if source.ConfigMap != nil {
+					refs[ConfigMapKind+"/"+source.ConfigMap.Name] = true
+				}
+				if source.Secret != nil {
+					refs[SecretKind+"/"+source.Secret.Name] = true
+				}
+			}
+		}
+	}
+	for _, c := range append(append([]apiv1.Container{}, spec.InitContainers...), spec.Containers...) {
+		for _, e := range c.EnvFrom {
+			if e.ConfigMapRef != nil {
+				refs[ConfigMapKind+"/"+e.ConfigMapRef.Name] = true
+			}
+			if e.SecretRef != nil {
+				refs[SecretKind+"/"+e.SecretRef.Name] = true
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.ConfigMapKeyRef != nil {
+				refs[ConfigMapKind+"/"+e.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				refs[SecretKind+"/"+e.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+	}
+	var sorted []string
+	for ref := range refs {
+		sorted = append(sorted, ref)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
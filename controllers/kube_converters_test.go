@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestConvertVolumeClaimTemplates(t *testing.T) {
+	disk := func(required string) *v1alpha1.Disk {
+		return &v1alpha1.Disk{Required: required}
+	}
+	containers := []v1alpha1.Container{
+		{Name: "db", Resources: v1alpha1.Resources{Volumes: []v1alpha1.Volume{
+			{Name: "data", MountPath: "/var/lib/mysql", Disk: disk("10Gi")},
+			{Name: "shared", MountPath: "/shared", SharingPolicy: v1alpha1.Shared, Disk: disk("1Gi")},
+			{Name: "tmp", MountPath: "/tmp"},
+			{Name: "cache", MountPath: "/cache", Disk: &v1alpha1.Disk{Required: "1Gi", Ephemeral: true}},
+		}}},
+		// the sidecar mounts the shared volume of db and a read only one
+		{Name: "backup", Resources: v1alpha1.Resources{Volumes: []v1alpha1.Volume{
+			{Name: "shared", MountPath: "/shared", SharingPolicy: v1alpha1.Shared, Disk: disk("1Gi")},
+			{Name: "config", MountPath: "/config", AccessMode: v1alpha1.RO, Disk: disk("100Mi")},
+		}}},
+	}
+	claims, volumes, err := convertVolumeClaimTemplates(containers)
+	if err != nil {
+		t.Fatalf("convertVolumeClaimTemplates() error = %v", err)
+	}
+	wantClaims := map[string]apiv1.PersistentVolumeAccessMode{
+		"data":   apiv1.ReadWriteOnce,
+		"shared": apiv1.ReadWriteMany,
+		"config": apiv1.ReadOnlyMany,
+	}
+	if len(claims) != len(wantClaims) {
+		t.Errorf("claim templates = %+v, want %v", claims, wantClaims)
+	}
+	for _, c := range claims {
+		if !reflect.DeepEqual(c.Spec.AccessModes, []apiv1.PersistentVolumeAccessMode{wantClaims[c.Name]}) {
+			t.Errorf("access modes of %s = %v, want %v", c.Name, c.Spec.AccessModes, wantClaims[c.Name])
+		}
+	}
+	if size := claims[0].Spec.Resources.Requests[apiv1.ResourceStorage]; size.Cmp(resource.MustParse("10Gi")) != 0 {
+		t.Errorf("storage of data = %s, want 10Gi", size.String())
+	}
+	var emptyDirs []string
+	for _, v := range volumes {
+		if v.EmptyDir != nil {
+			emptyDirs = append(emptyDirs, v.Name)
+		}
+	}
+	if want := []string{"tmp", "cache"}; !reflect.DeepEqual(emptyDirs, want) {
+		t.Errorf("empty dirs = %v, want %v", emptyDirs, want)
+	}
+
+	// an invalid size fails the conversion
+	invalid := []v1alpha1.Container{{Name: "db", Resources: v1alpha1.Resources{Volumes: []v1alpha1.Volume{{Name: "data", Disk: disk("ten gigs")}}}}}
+	if _, _, err := convertVolumeClaimTemplates(invalid); err == nil {
+		t.Error("convertVolumeClaimTemplates() of an invalid size error = nil, want an error")
+	}
+}
+
+func TestConvertCronJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		settings   string
+		parameters map[string]string
+		want       batchv1beta1.CronJobSpec
+		wantErr    bool
+	}{
+		{
+			name: "all settings",
+			settings: `[{"name": "schedule", "fromParam": "schedule", "default": "0 2 * * *"}, {"name": "concurrencyPolicy", "value": "Forbid"},
+				{"name": "startingDeadlineSeconds", "value": 300}, {"name": "successfulJobsHistoryLimit", "value": 3}, {"name": "failedJobsHistoryLimit", "value": 1}]`,
+			parameters: map[string]string{"schedule": "30 1 * * *"},
+			want: batchv1beta1.CronJobSpec{
+				Schedule:                   "30 1 * * *",
+				ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+				StartingDeadlineSeconds:    int64Ptr(300),
+				SuccessfulJobsHistoryLimit: int32Ptr(3),
+				FailedJobsHistoryLimit:     int32Ptr(1),
+			},
+		},
+		{
+			name:     "defaults of the CronJob",
+			settings: `[{"name": "schedule", "default": "0 2 * * *"}]`,
+			want:     batchv1beta1.CronJobSpec{Schedule: "0 2 * * *"},
+		},
+		{
+			name:    "without a schedule",
+			wantErr: true,
+		},
+		{
+			name:     "invalid concurrency policy",
+			settings: `[{"name": "schedule", "value": "@daily"}, {"name": "concurrencyPolicy", "value": "Queue"}]`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid deadline",
+			settings: `[{"name": "schedule", "value": "@daily"}, {"name": "startingDeadlineSeconds", "value": "soon"}]`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comp := v1alpha1.ComponentSchematic{
+				ObjectMeta: v1.ObjectMeta{Name: "report"},
+				Spec: v1alpha1.ComponentSpec{
+					WorkloadType:     WorkloadTypeScheduledTask,
+					WorkloadSettings: runtime.RawExtension{Raw: []byte(tt.settings)},
+					Containers:       []v1alpha1.Container{{Name: "report", Image: "busybox"}},
+				},
+			}
+			compConf := v1alpha1.ComponentConfiguration{ComponentName: "report", InstanceName: "nightly-report"}
+			cronJob, err := convertCronJob(v1.OwnerReference{}, map[string]string{}, compConf, comp, tt.parameters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convertCronJob() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cronJob.Name != "nightly-report" || cronJob.Annotations[Role] != "workload" {
+				t.Errorf("metadata = %+v, want the instance name and the workload role", cronJob.ObjectMeta)
+			}
+			template := cronJob.Spec.JobTemplate.Spec.Template.Spec
+			if template.RestartPolicy != apiv1.RestartPolicyOnFailure || len(template.Containers) != 1 {
+				t.Errorf("pod template = %+v, want the container restarted on failure", template)
+			}
+			if cronJob.Spec.JobTemplate.Labels[CronJobLabel] != "nightly-report" {
+				t.Errorf("job template labels = %v, want the CronJob", cronJob.Spec.JobTemplate.Labels)
+			}
+			cronJob.Spec.JobTemplate = batchv1beta1.JobTemplateSpec{}
+			if !reflect.DeepEqual(cronJob.Spec, tt.want) {
+				t.Errorf("convertCronJob() = %+v, want %+v", cronJob.Spec, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotateConfigHashes(t *testing.T) {
+	render := func(password string) []runtime.Object {
+		web := &appsv1.Deployment{ObjectMeta: v1.ObjectMeta{Name: "web"}}
+		web.Spec.Template.Spec = apiv1.PodSpec{
+			Volumes: []apiv1.Volume{{Name: "config", VolumeSource: apiv1.VolumeSource{ConfigMap: &apiv1.ConfigMapVolumeSource{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: "web-web"},
+			}}}},
+			Containers: []apiv1.Container{{Name: "web", Env: []apiv1.EnvVar{{Name: "PASSWORD", ValueFrom: &apiv1.EnvVarSource{
+				SecretKeyRef: &apiv1.SecretKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: "web-secret"}, Key: "password"},
+			}}}}},
+		}
+		// the migration mounts a ConfigMap which is not rendered for the component
+		migrate := &batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: "migrate"}}
+		migrate.Spec.Template.Spec.Containers = []apiv1.Container{{Name: "migrate", EnvFrom: []apiv1.EnvFromSource{{
+			ConfigMapRef: &apiv1.ConfigMapEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "shared"}},
+		}}}}
+		return []runtime.Object{
+			web,
+			migrate,
+			&apiv1.Secret{ObjectMeta: v1.ObjectMeta{Name: "web-secret"}, StringData: map[string]string{"password": password}},
+		}
+	}
+	hash := func(config, password string) (string, string) {
+		objects := render(password)
+		annotateConfigHashes(objects, []apiv1.ConfigMap{{ObjectMeta: v1.ObjectMeta{Name: "web-web"}, Data: map[string]string{"port": config}}})
+		return objects[0].(*appsv1.Deployment).Spec.Template.Annotations[ConfigChecksumAnnotation],
+			objects[1].(*batchv1.Job).Spec.Template.Annotations[ConfigChecksumAnnotation]
+	}
+
+	web, migrate := hash("80", "secret")
+	if web == "" || migrate != "" {
+		t.Fatalf("hashes = %q, %q, want the hash of web only", web, migrate)
+	}
+	if again, _ := hash("80", "secret"); again != web {
+		t.Errorf("hash of the same config = %q, want %q", again, web)
+	}
+	if changed, _ := hash("8080", "secret"); changed == web {
+		t.Errorf("hash of a changed ConfigMap = %q, want a new hash", changed)
+	}
+	if changed, _ := hash("80", "rotated"); changed == web {
+		t.Errorf("hash of a changed Secret = %q, want a new hash", changed)
+	}
+}
+
+func TestPodConfigReferences(t *testing.T) {
+	spec := &apiv1.PodSpec{
+		Volumes: []apiv1.Volume{
+			{Name: "config", VolumeSource: apiv1.VolumeSource{ConfigMap: &apiv1.ConfigMapVolumeSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "web"}}}},
+			{Name: "tls", VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{SecretName: "tls"}}},
+			{Name: "all", VolumeSource: apiv1.VolumeSource{Projected: &apiv1.ProjectedVolumeSource{Sources: []apiv1.VolumeProjection{
+				{ConfigMap: &apiv1.ConfigMapProjection{LocalObjectReference: apiv1.LocalObjectReference{Name: "web"}}},
+				{Secret: &apiv1.SecretProjection{LocalObjectReference: apiv1.LocalObjectReference{Name: "token"}}},
+			}}}},
+		},
+		InitContainers: []apiv1.Container{{Name: "init", EnvFrom: []apiv1.EnvFromSource{
+			{SecretRef: &apiv1.SecretEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "db"}}},
+		}}},
+		Containers: []apiv1.Container{{Name: "web", Env: []apiv1.EnvVar{
+			{Name: "MODE", ValueFrom: &apiv1.EnvVarSource{ConfigMapKeyRef: &apiv1.ConfigMapKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: "flags"}, Key: "mode"}}},
+			{Name: "HOST", Value: "localhost"},
+		}}},
+	}
+	want := []string{"ConfigMap/flags", "ConfigMap/web", "Secret/db", "Secret/tls", "Secret/token"}
+	if got := podConfigReferences(spec); !reflect.DeepEqual(got, want) {
+		t.Errorf("podConfigReferences() = %v, want %v", got, want)
+	}
+}
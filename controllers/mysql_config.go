@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	mysqlConfigKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	mysqlConfigSizePattern = regexp.MustCompile(`^[0-9]+[KkMmGg]?$`)
+)
+
+// mysqlConfigValidators validates the values of the known keys of my.cnf, keys are normalized to underscores.
+var mysqlConfigValidators = map[string]func(string) error{
+	"innodb_buffer_pool_size":      validateMysqlSize,
+	"innodb_buffer_pool_instances": validateMysqlInt(1, 64),
+	"innodb_log_file_size":         validateMysqlSize,
+	"max_allowed_packet":           validateMysqlSize,
+	"max_connections":              validateMysqlInt(1, 100000),
+	"max_user_connections":         validateMysqlInt(0, 100000),
+	"thread_cache_size":            validateMysqlInt(0, 16384),
+	"long_query_time":              validateMysqlNumber,
+	"slow_query_log":               validateMysqlSwitch,
+	"general_log":                  validateMysqlSwitch,
+}
+
+// mysqlConfigParameter is a key of my.cnf set by the workload setting config.<section>.<key>.
+type mysqlConfigParameter struct {
+	Section string
+	Key     string
+	Value   string
+}
+
+// getMysqlConfigParameters returns the my.cnf parameters of the workload settings, sorted by section and key.
+func getMysqlConfigParameters(settings map[string]string) ([]mysqlConfigParameter, error) {
+	var params []mysqlConfigParameter
+	for name, value := range settings {
+		if !strings.HasPrefix(name, MysqlConfigSettingPrefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(name, MysqlConfigSettingPrefix), ".", 2)
+		if len(parts) != 2 || !mysqlConfigKeyPattern.MatchString(parts[0]) || !mysqlConfigKeyPattern.MatchString(parts[1]) {
+			return nil, fmt.Errorf("invalid workloadSettings %s, must be %s<section>.<key>", name, MysqlConfigSettingPrefix)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("workloadSettings %s must be a single line", name)
+		}
+		if validate, ok := mysqlConfigValidators[normalizeMysqlKey(parts[1])]; ok {
+			if err := validate(value); err != nil {
+				return nil, fmt.Errorf("invalid workloadSettings %s: %v", name, err)
+			}
+		}
+		params = append(params, mysqlConfigParameter{Section: parts[0], Key: parts[1], Value: value})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].Section != params[j].Section {
+			return params[i].Section < params[j].Section
+		}
+		return params[i].Key < params[j].Key
+	})
+	return params, nil
+}
+
+// renderMysqlConfig merges the parameters over the base template. A key the template sets in the same section,
+// spelled with - or _, is replaced in place, other keys are appended to their section in order.
+func renderMysqlConfig(base string, params []mysqlConfigParameter) string {
+	lines := strings.Split(strings.TrimRight(base, "\n"), "\n")
+	if base == "" {
+		lines = nil
+	}
+	// the index of the last key of each section, keys are inserted after it
+	sectionEnds := map[string]int{}
+	keyLines := map[string]int{}
+	section := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			sectionEnds[section] = i
+			continue
+		}
+		key := trimmed
+		if idx := strings.Index(trimmed, "="); idx >= 0 {
+			key = trimmed[:idx]
+		}
+		keyLines[section+"."+normalizeMysqlKey(strings.TrimSpace(key))] = i
+		sectionEnds[section] = i
+	}
+
+	inserted := map[int][]string{}
+	var appended []string
+	appendedSection := ""
+	for _, p := range params {
+		if i, ok := keyLines[p.Section+"."+normalizeMysqlKey(p.Key)]; ok {
+			line := lines[i]
+			if idx := strings.Index(line, "="); idx >= 0 {
+				// keep the spacing of the template after the =
+				value := line[idx+1:]
+				lines[i] = line[:idx+1] + value[:len(value)-len(strings.TrimLeft(value, " \t"))] + p.Value
+			} else {
+				lines[i] = line + " = " + p.Value
+			}
+			continue
+		}
+		if end, ok := sectionEnds[p.Section]; ok {
+			inserted[end] = append(inserted[end], p.Key+" = "+p.Value)
+			continue
+		}
+		if appendedSection != p.Section {
+			appended = append(appended, "", "["+p.Section+"]")
+			appendedSection = p.Section
+		}
+		appended = append(appended, p.Key+" = "+p.Value)
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		b.WriteString(line + "\n")
+		for _, l := range inserted[i] {
+			b.WriteString(l + "\n")
+		}
+	}
+	for i, line := range appended {
+		if i == 0 && len(lines) == 0 {
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func normalizeMysqlKey(key string) string {
+	return strings.ToLower(strings.Replace(key, "-", "_", -1))
+}
+
+func validateMysqlSize(value string) error {
+	if !mysqlConfigSizePattern.MatchString(value) {
+		return fmt.Errorf("%q must be a size in bytes with an optional K, M or G suffix", value)
+	}
+	return nil
+}
+
+func validateMysqlInt(min, max int64) func(string) error {
+	return func(value string) error {
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || i < min || i > max {
+			return fmt.Errorf("%q must be an integer between %v and %v", value, min, max)
+		}
+		return nil
+	}
+}
+
+func validateMysqlNumber(value string) error {
+	if f, err := strconv.ParseFloat(value, 64); err != nil || f < 0 {
+		return fmt.Errorf("%q must be a non-negative number", value)
+	}
+	return nil
+}
+
+func validateMysqlSwitch(value string) error {
+	switch strings.ToUpper(value) {
+	case "ON", "OFF", "1", "0":
+		return nil
+	}
+	return fmt.Errorf("%q must be ON or OFF", value)
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetMysqlConfigParameters(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		want     []mysqlConfigParameter
+		wantErr  bool
+	}{
+		{
+			name: "sorted by section and key",
+			settings: map[string]string{
+				"config":                             "[mysqld]\n",
+				"replicas":                           "2",
+				"config.mysqld.max_connections":      "500",
+				"config.mysqld.innodb-log-file-size": "512M",
+				"config.client.port":                 "3306",
+			},
+			want: []mysqlConfigParameter{
+				{"client", "port", "3306"},
+				{"mysqld", "innodb-log-file-size", "512M"},
+				{"mysqld", "max_connections", "500"},
+			},
+		},
+		{
+			name:     "unknown keys are not validated",
+			settings: map[string]string{"config.mysqld.sql_mode": "STRICT_TRANS_TABLES"},
+			want:     []mysqlConfigParameter{{"mysqld", "sql_mode", "STRICT_TRANS_TABLES"}},
+		},
+		{
+			name:     "missing key",
+			settings: map[string]string{"config.mysqld": "500"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid section",
+			settings: map[string]string{"config.my sqld.max_connections": "500"},
+			wantErr:  true,
+		},
+		{
+			name:     "multiple lines",
+			settings: map[string]string{"config.mysqld.init_connect": "SET NAMES utf8\n[client]"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid size",
+			settings: map[string]string{"config.mysqld.innodb_buffer_pool_size": "1T"},
+			wantErr:  true,
+		},
+		{
+			name:     "integer out of range",
+			settings: map[string]string{"config.mysqld.max-connections": "0"},
+			wantErr:  true,
+		},
+		{
+			name:     "negative number",
+			settings: map[string]string{"config.mysqld.long_query_time": "-1"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid switch",
+			settings: map[string]string{"config.mysqld.slow_query_log": "yes"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := getMysqlConfigParameters(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMysqlConfigParameters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("getMysqlConfigParameters() = %v, want %v", params, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMysqlConfig(t *testing.T) {
+	base := "[client]\nport = 3306\n\n[mysqld]\n# tuned for small pods\nmax_connections = 100\nskip-name-resolve\n"
+	tests := []struct {
+		name   string
+		base   string
+		params []mysqlConfigParameter
+		want   string
+	}{
+		{
+			name: "no parameters",
+			base: base,
+			want: base,
+		},
+		{
+			name:   "key replaced in place",
+			base:   base,
+			params: []mysqlConfigParameter{{"mysqld", "max-connections", "500"}},
+			want:   "[client]\nport = 3306\n\n[mysqld]\n# tuned for small pods\nmax_connections = 500\nskip-name-resolve\n",
+		},
+		{
+			name:   "key without spaces replaced",
+			base:   "[mysqld]\nmax_connections=100\n",
+			params: []mysqlConfigParameter{{"mysqld", "max_connections", "500"}},
+			want:   "[mysqld]\nmax_connections=500\n",
+		},
+		{
+			name:   "key without value replaced",
+			base:   base,
+			params: []mysqlConfigParameter{{"mysqld", "skip_name_resolve", "ON"}},
+			want:   "[client]\nport = 3306\n\n[mysqld]\n# tuned for small pods\nmax_connections = 100\nskip-name-resolve = ON\n",
+		},
+		{
+			name:   "key of another section not replaced",
+			base:   base,
+			params: []mysqlConfigParameter{{"mysqld", "port", "3307"}},
+			want:   "[client]\nport = 3306\n\n[mysqld]\n# tuned for small pods\nmax_connections = 100\nskip-name-resolve\nport = 3307\n",
+		},
+		{
+			name:   "keys appended to their section",
+			base:   base,
+			params: []mysqlConfigParameter{{"client", "default-character-set", "utf8mb4"}, {"mysqld", "slow_query_log", "ON"}},
+			want:   "[client]\nport = 3306\ndefault-character-set = utf8mb4\n\n[mysqld]\n# tuned for small pods\nmax_connections = 100\nskip-name-resolve\nslow_query_log = ON\n",
+		},
+		{
+			name:   "new section appended",
+			base:   base,
+			params: []mysqlConfigParameter{{"mysqldump", "quick", "ON"}, {"mysqldump", "max_allowed_packet", "64M"}},
+			want:   base + "\n[mysqldump]\nquick = ON\nmax_allowed_packet = 64M\n",
+		},
+		{
+			name:   "empty template",
+			params: []mysqlConfigParameter{{"mysqld", "max_connections", "500"}, {"mysqld", "slow_query_log", "ON"}},
+			want:   "[mysqld]\nmax_connections = 500\nslow_query_log = ON\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMysqlConfig(tt.base, tt.params); got != tt.want {
+				t.Errorf("renderMysqlConfig() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
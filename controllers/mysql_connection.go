@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// connectionSecretData returns the keys of the connection Secret which the rendering can not know: the host of the
+// master the MysqlCluster reports, and the keys of the credentials Secret named by spec.secretName, except the host
+// and the port. The credentials are left out until the Secret exists, it may be created by the mysql operator.
+func connectionSecretData(k8sclient kubernetes.Interface, mysqlCluster *hcv1alpha1.MysqlCluster, namespace string) (map[string][]byte, error) {
+	data := map[string][]byte{ConnectionHost: []byte(mysqlConnectionHost(mysqlCluster, namespace))}
+	if mysqlCluster.Spec.SecretName == "" {
+		return data, nil
+	}
+	credentials, err := k8sclient.CoreV1().Secrets(namespace).Get(mysqlCluster.Spec.SecretName, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return data, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get Secret %s of MysqlCluster %s: %v", mysqlCluster.Spec.SecretName, mysqlCluster.Name, err)
+	}
+	for k, v := range credentials.Data {
+		if k != ConnectionHost && k != ConnectionPort {
+			data[k] = v
+		}
+	}
+	return data, nil
+}
+
+// completeConnectionSecrets sets the keys of connectionSecretData in the rendered connection Secrets of the
+// MysqlClusters, the host is of the live MysqlCluster once it exists.
+func completeConnectionSecrets(s *ApplicationConfigurationHandler, namespace string, objects []runtime.Object) error {
+	secrets := map[string]*apiv1.Secret{}
+	for _, obj := range objects {
+		if secret, ok := obj.(*apiv1.Secret); ok {
+			secrets[secret.Name] = secret
+		}
+	}
+	for _, obj := range objects {
+		mysqlCluster, ok := obj.(*hcv1alpha1.MysqlCluster)
+		if !ok {
+			continue
+		}
+		secret := secrets[mysqlCluster.Name+ConnectionSecretSuffix]
+		if secret == nil {
+			continue
+		}
+		live, err := s.Hcclient.MysqlV1alpha1().MysqlClusters(namespace).Get(nil, mysqlCluster.Name, v1.GetOptions{})
+		if err == nil {
+			live.Spec.SecretName = mysqlCluster.Spec.SecretName
+			mysqlCluster = live
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+		data, err := connectionSecretData(s.K8sclient, mysqlCluster, namespace)
+		if err != nil {
+			return err
+		}
+		for k, v := range data {
+			secret.Data[k] = v
+		}
+	}
+	return nil
+}
+
+// patchConnectionSecret updates the connection Secret of the MysqlCluster on its changes, which do not reconcile
+// the ApplicationConfiguration, e.g. when the master switched or the credentials Secret was created meanwhile.
+func patchConnectionSecret(k8sclient kubernetes.Interface, ac *v1alpha1.ApplicationConfiguration, mysqlCluster *hcv1alpha1.MysqlCluster) error {
+	secrets := k8sclient.CoreV1().Secrets(mysqlCluster.Namespace)
+	secret, err := secrets.Get(mysqlCluster.Name+ConnectionSecretSuffix, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !v1.IsControlledBy(secret, ac) {
+		return nil
+	}
+	data, err := connectionSecretData(k8sclient, mysqlCluster, mysqlCluster.Namespace)
+	if err != nil {
+		return err
+	}
+	changed := map[string][]byte{}
+	for k, v := range data {
+		if !bytes.Equal(secret.Data[k], v) {
+			changed[k] = v
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"data": changed})
+	if err != nil {
+		return err
+	}
+	_, err = secrets.Patch(secret.Name, types.MergePatchType, patch)
+	return err
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestMysqlCluster(secretName, master string) *hcv1alpha1.MysqlCluster {
+	mysqlCluster := &hcv1alpha1.MysqlCluster{
+		ObjectMeta: v1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: hcv1alpha1.MysqlClusterSpec{
+			SecretName:     secretName,
+			BusinessDeploy: []hcv1alpha1.BusinessDeploy{{Database: "shop", User: "app", Pwd: "plaintext"}},
+		},
+	}
+	if master != "" {
+		mysqlCluster.Status.Conditions = []hcv1alpha1.MysqlClusterCondition{
+			{Name: "db-0", Type: hcv1alpha1.SlaveMysql},
+			{Name: master, Type: hcv1alpha1.MasterMysql},
+		}
+	}
+	return mysqlCluster
+}
+
+func TestConvertMysqlConnectionSecret(t *testing.T) {
+	tests := []struct {
+		name         string
+		mysqlCluster *hcv1alpha1.MysqlCluster
+		wantHost     string
+		wantPassword bool
+	}{
+		{"password of the spec", newTestMysqlCluster("", ""), "db.default.svc", true},
+		{"password of the credentials Secret", newTestMysqlCluster("db-secret", ""), "db.default.svc", false},
+		{"master reported", newTestMysqlCluster("", "db-1"), "db-1.db.default.svc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := convertMysqlConnectionSecret(v1.OwnerReference{}, map[string]string{}, "default", tt.mysqlCluster)
+			if host := string(secret.Data[ConnectionHost]); host != tt.wantHost {
+				t.Errorf("convertMysqlConnectionSecret() host = %s, want %s", host, tt.wantHost)
+			}
+			if _, ok := secret.Data[ConnectionPassword]; ok != tt.wantPassword {
+				t.Errorf("convertMysqlConnectionSecret() has password = %v, want %v", ok, tt.wantPassword)
+			}
+		})
+	}
+}
+
+func TestConnectionSecretData(t *testing.T) {
+	credentials := &apiv1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "db-secret", Namespace: "default"},
+		Data:       map[string][]byte{ConnectionPassword: []byte("secret"), ConnectionHost: []byte("elsewhere")},
+	}
+	tests := []struct {
+		name         string
+		mysqlCluster *hcv1alpha1.MysqlCluster
+		objects      []runtime.Object
+		want         map[string][]byte
+	}{
+		{
+			name:         "no credentials Secret named",
+			mysqlCluster: newTestMysqlCluster("", "db-1"),
+			want:         map[string][]byte{ConnectionHost: []byte("db-1.db.default.svc")},
+		},
+		{
+			name:         "credentials copied except the host",
+			mysqlCluster: newTestMysqlCluster("db-secret", ""),
+			objects:      []runtime.Object{credentials},
+			want:         map[string][]byte{ConnectionHost: []byte("db.default.svc"), ConnectionPassword: []byte("secret")},
+		},
+		{
+			name:         "credentials Secret not created yet",
+			mysqlCluster: newTestMysqlCluster("db-secret", ""),
+			want:         map[string][]byte{ConnectionHost: []byte("db.default.svc")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := connectionSecretData(k8sfake.NewSimpleClientset(tt.objects...), tt.mysqlCluster, "default")
+			if err != nil {
+				t.Fatalf("connectionSecretData() error = %v", err)
+			}
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Errorf("connectionSecretData() = %v, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchConnectionSecret(t *testing.T) {
+	ac := newTestApplicationConfiguration()
+	secret := &apiv1.Secret{
+		ObjectMeta: ownedObjectMeta(ac, "db"+ConnectionSecretSuffix, "db"),
+		Data:       map[string][]byte{ConnectionHost: []byte("db-0.db.default.svc"), ConnectionUser: []byte("app")},
+	}
+	k8sclient := k8sfake.NewSimpleClientset(secret)
+	// the master switched to db-1
+	if err := patchConnectionSecret(k8sclient, ac, newTestMysqlCluster("", "db-1")); err != nil {
+		t.Fatalf("patchConnectionSecret() error = %v", err)
+	}
+	result, err := k8sclient.CoreV1().Secrets("default").Get(secret.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]byte{ConnectionHost: []byte("db-1.db.default.svc"), ConnectionUser: []byte("app")}
+	if !reflect.DeepEqual(result.Data, want) {
+		t.Errorf("connection Secret data = %v, want %v", result.Data, want)
+	}
+}
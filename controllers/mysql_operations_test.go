@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyMysqlOperations(t *testing.T) {
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		wantSpec        hcv1alpha1.MysqlClusterSpec
+		wantAnnotations map[string]string
+		wantErr         bool
+	}{
+		{
+			name:            "no operations",
+			annotations:     map[string]string{SwitchoverAnnotation: "other=other-1"},
+			wantAnnotations: map[string]string{Instance: "db"},
+		},
+		{
+			name:            "switchover",
+			annotations:     map[string]string{SwitchoverAnnotation: "other=other-1, db=db-1"},
+			wantSpec:        hcv1alpha1.MysqlClusterSpec{SwitchMaster: hcv1alpha1.ClusterSwitch{Master: "db-1"}},
+			wantAnnotations: map[string]string{Instance: "db", SwitchoverAnnotation: "db-1"},
+		},
+		{
+			name:            "forced switchover",
+			annotations:     map[string]string{ForceSwitchoverAnnotation: "db=db-2"},
+			wantSpec:        hcv1alpha1.MysqlClusterSpec{SwitchMaster: hcv1alpha1.ClusterSwitch{Master: "db-2", ForceSwitched: true}},
+			wantAnnotations: map[string]string{Instance: "db", ForceSwitchoverAnnotation: "db-2"},
+		},
+		{
+			name:        "both switchovers",
+			annotations: map[string]string{SwitchoverAnnotation: "db=db-1", ForceSwitchoverAnnotation: "db=db-2"},
+			wantErr:     true,
+		},
+		{
+			name:            "pause",
+			annotations:     map[string]string{PauseAnnotation: "db=TRUE"},
+			wantSpec:        hcv1alpha1.MysqlClusterSpec{Paused: true},
+			wantAnnotations: map[string]string{Instance: "db", PauseAnnotation: "true"},
+		},
+		{
+			name:            "resume",
+			annotations:     map[string]string{PauseAnnotation: "db=false"},
+			wantAnnotations: map[string]string{Instance: "db", PauseAnnotation: "false"},
+		},
+		{
+			name:        "invalid pause",
+			annotations: map[string]string{PauseAnnotation: "db=soon"},
+			wantErr:     true,
+		},
+		{
+			name:            "migrate",
+			annotations:     map[string]string{MigrateAnnotation: "db=db-0:node-2"},
+			wantSpec:        hcv1alpha1.MysqlClusterSpec{ProblemPodName: "db-0", TargetNodeName: "node-2"},
+			wantAnnotations: map[string]string{Instance: "db", MigrateAnnotation: "db-0:node-2"},
+		},
+		{
+			name:        "migrate without a node",
+			annotations: map[string]string{MigrateAnnotation: "db=db-0"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the annotations are shared with the other objects of the instance
+			shared := map[string]string{Instance: "db"}
+			mysqlCluster := &hcv1alpha1.MysqlCluster{ObjectMeta: v1.ObjectMeta{Name: "db", Annotations: shared}}
+			err := applyMysqlOperations(tt.annotations, mysqlCluster)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyMysqlOperations() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(shared) != 1 {
+				t.Errorf("shared annotations = %v, want them unchanged", shared)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(mysqlCluster.Spec, tt.wantSpec) {
+				t.Errorf("spec = %+v, want %+v", mysqlCluster.Spec, tt.wantSpec)
+			}
+			if !reflect.DeepEqual(mysqlCluster.Annotations, tt.wantAnnotations) {
+				t.Errorf("annotations = %v, want %v", mysqlCluster.Annotations, tt.wantAnnotations)
+			}
+		})
+	}
+}
+
+func TestPreserveMysqlOperations(t *testing.T) {
+	// the live MysqlCluster was switched over to db-1 and paused, the operator is migrating db-0
+	live := &hcv1alpha1.MysqlCluster{
+		ObjectMeta: v1.ObjectMeta{Name: "db", Annotations: map[string]string{SwitchoverAnnotation: "db-1", PauseAnnotation: "true"}},
+		Spec: hcv1alpha1.MysqlClusterSpec{
+			SwitchMaster:   hcv1alpha1.ClusterSwitch{Master: "db-1", Switched: true, Finished: true},
+			Paused:         true,
+			ProblemPodName: "db-0",
+			TargetNodeName: "node-1",
+			ForceSwitched:  true,
+			MigratePolicy:  hcv1alpha1.MigratePolicy{Started: true, Backed: true},
+		},
+	}
+	operatorFields := func(spec hcv1alpha1.MysqlClusterSpec) hcv1alpha1.MysqlClusterSpec {
+		spec.ForceSwitched = true
+		spec.MigratePolicy.Started = true
+		spec.MigratePolicy.Backed = true
+		return spec
+	}
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		spec           hcv1alpha1.MysqlClusterSpec
+		wantSpec       hcv1alpha1.MysqlClusterSpec
+		wantOperations []string
+	}{
+		{
+			name:     "without operations the live fields are kept",
+			wantSpec: live.Spec,
+		},
+		{
+			name:        "a done switchover is not requested again",
+			annotations: map[string]string{SwitchoverAnnotation: "db-1", PauseAnnotation: "true"},
+			spec:        hcv1alpha1.MysqlClusterSpec{SwitchMaster: hcv1alpha1.ClusterSwitch{Master: "db-1"}, Paused: true},
+			wantSpec:    live.Spec,
+		},
+		{
+			name:        "new switchover",
+			annotations: map[string]string{SwitchoverAnnotation: "db-2"},
+			spec:        hcv1alpha1.MysqlClusterSpec{SwitchMaster: hcv1alpha1.ClusterSwitch{Master: "db-2"}},
+			wantSpec: operatorFields(hcv1alpha1.MysqlClusterSpec{
+				SwitchMaster:   hcv1alpha1.ClusterSwitch{Master: "db-2"},
+				Paused:         true,
+				ProblemPodName: "db-0",
+				TargetNodeName: "node-1",
+			}),
+			wantOperations: []string{SwitchoverAnnotation},
+		},
+		{
+			name:        "new forced switchover",
+			annotations: map[string]string{ForceSwitchoverAnnotation: "db-2"},
+			spec:        hcv1alpha1.MysqlClusterSpec{SwitchMaster: hcv1alpha1.ClusterSwitch{Master: "db-2", ForceSwitched: true}},
+			wantSpec: operatorFields(hcv1alpha1.MysqlClusterSpec{
+				SwitchMaster:   hcv1alpha1.ClusterSwitch{Master: "db-2", ForceSwitched: true},
+				Paused:         true,
+				ProblemPodName: "db-0",
+				TargetNodeName: "node-1",
+			}),
+			wantOperations: []string{ForceSwitchoverAnnotation},
+		},
+		{
+			name:        "resume",
+			annotations: map[string]string{PauseAnnotation: "false"},
+			wantSpec: operatorFields(hcv1alpha1.MysqlClusterSpec{
+				SwitchMaster:   live.Spec.SwitchMaster,
+				ProblemPodName: "db-0",
+				TargetNodeName: "node-1",
+			}),
+			wantOperations: []string{PauseAnnotation},
+		},
+		{
+			name:        "new migration",
+			annotations: map[string]string{MigrateAnnotation: "db-2:node-3"},
+			spec:        hcv1alpha1.MysqlClusterSpec{ProblemPodName: "db-2", TargetNodeName: "node-3"},
+			wantSpec: operatorFields(hcv1alpha1.MysqlClusterSpec{
+				SwitchMaster:   live.Spec.SwitchMaster,
+				Paused:         true,
+				ProblemPodName: "db-2",
+				TargetNodeName: "node-3",
+			}),
+			wantOperations: []string{MigrateAnnotation},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mysqlCluster := &hcv1alpha1.MysqlCluster{ObjectMeta: v1.ObjectMeta{Name: "db", Annotations: tt.annotations}, Spec: tt.spec}
+			operations := preserveMysqlOperations(mysqlCluster, live)
+			if !reflect.DeepEqual(operations, tt.wantOperations) {
+				t.Errorf("preserveMysqlOperations() = %v, want %v", operations, tt.wantOperations)
+			}
+			if !reflect.DeepEqual(mysqlCluster.Spec, tt.wantSpec) {
+				t.Errorf("spec = %+v, want %+v", mysqlCluster.Spec, tt.wantSpec)
+			}
+		})
+	}
+}
+
+func TestMysqlClusterPatch(t *testing.T) {
+	// a resume and a new switchover set fields to false, which omitempty would drop
+	mysqlCluster := &hcv1alpha1.MysqlCluster{
+		ObjectMeta: v1.ObjectMeta{Name: "db"},
+		Spec: hcv1alpha1.MysqlClusterSpec{
+			Replicas:     int32Ptr(2),
+			SwitchMaster: hcv1alpha1.ClusterSwitch{Master: "db-2"},
+		},
+	}
+	data, err := mysqlClusterPatch(mysqlCluster)
+	if err != nil {
+		t.Fatalf("mysqlClusterPatch() error = %v", err)
+	}
+	var patch struct {
+		Metadata v1.ObjectMeta          `json:"metadata"`
+		Spec     map[string]interface{} `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		t.Fatal(err)
+	}
+	if patch.Metadata.Name != "db" || patch.Spec["replicas"] != float64(2) {
+		t.Errorf("patch = %s, want the fields of the MysqlCluster", data)
+	}
+	if paused, ok := patch.Spec["paused"]; !ok || paused != false {
+		t.Errorf("paused = %v, want false", paused)
+	}
+	wantSwitch := map[string]interface{}{"switched": false, "finished": false, "forceSwitched": false, "master": "db-2", "switchPod": ""}
+	if !reflect.DeepEqual(patch.Spec["clusterSwitch"], wantSwitch) {
+		t.Errorf("clusterSwitch = %v, want %v", patch.Spec["clusterSwitch"], wantSwitch)
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sort"
+	"strings"
+)
+
+var (
+	pruneLog = ctrl.Log.WithName("prune")
+)
+
+type resourceKey struct {
+	ApiVersion string
+	Kind       string
+	Name       string
+}
+
+// desiredResources records the resources rendered for an ApplicationConfiguration during one reconcile.
+// Resources of kept instances are never pruned, e.g. when their rendering failed.
+type desiredResources struct {
+	resources map[resourceKey]bool
+	kept      map[string]bool
+}
+
+func newDesiredResources() *desiredResources {
+	return &desiredResources{
+		resources: map[resourceKey]bool{},
+		kept:      map[string]bool{},
+	}
+}
+
+func (d *desiredResources) add(apiVersion, kind, name string) {
+	d.resources[resourceKey{ApiVersion: apiVersion, Kind: kind, Name: name}] = true
+}
+
+func (d *desiredResources) keep(instance string) {
+	d.kept[instance] = true
+}
+
+func (d *desiredResources) prunable(ac *v1alpha1.ApplicationConfiguration, apiVersion, kind string, obj v1.Object) bool {
+	if !v1.IsControlledBy(obj, ac.GetObjectMeta()) {
+		return false
+	}
+	annotations := obj.GetAnnotations()
+	if annotations["application"] != ac.Name || annotations[Role] == "" {
+		return false
+	}
+	if d.kept[annotations[Instance]] {
+		return false
+	}
+	return !d.resources[resourceKey{ApiVersion: apiVersion, Kind: kind, Name: obj.GetName()}]
+}
+
+// pruneResources deletes the live resources owned by the ApplicationConfiguration which were not rendered
+// in this reconcile, in reverse order of creation, and drops them from the status.
+func pruneResources(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, desired *desiredResources, instanceObjects map[string][]runtime.Object) error {
+	var objects []runtime.Object
+	for _, instance := range sortedInstances(instanceObjects) {
+		objects = append(objects, instanceObjects[instance]...)
+	}
+	sortObjects(objects)
+	for i := len(objects) - 1; i >= 0; i-- {
+		key, err := getResourceKey(objects[i])
+		if err != nil {
+			continue
+		}
+		o, err := meta.Accessor(objects[i])
+		if err != nil || !desired.prunable(ac, key.ApiVersion, key.Kind, o) {
+			continue
+		}
+		err = deleteOwnedObject(s, ac.Namespace, objects[i], v1.DeletePropagationBackground)
+		if err := prunedResource(s, ac, strings.ToLower(key.Kind), key.Kind, o, err); err != nil {
+			return err
+		}
+	}
+
+	// drop the status of instances which have been removed from the spec
+	instances := map[string]bool{}
+	for _, compConf := range ac.Spec.Components {
+		instances[compConf.InstanceName] = true
+	}
+	var resources []v1alpha1.ResourceStatus
+	for _, r := range ac.Status.Resources {
+		if instances[r.Component] {
+			resources = append(resources, r)
+		}
+	}
+	ac.Status.Resources = resources
+	var modules []v1alpha1.ModuleStatus
+	for _, m := range ac.Status.Modules {
+		if instances[m.NamespacedName] {
+			modules = append(modules, m)
+		}
+	}
+	ac.Status.Modules = modules
+	return nil
+}
+
+func prunedResource(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, resource string, kind string, obj v1.Object, err error) error {
+	if err != nil {
+		pruneLog.Info("Resource prune failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", obj.GetAnnotations()[Instance], kind, obj.GetName(), "Error", err)
+		s.Recorder.Event(ac, apiv1.EventTypeWarning, Failed, err.Error())
+		return err
+	}
+	pruneLog.Info("Resource pruned.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", obj.GetAnnotations()[Instance], kind, obj.GetName())
+	s.Recorder.Event(ac, apiv1.EventTypeNormal, Pruned, fmt.Sprintf(MessageResourcePruned, resource, obj.GetName()))
+	removeResourceStatus(&ac.Status.Resources, obj.GetName(), kind, obj.GetAnnotations()[Instance])
+	return nil
+}
+
+// sortedInstances returns the instances of the objects sorted by name.
+func sortedInstances(objects map[string][]runtime.Object) []string {
+	var instances []string
+	for instance := range objects {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	return instances
+}
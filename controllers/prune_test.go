@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPruneResources(t *testing.T) {
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+	removed := ownedObjectMeta(ac, "db", "db")
+	kept := ownedObjectMeta(ac, "failed", "failed")
+	foreign := ownedObjectMeta(ac, "foreign", "db")
+	foreign.OwnerReferences = nil
+	s, k8sclient, hcclient := newTestHandler(nil, []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: ownedObjectMeta(ac, "web", "web")},
+		&appsv1.Deployment{ObjectMeta: removed},
+		&apiv1.PersistentVolumeClaim{ObjectMeta: removed},
+		&apiv1.ConfigMap{ObjectMeta: removed},
+		&apiv1.Service{ObjectMeta: kept},
+		&apiv1.Service{ObjectMeta: foreign},
+	}, nil)
+	// the mysql operator is not installed
+	hcclient.PrependReactor("list", "mysqlclusters", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "mysqlclusters"}, "")
+	})
+
+	desired := newDesiredResources()
+	desired.add(DeploymentApiVersion, DeploymentKind, "web")
+	desired.keep("failed")
+	live, err := listInstanceObjects(s, ac)
+	if err != nil {
+		t.Fatalf("listInstanceObjects() error = %v", err)
+	}
+	if err := pruneResources(s, ac, desired, live); err != nil {
+		t.Fatalf("pruneResources() error = %v", err)
+	}
+
+	deployments, _ := k8sclient.AppsV1().Deployments(ac.Namespace).List(v1.ListOptions{})
+	if len(deployments.Items) != 1 || deployments.Items[0].Name != "web" {
+		t.Errorf("Deployments = %v, want web", deployments.Items)
+	}
+	if pvcs, _ := k8sclient.CoreV1().PersistentVolumeClaims(ac.Namespace).List(v1.ListOptions{}); len(pvcs.Items) != 0 {
+		t.Errorf("PersistentVolumeClaims = %v, want none", pvcs.Items)
+	}
+	if configMaps, _ := k8sclient.CoreV1().ConfigMaps(ac.Namespace).List(v1.ListOptions{}); len(configMaps.Items) != 0 {
+		t.Errorf("ConfigMaps = %v, want none", configMaps.Items)
+	}
+	if services, _ := k8sclient.CoreV1().Services(ac.Namespace).List(v1.ListOptions{}); len(services.Items) != 2 {
+		t.Errorf("Services = %v, want the kept and the foreign one", services.Items)
+	}
+}
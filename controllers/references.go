@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	"golang.org/x/time/rate"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// indexes of the cached ApplicationConfigurations, the names of the ComponentSchematics and Traits they refer to
+	ComponentSchematicIndex = "spec.components.componentName"
+	TraitIndex              = "spec.components.traits.name"
+
+	DefaultEnqueueQPS   = 5
+	DefaultEnqueueBurst = 10
+
+	// MaxEnqueueRetries is the number of times a request is retried after its annotation failed
+	MaxEnqueueRetries = 5
+
+	// DefaultRequeueInterval is the interval at which waiting ApplicationConfigurations are reconciled again
+	DefaultRequeueInterval = 10 * time.Second
+)
+
+var (
+	enqueueLog = ctrl.Log.WithName("application-configuration-enqueuer")
+)
+
+// IndexApplicationConfigurations indexes the ApplicationConfigurations of the cache by the names of the
+// ComponentSchematics and Traits they refer to. It must be called before the manager is started.
+func IndexApplicationConfigurations(indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(&v1alpha1.ApplicationConfiguration{}, ComponentSchematicIndex, func(obj runtime.Object) []string {
+		return componentSchematicNames(obj.(*v1alpha1.ApplicationConfiguration))
+	}); err != nil {
+		return err
+	}
+	return indexer.IndexField(&v1alpha1.ApplicationConfiguration{}, TraitIndex, func(obj runtime.Object) []string {
+		return traitNames(obj.(*v1alpha1.ApplicationConfiguration))
+	})
+}
+
+// componentSchematicNames returns the names of the ComponentSchematics the ApplicationConfiguration refers to, pinned or not.
+func componentSchematicNames(ac *v1alpha1.ApplicationConfiguration) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, compConf := range ac.Spec.Components {
+		name, _ := splitComponentName(compConf.ComponentName)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// traitNames returns the names of the Traits the ApplicationConfiguration refers to.
+func traitNames(ac *v1alpha1.ApplicationConfiguration) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, compConf := range ac.Spec.Components {
+		for _, trait := range compConf.Traits {
+			if !seen[trait.Name] {
+				seen[trait.Name] = true
+				names = append(names, trait.Name)
+			}
+		}
+	}
+	return names
+}
+
+// referringApplicationConfigurations returns the ApplicationConfigurations of the namespace, or of all namespaces,
+// which refer to the ComponentSchematic or Trait of the index. They are read from the index of the reader,
+// or listed by the oamclient without a reader.
+func referringApplicationConfigurations(reader client.Reader, oamclient versioned.Interface, namespace, index, name string) ([]v1alpha1.ApplicationConfiguration, error) {
+	if reader != nil {
+		list := new(v1alpha1.ApplicationConfigurationList)
+		if err := reader.List(context.Background(), list, client.InNamespace(namespace), client.MatchingField(index, name)); err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+	list, err := oamclient.CoreV1alpha1().ApplicationConfigurations(namespace).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	names := componentSchematicNames
+	if index == TraitIndex {
+		names = traitNames
+	}
+	var acs []v1alpha1.ApplicationConfiguration
+	for _, ac := range list.Items {
+		for _, n := range names(&ac) {
+			if n == name {
+				acs = append(acs, ac)
+				break
+			}
+		}
+	}
+	return acs, nil
+}
+
+// Handle touches the ApplicationConfigurations which refer to the Trait, so that its properties and workload
+// types are checked again.
+func (s *TraitDefinitionHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
+	trait, ok := obj.(*v1alpha1.Trait)
+	if !ok {
+		return errors.New("type mismatch")
+	}
+	data, _ := json.Marshal(trait.Spec)
+	version := contentHash(string(data))
+	acs, err := referringApplicationConfigurations(s.Reader, s.Oamclient, "", TraitIndex, trait.Name)
+	if err != nil {
+		return err
+	}
+	changed := trait.Name + ComponentVersionSeparator + version
+	for _, ac := range acs {
+		if ac.Annotations[TraitChangedAnnotation] == changed || ac.DeletionTimestamp != nil {
+			continue
+		}
+		s.Enqueuer.Enqueue(EnqueueRequest{
+			Namespace:  ac.Namespace,
+			Name:       ac.Name,
+			Annotation: TraitChangedAnnotation,
+			Value:      changed,
+			Reason:     TraitChanged,
+			Message:    fmt.Sprintf(MessageTraitChanged, trait.Name, version),
+		})
+	}
+	return nil
+}
+
+// EnqueueRequest annotates an ApplicationConfiguration, the update reconciles it.
+type EnqueueRequest struct {
+	Namespace  string
+	Name       string
+	Annotation string
+	Value      string
+	// Reason and Message of the event recorded, none is recorded without a reason
+	Reason  string
+	Message string
+}
+
+// ApplicationEnqueuer reconciles the ApplicationConfigurations affected by a change of a ComponentSchematic or Trait.
+// The ApplicationConfigurations of a namespace are reconciled at a limited rate, so that a change referred to
+// by many ApplicationConfigurations does not reconcile them at once.
+type ApplicationEnqueuer struct {
+	Oamclient versioned.Interface
+	Recorder  record.EventRecorder
+	queue     workqueue.RateLimitingInterface
+	// failures delays the retries of a failed request, apart from the rate of its namespace
+	failures workqueue.RateLimiter
+}
+
+func NewApplicationEnqueuer(oamclient versioned.Interface, recorder record.EventRecorder, qps float64, burst int) *ApplicationEnqueuer {
+	limiter := &namespaceRateLimiter{limit: rate.Limit(qps), burst: burst, limiters: map[string]*rate.Limiter{}}
+	return &ApplicationEnqueuer{
+		Oamclient: oamclient,
+		Recorder:  recorder,
+		queue:     workqueue.NewNamedRateLimitingQueue(limiter, "application-configurations"),
+		failures:  workqueue.NewItemExponentialFailureRateLimiter(time.Second, time.Minute),
+	}
+}
+
+// Enqueue adds the request, after the requests of the namespace before it.
+func (e *ApplicationEnqueuer) Enqueue(r EnqueueRequest) {
+	e.queue.AddRateLimited(r)
+}
+
+// RequeueAfter reconciles the ApplicationConfiguration again after the delay, by annotating it with the time it is due.
+// The requests of the same time are added once, and are not limited by the rate of the namespace.
+func (e *ApplicationEnqueuer) RequeueAfter(namespace, name string, delay time.Duration) {
+	due := time.Now().Add(delay).Truncate(time.Second)
+	e.queue.AddAfter(EnqueueRequest{
+		Namespace:  namespace,
+		Name:       name,
+		Annotation: RequeueAnnotation,
+		Value:      due.UTC().Format(time.RFC3339),
+	}, delay)
+}
+
+// Start processes the requests until the channel is closed, it is run by the manager.
+func (e *ApplicationEnqueuer) Start(stop <-chan struct{}) error {
+	defer e.queue.ShutDown()
+	go wait.Until(func() {
+		for e.processNextRequest() {
+		}
+	}, time.Second, stop)
+	<-stop
+	return nil
+}
+
+func (e *ApplicationEnqueuer) processNextRequest() bool {
+	item, shutdown := e.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer e.queue.Done(item)
+	r := item.(EnqueueRequest)
+	if err := e.annotate(r); err != nil {
+		enqueueLog.Info("Annotate ApplicationConfiguration failed.", "Namespace", r.Namespace, "ApplicationConfiguration", r.Name, "Error", err)
+		if e.failures.NumRequeues(item) < MaxEnqueueRetries {
+			e.queue.AddAfter(item, e.failures.When(item))
+			return true
+		}
+		enqueueLog.Info("ApplicationConfiguration not enqueued, retries exceeded.", "Namespace", r.Namespace, "ApplicationConfiguration", r.Name, r.Annotation, r.Value)
+	}
+	e.failures.Forget(item)
+	return true
+}
+
+func (e *ApplicationEnqueuer) annotate(r EnqueueRequest) error {
+	data, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{r.Annotation: r.Value},
+		},
+	})
+	ac, err := e.Oamclient.CoreV1alpha1().ApplicationConfigurations(r.Namespace).Patch(r.Name, types.MergePatchType, data)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	enqueueLog.Info("ApplicationConfiguration enqueued.", "Namespace", r.Namespace, "ApplicationConfiguration", r.Name, r.Annotation, r.Value)
+	if r.Reason != "" {
+		e.Recorder.Event(ac, apiv1.EventTypeNormal, r.Reason, r.Message)
+	}
+	return nil
+}
+
+// namespaceRateLimiter delays the requests of each namespace by a token bucket of the namespace.
+type namespaceRateLimiter struct {
+	limit    rate.Limit
+	burst    int
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func (l *namespaceRateLimiter) When(item interface{}) time.Duration {
+	namespace := item.(EnqueueRequest).Namespace
+	l.mu.Lock()
+	limiter, ok := l.limiters[namespace]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[namespace] = limiter
+	}
+	l.mu.Unlock()
+	return limiter.Reserve().Delay()
+}
+
+func (l *namespaceRateLimiter) Forget(item interface{}) {}
+
+func (l *namespaceRateLimiter) NumRequeues(item interface{}) int {
+	return 0
+}
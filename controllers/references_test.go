@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestEnqueue(t *testing.T) {
+	changed := func(namespace, name, value string) EnqueueRequest {
+		return EnqueueRequest{Namespace: namespace, Name: name, Annotation: SchematicChangedAnnotation, Value: value, Reason: SchematicChanged}
+	}
+	tests := []struct {
+		name     string
+		requests []EnqueueRequest
+		want     int
+	}{
+		{
+			name:     "same request",
+			requests: []EnqueueRequest{changed("default", "app", "web@v1"), changed("default", "app", "web@v1")},
+			want:     1,
+		},
+		{
+			name:     "other version",
+			requests: []EnqueueRequest{changed("default", "app", "web@v1"), changed("default", "app", "web@v2")},
+			want:     2,
+		},
+		{
+			name:     "other ApplicationConfigurations",
+			requests: []EnqueueRequest{changed("default", "app", "web@v1"), changed("default", "api", "web@v1"), changed("test", "app", "web@v1")},
+			want:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewApplicationEnqueuer(oamfake.NewSimpleClientset(), record.NewFakeRecorder(100), 100, 100)
+			defer e.queue.ShutDown()
+			for _, r := range tt.requests {
+				e.Enqueue(r)
+			}
+			if got := e.queue.Len(); got != tt.want {
+				t.Errorf("enqueued = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessNextRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantPatches int
+		wantEvents  int
+	}{
+		{
+			name:        "annotated",
+			wantPatches: 1,
+			wantEvents:  1,
+		},
+		{
+			name:        "retries of a failed annotation",
+			err:         errors.New("conflict"),
+			wantPatches: MaxEnqueueRetries + 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := newTestApplicationConfiguration()
+			oamclient := oamfake.NewSimpleClientset(ac)
+			if tt.err != nil {
+				oamclient.PrependReactor("patch", "applicationconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.err
+				})
+			}
+			recorder := record.NewFakeRecorder(100)
+			e := NewApplicationEnqueuer(oamclient, recorder, 100, 100)
+			defer e.queue.ShutDown()
+			// the retries are not delayed
+			e.failures = workqueue.NewItemExponentialFailureRateLimiter(0, 0)
+			r := EnqueueRequest{Namespace: ac.Namespace, Name: ac.Name, Annotation: SchematicChangedAnnotation, Value: "web@v1", Reason: SchematicChanged}
+			e.Enqueue(r)
+			for i := 0; e.queue.Len() > 0 && i <= 2*MaxEnqueueRetries; i++ {
+				e.processNextRequest()
+			}
+
+			if e.queue.Len() > 0 || e.failures.NumRequeues(r) > 0 {
+				t.Errorf("queue = %d, retries = %d, want the request dropped", e.queue.Len(), e.failures.NumRequeues(r))
+			}
+			var patches int
+			for _, action := range oamclient.Actions() {
+				if action.GetVerb() == "patch" {
+					patches++
+				}
+			}
+			if patches != tt.wantPatches {
+				t.Errorf("patches = %d, want %d", patches, tt.wantPatches)
+			}
+			if events := len(recorder.Events); events != tt.wantEvents {
+				t.Errorf("events = %d, want %d", events, tt.wantEvents)
+			}
+			if tt.err == nil {
+				live, err := oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+				if err != nil || live.Annotations[SchematicChangedAnnotation] != r.Value {
+					t.Errorf("annotations = %v, %v, want %s=%s", live.Annotations, err, SchematicChangedAnnotation, r.Value)
+				}
+			}
+		})
+	}
+}
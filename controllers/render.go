@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Resolver looks up the ComponentSchematics, Traits and WorkloadTypes an ApplicationConfiguration refers to.
+// Missing objects are reported by a NotFound error of k8s.io/apimachinery/pkg/api/errors.
+type Resolver interface {
+	GetComponentSchematic(namespace, name string) (*v1alpha1.ComponentSchematic, error)
+	GetTrait(name string) (*v1alpha1.Trait, error)
+	// GetWorkloadType returns the WorkloadType of a workload type, e.g. harmonycloud.cn/v1alpha1.Redis.
+	GetWorkloadType(workloadType string) (*v1alpha1.WorkloadType, error)
+}
+
+// clusterResolver looks up the objects in the cluster.
+type clusterResolver struct {
+	Oamclient versioned.Interface
+	// Reader reads the WorkloadTypes from the cache, they are listed by Oamclient without it
+	Reader client.Reader
+}
+
+func (r *clusterResolver) GetComponentSchematic(namespace, name string) (*v1alpha1.ComponentSchematic, error) {
+	return r.Oamclient.CoreV1alpha1().ComponentSchematics(namespace).Get(name, v1.GetOptions{})
+}
+
+func (r *clusterResolver) GetTrait(name string) (*v1alpha1.Trait, error) {
+	return r.Oamclient.CoreV1alpha1().Traits("").Get(name, v1.GetOptions{})
+}
+
+func (r *clusterResolver) GetWorkloadType(workloadType string) (*v1alpha1.WorkloadType, error) {
+	workloadTypes, err := listWorkloadTypes(r.Reader, r.Oamclient)
+	if err != nil {
+		return nil, err
+	}
+	for i := range workloadTypes {
+		if WorkloadTypeName(&workloadTypes[i]) == workloadType {
+			return &workloadTypes[i], nil
+		}
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.Resource("workloadtypes"), workloadType)
+}
+
+func instanceAnnotations(ac *v1alpha1.ApplicationConfiguration, compConf v1alpha1.ComponentConfiguration) map[string]string {
+	return map[string]string{
+		"application": ac.Name,
+		"component":   compConf.ComponentName,
+		"instance":    compConf.InstanceName,
+	}
+}
+
+// labelApplication labels the object with the name of the ApplicationConfiguration, by which its live objects are
+// listed. The labels are copied, the converters share them with selectors.
+func labelApplication(ac *v1alpha1.ApplicationConfiguration, obj runtime.Object) {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	objLabels := map[string]string{}
+	for k, v := range o.GetLabels() {
+		objLabels[k] = v
+	}
+	objLabels[ApplicationLabel] = ac.Name
+	o.SetLabels(objLabels)
+}
+
+// RenderApplicationConfiguration renders the objects of all component instances of the ApplicationConfiguration
+// the same way ApplicationConfigurationHandler does, without a cluster. The problems the handler reports
+// by events are returned as warnings.
+func RenderApplicationConfiguration(ac *v1alpha1.ApplicationConfiguration, resolver Resolver) ([]runtime.Object, []string, error) {
+	owner := *v1.NewControllerRef(ac, v1alpha1.SchemeGroupVersion.WithKind("ApplicationConfiguration"))
+	var objects []runtime.Object
+	var warnings []string
+	// dependencies are rendered first, waiting for them to be healthy is left to the cluster
+	components, dependencyErrs := orderComponents(ac.Spec.Components)
+	for _, compConf := range components {
+		if e, ok := dependencyErrs[compConf.InstanceName]; ok {
+			warnings = append(warnings, e.Message)
+			continue
+		}
+		comp, err := resolveComponentSchematic(resolver, nil, ac.Namespace, compConf.ComponentName)
+		if apierrors.IsNotFound(err) {
+			warnings = append(warnings, fmt.Sprintf(ComponentNotFound, compConf.ComponentName))
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		parameterMap, err := parseParameters(comp.Spec.Parameters, compConf.ParameterValues, ac.Spec.Variables)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf(ParametersInvalidMessage, compConf.InstanceName, err.Error()))
+			continue
+		}
+
+		annotations := instanceAnnotations(ac, compConf)
+		parameterMap, parametersSecret := convertSensitiveParameters(owner, annotations, compConf, *comp, parameterMap)
+		configMaps := convertConfigMaps(owner, annotations, compConf, *comp, parameterMap)
+		for i := range configMaps {
+			objects = append(objects, &configMaps[i])
+		}
+
+		renderer, err := resolveWorkloadRenderer(resolver, comp.Spec.WorkloadType)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		if renderer == nil {
+			warnings = append(warnings, fmt.Sprintf(WorkeloadTypeUndefined, comp.Spec.WorkloadType))
+			continue
+		}
+
+		traits, rejections, err := filterTraits(resolver, compConf, comp.Spec.WorkloadType)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range rejections {
+			warnings = append(warnings, r.Message)
+		}
+		compConf.Traits = traits
+
+		rendered, err := renderer.Render(&RenderContext{
+			Owner:                  owner,
+			Namespace:              ac.Namespace,
+			Annotations:            annotations,
+			ApplicationAnnotations: ac.Annotations,
+			ComponentConfiguration: compConf,
+			Component:              *comp,
+			Parameters:             parameterMap,
+			ConfigMaps:             configMaps,
+		})
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		if parametersSecret != nil {
+			rendered = append(rendered, parametersSecret)
+		}
+		annotateConfigHashes(rendered, configMaps)
+		if getRollout(compConf) != nil {
+			labelRevision(compConf.InstanceName, rendered)
+		}
+		sortObjects(rendered)
+		objects = append(objects, rendered...)
+	}
+	for _, obj := range objects {
+		labelApplication(ac, obj)
+	}
+	return objects, warnings, nil
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRenderApplicationConfiguration(t *testing.T) {
+	RegisterBuiltins()
+	web := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeServer,
+			Parameters:   []v1alpha1.Parameter{{Name: "port", ParameterType: v1alpha1.Number, Default: "80"}},
+			Containers: []v1alpha1.Container{{
+				Name:   "web",
+				Image:  "nginx:1",
+				Ports:  []v1alpha1.Port{{Name: "http", ContainerPort: 80}},
+				Config: []v1alpha1.ConfigFile{{Path: "/etc/nginx/port", FromParam: "port"}},
+			}},
+		},
+	}
+	worker := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "worker", Namespace: "default"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeWorker,
+			Containers:   []v1alpha1.Container{{Name: "worker", Image: "busybox"}},
+		},
+	}
+	scaler := &v1alpha1.Trait{
+		ObjectMeta: v1.ObjectMeta{Name: TraitManualScaler},
+		Spec:       v1alpha1.TraitSpec{AppliesTo: []string{WorkloadTypeServer}},
+	}
+	dependsOn := &v1alpha1.Trait{
+		ObjectMeta: v1.ObjectMeta{Name: TraitDependsOn},
+		Spec:       v1alpha1.TraitSpec{AppliesTo: []string{"*"}},
+	}
+	trait := func(name, properties string) v1alpha1.TraitBinding {
+		return v1alpha1.TraitBinding{Name: name, Properties: runtime.RawExtension{Raw: []byte(properties)}}
+	}
+	ac := newTestApplicationConfiguration(
+		// the worker depends on web and is rendered after it, its scaler does not apply to Workers
+		v1alpha1.ComponentConfiguration{
+			ComponentName: "worker",
+			InstanceName:  "worker",
+			Traits:        []v1alpha1.TraitBinding{trait(TraitDependsOn, `{"instances": ["web"]}`), trait(TraitManualScaler, `{"replicaCount": 2}`)},
+		},
+		v1alpha1.ComponentConfiguration{
+			ComponentName:   "web",
+			InstanceName:    "web",
+			ParameterValues: []v1alpha1.ParameterValue{{Name: "port", Value: "8080"}},
+			Traits:          []v1alpha1.TraitBinding{trait(TraitManualScaler, `{"replicaCount": 3}`)},
+		},
+		v1alpha1.ComponentConfiguration{ComponentName: "api", InstanceName: "api"},
+		v1alpha1.ComponentConfiguration{
+			ComponentName: "worker",
+			InstanceName:  "jobs",
+			Traits:        []v1alpha1.TraitBinding{trait(TraitDependsOn, `{"instances": ["queue"]}`)},
+		},
+	)
+
+	resolver := &clusterResolver{Oamclient: oamfake.NewSimpleClientset(web, worker, scaler, dependsOn)}
+	objects, warnings, err := RenderApplicationConfiguration(ac, resolver)
+	if err != nil {
+		t.Fatalf("RenderApplicationConfiguration() error = %v", err)
+	}
+
+	var rendered []string
+	for _, obj := range objects {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered = append(rendered, fmt.Sprintf("%T %s", obj, o.GetName()))
+		if o.GetLabels()[ApplicationLabel] != ac.Name || o.GetAnnotations()[Instance] == "" {
+			t.Errorf("%T %s labels = %v, annotations = %v, want the application label and the instance", obj, o.GetName(), o.GetLabels(), o.GetAnnotations())
+		}
+		if d, ok := obj.(*appsv1.Deployment); ok && d.Name == "web" && *d.Spec.Replicas != 3 {
+			t.Errorf("replicas of web = %v, want 3 of the manual-scaler", *d.Spec.Replicas)
+		}
+	}
+	wantObjects := []string{"*v1.ConfigMap web-web", "*v1.Deployment web", "*v1.Service web", "*v1.Deployment worker"}
+	if !reflect.DeepEqual(rendered, wantObjects) {
+		t.Errorf("RenderApplicationConfiguration() = %v, want %v", rendered, wantObjects)
+	}
+	// warnings are reported in the order the components are rendered
+	wantWarnings := []string{
+		fmt.Sprintf(TraitNotApplicableMessage, TraitManualScaler, WorkloadTypeWorker, "worker"),
+		fmt.Sprintf(ComponentNotFound, "api"),
+		fmt.Sprintf(DependencyNotFoundMessage, "jobs", "queue"),
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+}
+
+func TestRenderSensitiveParameters(t *testing.T) {
+	RegisterBuiltins()
+	comp := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "api-component",
+			Namespace:   "default",
+			Annotations: map[string]string{SensitiveParametersAnnotation: "apiToken, credentials"},
+		},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeServer,
+			Parameters: []v1alpha1.Parameter{
+				{Name: "apiToken", ParameterType: v1alpha1.String, Required: true},
+				{Name: "credentials", ParameterType: v1alpha1.String, Required: true},
+				{Name: "logLevel", ParameterType: v1alpha1.String, Default: "info"},
+			},
+			Containers: []v1alpha1.Container{{
+				Name:  "server",
+				Image: "nginx:latest",
+				Env:   []v1alpha1.Env{{Name: "API_TOKEN", FromParam: "apiToken"}, {Name: "LOG_LEVEL", FromParam: "logLevel"}},
+				Config: []v1alpha1.ConfigFile{
+					{Path: "/etc/api/credentials.json", FromParam: "credentials"},
+					{Path: "/etc/api/log-level", FromParam: "logLevel"},
+				},
+			}},
+		},
+	}
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{
+		ComponentName: "api-component",
+		InstanceName:  "api",
+		ParameterValues: []v1alpha1.ParameterValue{
+			// references to Secrets are kept
+			{Name: "apiToken", Value: "[fromSecret(api-token,token)]"},
+			{Name: "credentials", Value: `{"user": "api", "password": "changeme"}`},
+		},
+	})
+
+	objects, warnings, err := RenderApplicationConfiguration(ac, &clusterResolver{Oamclient: oamfake.NewSimpleClientset(comp)})
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("RenderApplicationConfiguration() = %v, %v", warnings, err)
+	}
+	var secret *apiv1.Secret
+	var deployment *appsv1.Deployment
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *apiv1.Secret:
+			secret = o
+		case *appsv1.Deployment:
+			deployment = o
+		case *apiv1.ConfigMap:
+			// the sensitive files are mounted from the Secret
+			if want := map[string]string{"log-level": "info"}; !reflect.DeepEqual(o.Data, want) {
+				t.Errorf("ConfigMap %s data = %v, want %v", o.Name, o.Data, want)
+			}
+		}
+	}
+	if secret == nil || secret.Name != "api"+ParametersSecretSuffix {
+		t.Fatalf("Secret = %+v, want the Secret of the sensitive parameters", secret)
+	}
+	if want := map[string][]byte{"credentials": []byte(`{"user": "api", "password": "changeme"}`)}; !reflect.DeepEqual(secret.Data, want) {
+		t.Errorf("Secret data = %s, want %s", secret.Data, want)
+	}
+	if deployment == nil {
+		t.Fatal("no Deployment rendered")
+	}
+	spec := deployment.Spec.Template.Spec
+	wantEnv := []apiv1.EnvVar{
+		{Name: "API_TOKEN", ValueFrom: &apiv1.EnvVarSource{SecretKeyRef: &apiv1.SecretKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: "api-token"}, Key: "token"}}},
+		{Name: "LOG_LEVEL", Value: "info"},
+	}
+	if !reflect.DeepEqual(spec.Containers[0].Env, wantEnv) {
+		t.Errorf("env = %+v, want %+v", spec.Containers[0].Env, wantEnv)
+	}
+	var mounted bool
+	for _, m := range spec.Containers[0].VolumeMounts {
+		if m.MountPath == "/etc/api/credentials.json" {
+			mounted = m.Name == secretVolumeName(secret.Name) && m.SubPath == "credentials" && m.ReadOnly
+		}
+	}
+	var volume bool
+	for _, v := range spec.Volumes {
+		if v.Name == secretVolumeName(secret.Name) {
+			volume = v.Secret != nil && v.Secret.SecretName == secret.Name
+		}
+	}
+	if !mounted || !volume {
+		t.Errorf("volumes = %+v, mounts = %+v, want credentials.json mounted from the Secret", spec.Volumes, spec.Containers[0].VolumeMounts)
+	}
+	for _, obj := range objects {
+		if _, ok := obj.(*apiv1.Secret); !ok && strings.Contains(fmt.Sprintf("%+v", obj), "changeme") {
+			t.Errorf("%T contains the sensitive value", obj)
+		}
+	}
+}
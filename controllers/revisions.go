@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultRevisionHistoryLimit is the number of revisions kept of an ApplicationConfiguration.
+const DefaultRevisionHistoryLimit = 10
+
+// applicationRevision is the spec of an ApplicationConfiguration with the ComponentSchematics it resolved,
+// recorded by the data of a ControllerRevision.
+type applicationRevision struct {
+	Spec                v1alpha1.ApplicationConfigurationSpec `json:"spec"`
+	ComponentSchematics []v1alpha1.ComponentSchematic         `json:"componentSchematics"`
+}
+
+// newApplicationRevision returns the revision of the spec and the ComponentSchematics, which keep their name,
+// resource version, annotations and spec.
+func newApplicationRevision(spec v1alpha1.ApplicationConfigurationSpec, comps []v1alpha1.ComponentSchematic) *applicationRevision {
+	revision := &applicationRevision{Spec: spec}
+	seen := map[string]bool{}
+	for _, comp := range comps {
+		if seen[comp.Name] {
+			continue
+		}
+		seen[comp.Name] = true
+		revision.ComponentSchematics = append(revision.ComponentSchematics, v1alpha1.ComponentSchematic{
+			ObjectMeta: v1.ObjectMeta{
+				Name:            comp.Name,
+				ResourceVersion: comp.ResourceVersion,
+				Annotations:     schematicAnnotations(&comp),
+			},
+			Spec: comp.Spec,
+		})
+	}
+	sort.Slice(revision.ComponentSchematics, func(i, j int) bool {
+		return revision.ComponentSchematics[i].Name < revision.ComponentSchematics[j].Name
+	})
+	return revision
+}
+
+// hash returns the hash of the revision, resource versions are left out so that a rewrite of an unchanged
+// ComponentSchematic does not record a revision.
+func (r *applicationRevision) hash() string {
+	c := *r
+	c.ComponentSchematics = nil
+	for _, comp := range r.ComponentSchematics {
+		comp.ResourceVersion = ""
+		c.ComponentSchematics = append(c.ComponentSchematics, comp)
+	}
+	data, _ := json.Marshal(c)
+	return contentHash(string(data))
+}
+
+// getComponentSchematic returns the ComponentSchematic of the revision.
+func (r *applicationRevision) getComponentSchematic(name string) *v1alpha1.ComponentSchematic {
+	for i := range r.ComponentSchematics {
+		if r.ComponentSchematics[i].Name == name {
+			return &r.ComponentSchematics[i]
+		}
+	}
+	return nil
+}
+
+// listRevisions returns the ControllerRevisions of the ApplicationConfiguration sorted by their revision.
+func listRevisions(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) ([]appsv1.ControllerRevision, error) {
+	selector := labels.SelectorFromSet(labels.Set{ApplicationLabel: ac.Name}).String()
+	list, err := s.K8sclient.AppsV1().ControllerRevisions(ac.Namespace).List(v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	var revisions []appsv1.ControllerRevision
+	for _, r := range list.Items {
+		if v1.IsControlledBy(&r, ac.GetObjectMeta()) {
+			revisions = append(revisions, r)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// recordRevision records the revision of the ApplicationConfiguration by a ControllerRevision <name>-<hash>,
+// and returns its number. A revision equal to a recorded one takes the next number, as a rollout of a
+// StatefulSet does. Revisions beyond the history limit are deleted, the oldest first.
+func recordRevision(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, revision *applicationRevision) (int64, error) {
+	revisions, err := listRevisions(s, ac)
+	if err != nil {
+		return 0, err
+	}
+	client := s.K8sclient.AppsV1().ControllerRevisions(ac.Namespace)
+	name := ac.Name + "-" + revision.hash()
+	var next int64 = 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+
+	var number int64
+	var recorded *appsv1.ControllerRevision
+	for i := range revisions {
+		if revisions[i].Name == name {
+			recorded = &revisions[i]
+		}
+	}
+	if recorded != nil && recorded.Revision == next-1 {
+		number = recorded.Revision
+	} else if recorded != nil {
+		recorded.Revision = next
+		if _, err := client.Update(recorded); err != nil {
+			return 0, err
+		}
+		number = next
+	} else {
+		data, err := json.Marshal(revision)
+		if err != nil {
+			return 0, err
+		}
+		_, err = client.Create(&appsv1.ControllerRevision{
+			ObjectMeta: v1.ObjectMeta{
+				Name:            name,
+				Labels:          map[string]string{ApplicationLabel: ac.Name},
+				OwnerReferences: []v1.OwnerReference{*v1.NewControllerRef(ac, v1alpha1.SchemeGroupVersion.WithKind(ApplicationConfigurationKind))},
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: next,
+		})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return 0, err
+		}
+		number = next
+		revisions = append(revisions, appsv1.ControllerRevision{ObjectMeta: v1.ObjectMeta{Name: name}, Revision: next})
+	}
+
+	limit := s.RevisionHistoryLimit
+	if limit <= 0 {
+		limit = DefaultRevisionHistoryLimit
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	rollback := getRollbackRevision(ac)
+	for i := 0; len(revisions)-i > limit; i++ {
+		if revisions[i].Name == name || revisions[i].Revision == rollback {
+			continue
+		}
+		if err := client.Delete(revisions[i].Name, &v1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return 0, err
+		}
+	}
+	return number, nil
+}
+
+// getRevision returns the recorded revision of the ApplicationConfiguration with the number.
+func getRevision(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, number int64) (*applicationRevision, error) {
+	revisions, err := listRevisions(s, ac)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range revisions {
+		if r.Revision != number {
+			continue
+		}
+		revision := new(applicationRevision)
+		if err := json.Unmarshal(r.Data.Raw, revision); err != nil {
+			return nil, fmt.Errorf("invalid revision %d of %s: %v", number, ac.Name, err)
+		}
+		return revision, nil
+	}
+	return nil, fmt.Errorf("revision %d of %s not found", number, ac.Name)
+}
+
+// getRollbackRevision returns the revision requested by the rollback annotation, or 0.
+func getRollbackRevision(ac *v1alpha1.ApplicationConfiguration) int64 {
+	number, err := strconv.ParseInt(ac.Annotations[RollbackAnnotation], 10, 64)
+	if err != nil || number <= 0 {
+		return 0
+	}
+	return number
+}
+
+// getComponentSchematic returns the ComponentSchematic of the revision rolled back to, or the one in the cluster.
+// Pinned versions are not recorded by the revisions of ApplicationConfigurations.
+func (s *ApplicationConfigurationHandler) getComponentSchematic(namespace, componentName string) (*v1alpha1.ComponentSchematic, error) {
+	if _, version := splitComponentName(componentName); s.rollback != nil && version == "" {
+		if comp := s.rollback.getComponentSchematic(componentName); comp != nil {
+			comp = comp.DeepCopy()
+			comp.Namespace = namespace
+			return comp, nil
+		}
+	}
+	return resolveComponentSchematic(&clusterResolver{Oamclient: s.Oamclient, Reader: s.Reader}, s.K8sclient, namespace, componentName)
+}
+
+// updateRevisionStatus records the revision of the spec and the ComponentSchematics rendered, unless a revision
+// is rolled back to, and writes the current and the last applied revision to the status.
+func updateRevisionStatus(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, comps []v1alpha1.ComponentSchematic) error {
+	if s.rollback == nil {
+		number, err := recordRevision(s, ac, newApplicationRevision(ac.Spec, comps))
+		if err != nil {
+			return err
+		}
+		return patchRevisionStatus(s, ac, number, number)
+	}
+	revisions, err := listRevisions(s, ac)
+	if err != nil {
+		return err
+	}
+	var current int64
+	if len(revisions) > 0 {
+		current = revisions[len(revisions)-1].Revision
+	}
+	applied := getRollbackRevision(ac)
+	s.Recorder.Event(ac, apiv1.EventTypeNormal, RolledBack, fmt.Sprintf(MessageRevisionRendered, applied, ac.Name))
+	return patchRevisionStatus(s, ac, current, applied)
+}
+
+// patchRevisionStatus writes the current and the last applied revision of the ApplicationConfiguration.
+func patchRevisionStatus(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, current, applied int64) error {
+	return patchStatusFields(s.Oamclient, ac, map[string]interface{}{
+		"currentRevision":     current,
+		"lastAppliedRevision": applied,
+	})
+}
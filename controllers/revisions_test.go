@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordRevision(t *testing.T) {
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+	revision := newApplicationRevision(ac.Spec, nil)
+	current := ac.Name + "-" + revision.hash()
+	// controllerRevision returns the recorded revision r<number>, or the current one if the number is negative
+	controllerRevision := func(number int64) runtime.Object {
+		meta := v1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-r%d", ac.Name, number),
+			Namespace:       ac.Namespace,
+			Labels:          map[string]string{"application": ac.Name},
+			OwnerReferences: []v1.OwnerReference{*v1.NewControllerRef(ac, v1alpha1.SchemeGroupVersion.WithKind(ApplicationConfigurationKind))},
+		}
+		if number < 0 {
+			meta.Name = current
+			number = -number
+		}
+		return &appsv1.ControllerRevision{ObjectMeta: meta, Revision: number}
+	}
+	tests := []struct {
+		name       string
+		recorded   []runtime.Object
+		limit      int
+		rollback   string
+		wantNumber int64
+		want       map[string]int64
+	}{
+		{
+			name:       "first revision",
+			limit:      2,
+			wantNumber: 1,
+			want:       map[string]int64{current: 1},
+		},
+		{
+			name:       "latest revision unchanged",
+			recorded:   []runtime.Object{controllerRevision(1), controllerRevision(-2)},
+			limit:      2,
+			wantNumber: 2,
+			want:       map[string]int64{"app-r1": 1, current: 2},
+		},
+		{
+			name:       "oldest revisions pruned",
+			recorded:   []runtime.Object{controllerRevision(1), controllerRevision(2), controllerRevision(3)},
+			limit:      2,
+			wantNumber: 4,
+			want:       map[string]int64{"app-r3": 3, current: 4},
+		},
+		{
+			name:       "recorded revision takes the next number",
+			recorded:   []runtime.Object{controllerRevision(-1), controllerRevision(2), controllerRevision(3)},
+			limit:      2,
+			wantNumber: 4,
+			want:       map[string]int64{"app-r3": 3, current: 4},
+		},
+		{
+			name:       "revision rolled back to kept",
+			recorded:   []runtime.Object{controllerRevision(1), controllerRevision(2), controllerRevision(3)},
+			limit:      2,
+			rollback:   "1",
+			wantNumber: 4,
+			want:       map[string]int64{"app-r1": 1, "app-r3": 3, current: 4},
+		},
+		{
+			name:       "default history limit",
+			recorded:   []runtime.Object{controllerRevision(1), controllerRevision(2)},
+			wantNumber: 3,
+			want:       map[string]int64{"app-r1": 1, "app-r2": 2, current: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := ac.DeepCopy()
+			if tt.rollback != "" {
+				ac.Annotations[RollbackAnnotation] = tt.rollback
+			}
+			s, k8sclient, _ := newTestHandler(nil, tt.recorded, nil)
+			s.RevisionHistoryLimit = tt.limit
+			number, err := recordRevision(s, ac, revision)
+			if err != nil {
+				t.Fatalf("recordRevision() error = %v", err)
+			}
+			if number != tt.wantNumber {
+				t.Errorf("recordRevision() = %d, want %d", number, tt.wantNumber)
+			}
+			list, err := k8sclient.AppsV1().ControllerRevisions(ac.Namespace).List(v1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			revisions := map[string]int64{}
+			for _, r := range list.Items {
+				revisions[r.Name] = r.Revision
+			}
+			if !reflect.DeepEqual(revisions, tt.want) {
+				t.Errorf("revisions = %v, want %v", revisions, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordSchematicRevision(t *testing.T) {
+	comp := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default", UID: "web-uid"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: WorkloadTypeServer,
+			Containers:   []v1alpha1.Container{{Name: "web", Image: "nginx:1"}},
+		},
+	}
+	current := "web-" + schematicVersion(comp)
+	// schematicRevision returns the recorded revision web-<version>, of the current version if it is empty
+	schematicRevision := func(version string, number int64) runtime.Object {
+		name := "web-" + version
+		if version == "" {
+			name = current
+		}
+		return &appsv1.ControllerRevision{
+			ObjectMeta: v1.ObjectMeta{
+				Name:            name,
+				Namespace:       comp.Namespace,
+				Labels:          map[string]string{SchematicLabel: comp.Name},
+				OwnerReferences: []v1.OwnerReference{*v1.NewControllerRef(comp, v1alpha1.SchemeGroupVersion.WithKind("ComponentSchematic"))},
+			},
+			Revision: number,
+		}
+	}
+	// application returns an ApplicationConfiguration of the componentNames
+	application := func(name string, componentNames ...string) runtime.Object {
+		ac := newTestApplicationConfiguration()
+		ac.Name = name
+		for _, componentName := range componentNames {
+			ac.Spec.Components = append(ac.Spec.Components, v1alpha1.ComponentConfiguration{ComponentName: componentName, InstanceName: componentName})
+		}
+		return ac
+	}
+	recorded := []runtime.Object{schematicRevision("v1", 1), schematicRevision("v2", 2), schematicRevision("v3", 3)}
+	tests := []struct {
+		name         string
+		recorded     []runtime.Object
+		acs          []runtime.Object
+		wantEnqueued int
+		want         map[string]int64
+	}{
+		{
+			name:     "oldest versions pruned",
+			recorded: recorded,
+			want:     map[string]int64{"web-v3": 3, current: 4},
+		},
+		{
+			name:         "pinned version kept",
+			recorded:     recorded,
+			acs:          []runtime.Object{application("pinned", "web@v1"), application("floating", "web")},
+			wantEnqueued: 1,
+			want:         map[string]int64{"web-v1": 1, "web-v3": 3, current: 4},
+		},
+		{
+			name:         "pinned and floating components of an ApplicationConfiguration",
+			recorded:     recorded,
+			acs:          []runtime.Object{application("app", "web@v2", "web")},
+			wantEnqueued: 1,
+			want:         map[string]int64{"web-v2": 2, "web-v3": 3, current: 4},
+		},
+		{
+			name:     "pinned versions beyond the history limit",
+			recorded: recorded,
+			acs:      []runtime.Object{application("v1", "web@v1"), application("v2", "web@v2")},
+			want:     map[string]int64{"web-v1": 1, "web-v2": 2, "web-v3": 3, current: 4},
+		},
+		{
+			name:     "pins of other ComponentSchematics",
+			recorded: recorded,
+			acs:      []runtime.Object{application("api", "api@v1")},
+			want:     map[string]int64{"web-v3": 3, current: 4},
+		},
+		{
+			name:     "recorded version takes the next number",
+			recorded: []runtime.Object{schematicRevision("", 1), schematicRevision("v2", 2), schematicRevision("v3", 3)},
+			acs:      []runtime.Object{application("pinned", "web@v2")},
+			want:     map[string]int64{"web-v2": 2, "web-v3": 3, current: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oamclient := oamfake.NewSimpleClientset(tt.acs...)
+			k8sclient := k8sfake.NewSimpleClientset(tt.recorded...)
+			enqueuer := NewApplicationEnqueuer(oamclient, record.NewFakeRecorder(100), 100, 100)
+			s := &ComponentSchematicHandler{Name: "test", Oamclient: oamclient, K8sclient: k8sclient, Enqueuer: enqueuer, RevisionHistoryLimit: 2}
+			if err := s.Handle(nil, comp.DeepCopy(), oam.CreateOrUpdate); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			list, err := k8sclient.AppsV1().ControllerRevisions(comp.Namespace).List(v1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			revisions := map[string]int64{}
+			for _, r := range list.Items {
+				revisions[r.Name] = r.Revision
+			}
+			if !reflect.DeepEqual(revisions, tt.want) {
+				t.Errorf("revisions = %v, want %v", revisions, tt.want)
+			}
+			// the ApplicationConfigurations of the pinned versions are not touched
+			if enqueued := enqueuer.queue.Len(); enqueued != tt.wantEnqueued {
+				t.Errorf("enqueued = %d, want %d", enqueued, tt.wantEnqueued)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const defaultRolloutDeadlineSeconds = 600
+
+var defaultRolloutSteps = []traits2.RolloutStep{{Weight: 20, PauseSeconds: 60}, {Weight: 50, PauseSeconds: 60}}
+
+// getRollout returns the rollout trait of the component instance, or nil if it has none.
+func getRollout(compConf v1alpha1.ComponentConfiguration) *traits2.Rollout {
+	for _, tr := range compConf.Traits {
+		if getTraitName(tr.Name) != TraitRollout {
+			continue
+		}
+		rollout := new(traits2.Rollout)
+		if err := parsePropertiesOfTrait(tr, rollout); err != nil {
+			// invalid properties are reported by applicableTraits
+			continue
+		}
+		if rollout.Strategy == "" {
+			rollout.Strategy = RolloutRollingUpdate
+		}
+		if rollout.ProgressDeadlineSeconds <= 0 {
+			rollout.ProgressDeadlineSeconds = defaultRolloutDeadlineSeconds
+		}
+		if len(rollout.Steps) == 0 {
+			rollout.Steps = defaultRolloutSteps
+		}
+		if rollout.Strategy == RolloutBlueGreen {
+			// the preview runs all replicas, the Service is switched after it was ready for previewSeconds
+			rollout.Steps = []traits2.RolloutStep{{Weight: 100, PauseSeconds: rollout.PreviewSeconds}}
+		}
+		return rollout
+	}
+	return nil
+}
+
+// labelRevision labels the pods of the Deployment of a component instance with a rollout trait by the hash
+// of its pod template, and returns the revision.
+func labelRevision(instanceName string, objects []runtime.Object) string {
+	deployment, _ := findRolloutObjects(instanceName, objects)
+	if deployment == nil {
+		return ""
+	}
+	delete(deployment.Spec.Template.Labels, RevisionLabel)
+	data, _ := json.Marshal(deployment.Spec.Template)
+	revision := contentHash(string(data))
+	deployment.Spec.Template.Labels[RevisionLabel] = revision
+	return revision
+}
+
+// findRolloutObjects returns the Deployment and the Service of the component instance.
+func findRolloutObjects(instanceName string, objects []runtime.Object) (*appsv1.Deployment, *apiv1.Service) {
+	var deployment *appsv1.Deployment
+	var service *apiv1.Service
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			if o.Name == instanceName {
+				deployment = o
+			}
+		case *apiv1.Service:
+			if o.Name == instanceName {
+				service = o
+			}
+		}
+	}
+	return deployment, service
+}
+
+// progressRollout rolls out a new revision of the Deployment of a component instance by a canary or a preview
+// Deployment. The live Deployment keeps its pod template until the revision is promoted, the step of the rollout
+// and when it started are kept by annotations of the canary Deployment. A revision is rolled back when the pods
+// of a step are not ready within the progress deadline or the rollout is aborted. It returns the objects to apply,
+// and a message and the delay of the next check while the rollout progresses, so that the ApplicationConfiguration
+// is requeued when the next step is due.
+func progressRollout(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, compConf v1alpha1.ComponentConfiguration, rollout *traits2.Rollout, objects []runtime.Object) ([]runtime.Object, string, time.Duration, error) {
+	instanceName := compConf.InstanceName
+	deployment, service := findRolloutObjects(instanceName, objects)
+	if deployment == nil {
+		return objects, "", 0, nil
+	}
+	revision := deployment.Spec.Template.Labels[RevisionLabel]
+	if rollout.Strategy != RolloutCanary && rollout.Strategy != RolloutBlueGreen {
+		setRolloutStatus(ac, instanceName, fmt.Sprintf("%s: revision %s", rollout.Strategy, revision))
+		return objects, "", 0, nil
+	}
+
+	deploymentsClient := s.K8sclient.AppsV1().Deployments(ac.Namespace)
+	stable, err := deploymentsClient.Get(deployment.Name, v1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return objects, "", 0, err
+	}
+	if stable == nil || !v1.IsControlledBy(stable, ac.GetObjectMeta()) {
+		stable = nil
+	}
+	canaryName := instanceName + CanarySuffix
+	if rollout.Strategy == RolloutBlueGreen {
+		canaryName = instanceName + PreviewSuffix
+	}
+	canary, err := deploymentsClient.Get(canaryName, v1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return objects, "", 0, err
+	}
+	if canary == nil || !v1.IsControlledBy(canary, ac.GetObjectMeta()) || canary.Spec.Template.Labels[RevisionLabel] != revision {
+		canary = nil
+	}
+
+	total := int32(1)
+	if deployment.Spec.Replicas != nil {
+		total = *deployment.Spec.Replicas
+	}
+	if stable != nil && stable.Spec.Replicas != nil && controlsReplicas(ac, instanceName) {
+		// the stable replicas are kept by the autoscaler, the canary runs on top of them
+		total = *stable.Spec.Replicas
+	}
+	stableRevision := ""
+	if stable != nil {
+		stableRevision = stable.Spec.Template.Labels[RevisionLabel]
+	}
+	selectRevision := func(r string) {
+		if service != nil && rollout.Strategy == RolloutBlueGreen {
+			service.Spec.Selector[RevisionLabel] = r
+		}
+	}
+
+	// the revision is stable, a canary of it is kept until the stable pods are updated
+	if stableRevision == "" || stableRevision == revision {
+		if stableRevision != "" {
+			selectRevision(revision)
+		}
+		if canary != nil && stable != nil && !deploymentHealth(stable).Ready {
+			objects = append(objects, newCanaryDeployment(deployment, canary, canaryName, *canary.Spec.Replicas, canary.Annotations[RolloutStepAnnotation], canary.Annotations[RolloutStepStartedAnnotation]))
+			msg := fmt.Sprintf("%s: promoting revision %s", rollout.Strategy, revision)
+			setRolloutStatus(ac, instanceName, msg)
+			return objects, msg, requeueInterval(s), nil
+		}
+		setRolloutStatus(ac, instanceName, fmt.Sprintf("%s: revision %s", rollout.Strategy, revision))
+		return objects, "", 0, nil
+	}
+
+	promote := func() ([]runtime.Object, string, time.Duration, error) {
+		selectRevision(revision)
+		if canary != nil {
+			objects = append(objects, newCanaryDeployment(deployment, canary, canaryName, *canary.Spec.Replicas, canary.Annotations[RolloutStepAnnotation], canary.Annotations[RolloutStepStartedAnnotation]))
+		}
+		s.Recorder.Event(ac, apiv1.EventTypeNormal, Promoted, fmt.Sprintf(MessagePromoted, revision, instanceName))
+		msg := fmt.Sprintf("%s: promoting revision %s", rollout.Strategy, revision)
+		setRolloutStatus(ac, instanceName, msg)
+		return objects, msg, requeueInterval(s), nil
+	}
+	keepStable := func(replicas int32) {
+		deployment.Spec.Template = *stable.Spec.Template.DeepCopy()
+		deployment.Spec.Replicas = &replicas
+		selectRevision(stableRevision)
+	}
+	rollback := func(reason string) ([]runtime.Object, string, time.Duration, error) {
+		keepStable(total)
+		deployment.Annotations[RolledBackAnnotation] = revision + ":" + stableRevision
+		s.Recorder.Event(ac, apiv1.EventTypeWarning, RolledBack, fmt.Sprintf(MessageRolledBack, revision, instanceName, reason))
+		setRolloutStatus(ac, instanceName, fmt.Sprintf("%s: revision %s rolled back to %s, %s", rollout.Strategy, revision, stableRevision, reason))
+		return objects, "", 0, nil
+	}
+
+	if getInstanceAnnotation(ac.Annotations, RolloutPromoteAnnotation, instanceName) == revision {
+		return promote()
+	}
+	// a rolled back revision is not rolled out again unless it is promoted or the stable revision changed
+	if stable.Annotations[RolledBackAnnotation] == revision+":"+stableRevision {
+		keepStable(total)
+		setRolloutStatus(ac, instanceName, fmt.Sprintf("%s: revision %s rolled back to %s", rollout.Strategy, revision, stableRevision))
+		return objects, "", 0, nil
+	}
+	if getInstanceAnnotation(ac.Annotations, RolloutAbortAnnotation, instanceName) == revision {
+		return rollback("aborted by " + RolloutAbortAnnotation)
+	}
+
+	now := time.Now()
+	step, started := 0, now
+	// the pods of a new step are checked at the interval
+	delay := requeueInterval(s)
+	if canary == nil {
+		s.Recorder.Event(ac, apiv1.EventTypeNormal, Rollout, fmt.Sprintf(MessageRolloutStep, revision, instanceName, 1, len(rollout.Steps), canaryReplicas(total, rollout.Steps[0].Weight), total))
+	} else {
+		if i, err := strconv.Atoi(canary.Annotations[RolloutStepAnnotation]); err == nil && i >= 0 && i < len(rollout.Steps) {
+			step = i
+		}
+		if t, err := time.Parse(time.RFC3339, canary.Annotations[RolloutStepStartedAnnotation]); err == nil {
+			started = t
+		}
+		replicas := canaryReplicas(total, rollout.Steps[step].Weight)
+		ready := *canary.Spec.Replicas == replicas && deploymentHealth(canary).Ready
+		deadline := started.Add(time.Duration(rollout.ProgressDeadlineSeconds) * time.Second)
+		if !ready && now.After(deadline) {
+			return rollback(fmt.Sprintf("%d pods of %s not ready within %ds", replicas, canaryName, rollout.ProgressDeadlineSeconds))
+		}
+		paused := started.Add(time.Duration(rollout.Steps[step].PauseSeconds) * time.Second)
+		if !ready && deadline.Sub(now) < delay {
+			// the pods of the step are checked at the interval until the deadline
+			delay = deadline.Sub(now)
+		} else if ready && now.Before(paused) {
+			// the next step is due once the pause of the ready step is over
+			delay = paused.Sub(now)
+		}
+		if ready && !now.Before(paused) {
+			if step+1 < len(rollout.Steps) {
+				step, started = step+1, now
+				s.Recorder.Event(ac, apiv1.EventTypeNormal, Rollout, fmt.Sprintf(MessageRolloutStep, revision, instanceName, step+1, len(rollout.Steps), canaryReplicas(total, rollout.Steps[step].Weight), total))
+			} else if rollout.AutoPromote == nil || *rollout.AutoPromote {
+				return promote()
+			} else {
+				keepStable(stableReplicas(rollout, total, replicas))
+				objects = append(objects, newCanaryDeployment(deployment, canary, canaryName, replicas, strconv.Itoa(step), canary.Annotations[RolloutStepStartedAnnotation]))
+				if rollout.Strategy == RolloutBlueGreen && service != nil {
+					objects = append(objects, newPreviewService(service, canaryName, revision))
+				}
+				setRolloutStatus(ac, instanceName, fmt.Sprintf("%s: revision %s waits for %s=%s=%s", rollout.Strategy, revision, RolloutPromoteAnnotation, instanceName, revision))
+				return objects, "", 0, nil
+			}
+		}
+	}
+
+	replicas := canaryReplicas(total, rollout.Steps[step].Weight)
+	stepped := newCanaryDeployment(deployment, nil, canaryName, replicas, strconv.Itoa(step), started.UTC().Format(time.RFC3339))
+	keepStable(stableReplicas(rollout, total, replicas))
+	objects = append(objects, stepped)
+	if rollout.Strategy == RolloutBlueGreen && service != nil {
+		objects = append(objects, newPreviewService(service, canaryName, revision))
+	}
+	msg := fmt.Sprintf("%s: revision %s at step %d/%d, %d of %d replicas, stable revision %s", rollout.Strategy, revision, step+1, len(rollout.Steps), replicas, total, stableRevision)
+	setRolloutStatus(ac, instanceName, msg)
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return objects, msg, delay, nil
+}
+
+// canaryReplicas returns the replicas of the canary at a weight, at least one.
+func canaryReplicas(total int32, weight int32) int32 {
+	replicas := (total*weight + 99) / 100
+	if replicas < 1 {
+		replicas = 1
+	}
+	return replicas
+}
+
+// stableReplicas returns the replicas of the stable Deployment while a canary runs, the preview of a blue/green
+// rollout runs besides all stable replicas.
+func stableReplicas(rollout *traits2.Rollout, total int32, canary int32) int32 {
+	if rollout.Strategy == RolloutBlueGreen || canary >= total {
+		return total
+	}
+	return total - canary
+}
+
+// newCanaryDeployment returns the canary or preview Deployment of the rendered Deployment. Its pods are tracked
+// by their own selector, a canary keeps the app label so that the Service sends it a share of the traffic.
+func newCanaryDeployment(deployment *appsv1.Deployment, live *appsv1.Deployment, name string, replicas int32, step string, started string) *appsv1.Deployment {
+	canary := deployment.DeepCopy()
+	if live != nil {
+		// promoted canaries keep their live pod template until they are pruned
+		canary.Spec.Template = *live.Spec.Template.DeepCopy()
+	}
+	canary.Name = name
+	canary.Spec.Replicas = &replicas
+	canary.Spec.Selector = &v1.LabelSelector{MatchLabels: map[string]string{
+		"app":      deployment.Spec.Template.Labels["app"],
+		TrackLabel: name,
+	}}
+	canary.Spec.Template.Labels[TrackLabel] = name
+	delete(canary.Annotations, RolledBackAnnotation)
+	canary.Annotations[RolloutStepAnnotation] = step
+	canary.Annotations[RolloutStepStartedAnnotation] = started
+	return canary
+}
+
+// newPreviewService returns the Service of the preview pods of a blue/green rollout.
+func newPreviewService(service *apiv1.Service, name string, revision string) *apiv1.Service {
+	preview := service.DeepCopy()
+	preview.Name = name
+	preview.Spec.Selector[RevisionLabel] = revision
+	return preview
+}
+
+// setRolloutStatus reports the progress of the rollout in the status of the rollout trait of the instance.
+func setRolloutStatus(ac *v1alpha1.ApplicationConfiguration, instanceName string, status string) {
+	addResourceStatus(&ac.Status.Resources, TraitRollout, OamV1alpha1GroupVersion, TraitKind, instanceName, Trait, status)
+}
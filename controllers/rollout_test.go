@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestProgressRollout(t *testing.T) {
+	canary := traits2.Rollout{Strategy: RolloutCanary, Steps: []traits2.RolloutStep{{Weight: 20, PauseSeconds: 60}, {Weight: 50, PauseSeconds: 60}}}
+	manual := canary
+	manual.AutoPromote = new(bool)
+	blueGreen := traits2.Rollout{Strategy: RolloutBlueGreen, PreviewSeconds: 30}
+	// the live canary or preview of the new revision at a step, started the seconds ago
+	type liveCanary struct {
+		step     string
+		started  int
+		replicas int32
+		ready    bool
+	}
+	tests := []struct {
+		name            string
+		rollout         traits2.Rollout
+		annotations     map[string]string
+		rolledBack      string
+		canary          *liveCanary
+		wantRevision    string
+		wantReplicas    int32
+		wantCanaryStep  string
+		wantCanary      int32
+		wantSelector    string
+		wantPreview     bool
+		wantRolledBack  string
+		wantDelay       time.Duration
+		wantNotRequeued bool
+	}{
+		{
+			name:           "first step",
+			rollout:        canary,
+			wantRevision:   "old",
+			wantReplicas:   8,
+			wantCanaryStep: "0",
+			wantCanary:     2,
+			wantDelay:      DefaultRequeueInterval,
+		},
+		{
+			name:           "step paused",
+			rollout:        canary,
+			canary:         &liveCanary{step: "0", started: 30, replicas: 2, ready: true},
+			wantRevision:   "old",
+			wantReplicas:   8,
+			wantCanaryStep: "0",
+			wantCanary:     2,
+			wantDelay:      30 * time.Second,
+		},
+		{
+			name:           "step advanced after the pause",
+			rollout:        canary,
+			canary:         &liveCanary{step: "0", started: 61, replicas: 2, ready: true},
+			wantRevision:   "old",
+			wantReplicas:   5,
+			wantCanaryStep: "1",
+			wantCanary:     5,
+			wantDelay:      DefaultRequeueInterval,
+		},
+		{
+			name:           "step not ready before the deadline",
+			rollout:        canary,
+			canary:         &liveCanary{step: "0", started: 595, replicas: 2},
+			wantRevision:   "old",
+			wantReplicas:   8,
+			wantCanaryStep: "0",
+			wantCanary:     2,
+			wantDelay:      5 * time.Second,
+		},
+		{
+			name:            "step not ready after the deadline",
+			rollout:         canary,
+			canary:          &liveCanary{step: "0", started: 601, replicas: 2},
+			wantRevision:    "old",
+			wantReplicas:    10,
+			wantRolledBack:  "new:old",
+			wantNotRequeued: true,
+		},
+		{
+			name:           "promoted after the last step",
+			rollout:        canary,
+			canary:         &liveCanary{step: "1", started: 61, replicas: 5, ready: true},
+			wantRevision:   "new",
+			wantReplicas:   10,
+			wantCanaryStep: "1",
+			wantCanary:     5,
+			wantDelay:      DefaultRequeueInterval,
+		},
+		{
+			name:            "waiting for the promotion after the last step",
+			rollout:         manual,
+			canary:          &liveCanary{step: "1", started: 61, replicas: 5, ready: true},
+			wantRevision:    "old",
+			wantReplicas:    5,
+			wantCanaryStep:  "1",
+			wantCanary:      5,
+			wantNotRequeued: true,
+		},
+		{
+			name:           "promoted by the annotation",
+			rollout:        manual,
+			annotations:    map[string]string{RolloutPromoteAnnotation: "web=new"},
+			canary:         &liveCanary{step: "0", started: 10, replicas: 2},
+			wantRevision:   "new",
+			wantReplicas:   10,
+			wantCanaryStep: "0",
+			wantCanary:     2,
+			wantDelay:      DefaultRequeueInterval,
+		},
+		{
+			name:            "aborted by the annotation",
+			rollout:         canary,
+			annotations:     map[string]string{RolloutAbortAnnotation: "web=new"},
+			canary:          &liveCanary{step: "0", started: 10, replicas: 2, ready: true},
+			wantRevision:    "old",
+			wantReplicas:    10,
+			wantRolledBack:  "new:old",
+			wantNotRequeued: true,
+		},
+		{
+			name:            "rolled back revision",
+			rollout:         canary,
+			rolledBack:      "new:old",
+			wantRevision:    "old",
+			wantReplicas:    10,
+			wantNotRequeued: true,
+		},
+		{
+			name:           "blue/green preview",
+			rollout:        blueGreen,
+			wantRevision:   "old",
+			wantReplicas:   10,
+			wantCanaryStep: "0",
+			wantCanary:     10,
+			wantSelector:   "old",
+			wantPreview:    true,
+			wantDelay:      DefaultRequeueInterval,
+		},
+		{
+			name:           "blue/green preview ready",
+			rollout:        blueGreen,
+			canary:         &liveCanary{step: "0", started: 10, replicas: 10, ready: true},
+			wantRevision:   "old",
+			wantReplicas:   10,
+			wantCanaryStep: "0",
+			wantCanary:     10,
+			wantSelector:   "old",
+			wantPreview:    true,
+			wantDelay:      20 * time.Second,
+		},
+		{
+			name:           "blue/green switched",
+			rollout:        blueGreen,
+			canary:         &liveCanary{step: "0", started: 31, replicas: 10, ready: true},
+			wantRevision:   "new",
+			wantReplicas:   10,
+			wantCanaryStep: "0",
+			wantCanary:     10,
+			wantSelector:   "new",
+			wantDelay:      DefaultRequeueInterval,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties, _ := json.Marshal(tt.rollout)
+			compConf := v1alpha1.ComponentConfiguration{
+				ComponentName: "web",
+				InstanceName:  "web",
+				Traits:        []v1alpha1.TraitBinding{{Name: TraitRollout, Properties: runtime.RawExtension{Raw: properties}}},
+			}
+			ac := newTestApplicationConfiguration(compConf)
+			for k, v := range tt.annotations {
+				ac.Annotations[k] = v
+			}
+			deployment := func(name, revision string, replicas int32, ready bool) *appsv1.Deployment {
+				d := &appsv1.Deployment{ObjectMeta: ownedObjectMeta(ac, name, "web")}
+				d.Spec.Replicas = &replicas
+				d.Spec.Template.Labels = map[string]string{"app": "web", RevisionLabel: revision}
+				if ready {
+					d.Status = appsv1.DeploymentStatus{Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: replicas, AvailableReplicas: replicas}
+				}
+				return d
+			}
+			stable := deployment("web", "old", 10, true)
+			if tt.rolledBack != "" {
+				stable.Annotations[RolledBackAnnotation] = tt.rolledBack
+			}
+			live := []runtime.Object{stable}
+			canaryName := "web" + CanarySuffix
+			if tt.rollout.Strategy == RolloutBlueGreen {
+				canaryName = "web" + PreviewSuffix
+			}
+			if c := tt.canary; c != nil {
+				d := deployment(canaryName, "new", c.replicas, c.ready)
+				d.Annotations[RolloutStepAnnotation] = c.step
+				d.Annotations[RolloutStepStartedAnnotation] = time.Now().Add(-time.Duration(c.started) * time.Second).UTC().Format(time.RFC3339)
+				live = append(live, d)
+			}
+			s, _, _ := newTestHandler(nil, live, nil)
+			service := &apiv1.Service{ObjectMeta: ownedObjectMeta(ac, "web", "web"), Spec: apiv1.ServiceSpec{Selector: map[string]string{"app": "web"}}}
+			rendered := deployment("web", "new", 10, false)
+
+			objects, msg, delay, err := progressRollout(s, ac, compConf, getRollout(compConf), []runtime.Object{rendered, service})
+			if err != nil {
+				t.Fatalf("progressRollout() error = %v", err)
+			}
+			if revision := rendered.Spec.Template.Labels[RevisionLabel]; revision != tt.wantRevision || *rendered.Spec.Replicas != tt.wantReplicas {
+				t.Errorf("Deployment web = revision %s with %d replicas, want %s with %d", revision, *rendered.Spec.Replicas, tt.wantRevision, tt.wantReplicas)
+			}
+			if rolledBack := rendered.Annotations[RolledBackAnnotation]; rolledBack != tt.wantRolledBack {
+				t.Errorf("rolled back = %q, want %q", rolledBack, tt.wantRolledBack)
+			}
+			if selector := service.Spec.Selector[RevisionLabel]; selector != tt.wantSelector {
+				t.Errorf("Service web selects revision %q, want %q", selector, tt.wantSelector)
+			}
+			var step string
+			var replicas int32
+			var preview bool
+			for _, obj := range objects {
+				switch o := obj.(type) {
+				case *appsv1.Deployment:
+					if o.Name == canaryName {
+						step, replicas = o.Annotations[RolloutStepAnnotation], *o.Spec.Replicas
+					}
+				case *apiv1.Service:
+					if o.Name == canaryName {
+						preview = o.Spec.Selector[RevisionLabel] == "new"
+					}
+				}
+			}
+			if step != tt.wantCanaryStep || replicas != tt.wantCanary {
+				t.Errorf("%s = step %q with %d replicas, want step %q with %d", canaryName, step, replicas, tt.wantCanaryStep, tt.wantCanary)
+			}
+			if preview != tt.wantPreview {
+				t.Errorf("preview Service = %v, want %v", preview, tt.wantPreview)
+			}
+			if (msg == "") != tt.wantNotRequeued {
+				t.Errorf("message = %q, want requeued %v", msg, !tt.wantNotRequeued)
+			}
+			// the start of a step is recorded in seconds
+			if !tt.wantNotRequeued && (delay < tt.wantDelay-2*time.Second || delay > tt.wantDelay+time.Second) {
+				t.Errorf("delay = %v, want %v", delay, tt.wantDelay)
+			}
+		})
+	}
+}
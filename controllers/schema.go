@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var trailingCommas = regexp.MustCompile(`,(\s*[}\]])`)
+
+// jsonSchema is the subset of JSON schema draft-07 used by the properties of traits.
+type jsonSchema struct {
+	Type       interface{}            `json:"type,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+	Properties map[string]*jsonSchema `json:"properties,omitempty"`
+	Items      *jsonSchema            `json:"items,omitempty"`
+	Default    interface{}            `json:"default,omitempty"`
+	Enum       []interface{}          `json:"enum,omitempty"`
+	Minimum    *float64               `json:"minimum,omitempty"`
+	Maximum    *float64               `json:"maximum,omitempty"`
+}
+
+// parseSchema parses a JSON schema. Trailing commas, which some Trait manifests contain, are tolerated.
+func parseSchema(schema string) (*jsonSchema, error) {
+	if strings.TrimSpace(schema) == "" {
+		return nil, nil
+	}
+	s := new(jsonSchema)
+	if err := json.Unmarshal([]byte(trailingCommas.ReplaceAllString(schema, "$1")), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *jsonSchema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			if str, ok := v.(string); ok {
+				types = append(types, str)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// applyDefaults sets the defaults of the missing properties of objects, recursively.
+func (s *jsonSchema) applyDefaults(value interface{}) interface{} {
+	if s == nil {
+		return value
+	}
+	if value == nil {
+		return s.Default
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	for name, property := range s.Properties {
+		if v := property.applyDefaults(object[name]); v != nil {
+			object[name] = v
+		}
+	}
+	return object
+}
+
+// validate returns the violations of value against the schema, sorted.
+func (s *jsonSchema) validate(path string, value interface{}) []string {
+	if s == nil {
+		return nil
+	}
+	var errs []string
+	if types := s.types(); len(types) > 0 && !matchesType(types, value) {
+		return []string{fmt.Sprintf("%s: must be of type %s", path, strings.Join(types, " or "))}
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: must be one of %v", path, s.Enum))
+		}
+	}
+	if number, ok := value.(float64); ok {
+		if s.Minimum != nil && number < *s.Minimum {
+			errs = append(errs, fmt.Sprintf("%s: must be greater than or equal to %v", path, *s.Minimum))
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			errs = append(errs, fmt.Sprintf("%s: must be less than or equal to %v", path, *s.Maximum))
+		}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s.%s: is required", path, name))
+			}
+		}
+		for name, property := range s.Properties {
+			if pv, ok := v[name]; ok {
+				errs = append(errs, property.validate(path+"."+name, pv)...)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			errs = append(errs, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+func matchesType(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch t {
+		// map is not a JSON schema type, but is used by some Trait manifests for objects
+		case "object", "map":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := value.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
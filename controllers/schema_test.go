@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["replicas"],
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 0, "maximum": 10},
+    "policy": {"type": "string", "enum": ["Always", "Never"], "default": "Always"},
+    "ports": {"type": "array", "items": {"type": "integer"}},
+  }
+}`
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := parseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("parseSchema() error = %v", err)
+	}
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"valid", `{"replicas": 2, "policy": "Never", "ports": [80]}`, nil},
+		{"missing required", `{"policy": "Never"}`, []string{"properties.replicas: is required"}},
+		{"not an object", `"2"`, []string{"properties: must be of type object"}},
+		{"not an integer", `{"replicas": 1.5}`, []string{"properties.replicas: must be of type integer"}},
+		{"out of range", `{"replicas": 11}`, []string{"properties.replicas: must be less than or equal to 10"}},
+		{"below range", `{"replicas": -1}`, []string{"properties.replicas: must be greater than or equal to 0"}},
+		{"not in enum", `{"replicas": 1, "policy": "Sometimes"}`, []string{"properties.policy: must be one of [Always Never]"}},
+		{"invalid item", `{"replicas": 1, "ports": [80, "http"]}`, []string{"properties.ports[1]: must be of type integer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			if got := schema.validate("properties", value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaApplyDefaults(t *testing.T) {
+	schema, err := parseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("parseSchema() error = %v", err)
+	}
+	got := schema.applyDefaults(map[string]interface{}{"replicas": 1.0})
+	want := map[string]interface{}{"replicas": 1.0, "policy": "Always"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyDefaults() = %v, want %v", got, want)
+	}
+}
+
+func TestParseSchemaEmpty(t *testing.T) {
+	schema, err := parseSchema(" ")
+	if schema != nil || err != nil {
+		t.Errorf("parseSchema() = %v, %v, want nil", schema, err)
+	}
+	if errs := schema.validate("properties", "anything"); errs != nil {
+		t.Errorf("validate() of no schema = %v, want nil", errs)
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	parameters := []v1alpha1.Parameter{
+		{Name: "port", ParameterType: v1alpha1.Number, Default: "80"},
+		{Name: "debug", ParameterType: v1alpha1.Boolean},
+		{Name: "image", ParameterType: v1alpha1.String, Required: true},
+	}
+	tests := []struct {
+		name    string
+		values  []v1alpha1.ParameterValue
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			values: []v1alpha1.ParameterValue{{Name: "image", Value: "nginx"}},
+			want:   map[string]string{"image": "nginx", "port": "80"},
+		},
+		{
+			name:   "from variable",
+			values: []v1alpha1.ParameterValue{{Name: "image", Value: "[fromVariable(image)]"}, {Name: "debug", Value: "true"}},
+			want:   map[string]string{"image": "redis", "port": "80", "debug": "true"},
+		},
+		{
+			name:    "required",
+			values:  nil,
+			want:    map[string]string{"port": "80"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid type",
+			values:  []v1alpha1.ParameterValue{{Name: "image", Value: "nginx"}, {Name: "port", Value: "http"}},
+			want:    map[string]string{"image": "nginx", "port": "http"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseParameters(parameters, tt.values, []v1alpha1.Variable{{Name: "image", Value: "redis"}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseParameters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,25 +1,143 @@
 package controllers
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	oamv1alpha1 "github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
 	"github.com/oam-dev/oam-go-sdk/pkg/oam"
-	"hc-oam-controller/api/harmonycloud.cn/v1alpha1"
+	acstatus "hc-oam-controller/api/core.oam.dev/v1alpha1/status"
 	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	"hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/autoscaling/v2beta2"
 	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
 	statusLog = ctrl.Log.WithName("status-handler")
 )
 
+// patchStatus writes the status of the ApplicationConfiguration with the conditions and the ComponentSchematic
+// revisions of its modules. The status is written by a merge patch, UpdateStatus would drop the fields unknown to oam-go-sdk.
+func patchStatus(oamclient versioned.Interface, ac *oamv1alpha1.ApplicationConfiguration, moduleConditions map[string][]acstatus.Condition, moduleRevisions map[string]string) error {
+	var modules []acstatus.ModuleStatus
+	for _, m := range ac.Status.Modules {
+		modules = append(modules, acstatus.ModuleStatus{
+			NamespacedName: m.NamespacedName,
+			Kind:           m.Kind,
+			GroupVersion:   m.GroupVersion,
+			Status:         m.Status,
+			Conditions:     moduleConditions[m.NamespacedName],
+			// the revision is the version of the ComponentSchematic rendered
+			ComponentRevision: moduleRevisions[m.NamespacedName],
+		})
+	}
+	// empty lists are written as null so that the merge patch removes them
+	return patchStatusFields(oamclient, ac, map[string]interface{}{
+		"phase":              ac.Status.Phase,
+		"observedGeneration": ac.Generation,
+		"modules":            modules,
+		"conditions":         statusConditions(ac),
+		"resources":          ac.Status.Resources,
+	})
+}
+
+// statusConditions returns the conditions of the ApplicationConfiguration with the generation they were observed at.
+func statusConditions(ac *oamv1alpha1.ApplicationConfiguration) []acstatus.Condition {
+	var conditions []acstatus.Condition
+	for _, c := range ac.Status.Conditions {
+		conditions = append(conditions, acstatus.Condition{
+			Type:               c.Type,
+			Status:             c.Status,
+			ObservedGeneration: ac.Generation,
+			LastUpdateTime:     c.LastUpdateTime,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+	return conditions
+}
+
+// patchResourceStatus adds the status of a resource to the resource status of the ApplicationConfiguration and
+// writes it. If the ApplicationConfiguration was changed meanwhile, it is read again and the status added again.
+func patchResourceStatus(oamclient versioned.Interface, ac *oamv1alpha1.ApplicationConfiguration, name, apiVersion, kind, component, role, status string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		addResourceStatus(&ac.Status.Resources, name, apiVersion, kind, component, role, status)
+		err := patchStatusFields(oamclient, ac, map[string]interface{}{
+			"resources": ac.Status.Resources,
+		})
+		if apierrors.IsConflict(err) {
+			latest, getErr := oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			*ac = *latest
+		}
+		return err
+	})
+}
+
+// patchStatusFields writes the fields of the status by a merge patch. The resource version makes the patch fail
+// with a conflict if the ApplicationConfiguration was changed since it was read, so that no status is lost.
+func patchStatusFields(oamclient versioned.Interface, ac *oamv1alpha1.ApplicationConfiguration, fields map[string]interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"resourceVersion": ac.ResourceVersion},
+		"status":   fields,
+	})
+	if err != nil {
+		return err
+	}
+	result, err := oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Patch(ac.Name, types.MergePatchType, data, "status")
+	if err != nil {
+		return err
+	}
+	ac.ResourceVersion = result.ResourceVersion
+	return nil
+}
+
+// getModuleConditions reads the conditions of the modules, which the typed ApplicationConfiguration does not have.
+func getModuleConditions(oamclient versioned.Interface, ac *oamv1alpha1.ApplicationConfiguration) (map[string][]acstatus.Condition, error) {
+	conditions := map[string][]acstatus.Condition{}
+	restClient, ok := oamclient.CoreV1alpha1().RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil {
+		// fake clientsets have no RESTClient
+		return conditions, nil
+	}
+	data, err := restClient.Get().Namespace(ac.Namespace).Resource("applicationconfigurations").Name(ac.Name).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	var live struct {
+		Status acstatus.ApplicationConfigurationStatus `json:"status"`
+	}
+	if err := json.Unmarshal(data, &live); err != nil {
+		return nil, err
+	}
+	for _, m := range live.Status.Modules {
+		conditions[m.NamespacedName] = m.Conditions
+	}
+	return conditions, nil
+}
+
 func (s *DeploymentHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
 	deployment, ok := obj.(*appsv1.Deployment)
 	if !ok {
@@ -34,11 +152,77 @@ func (s *DeploymentHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, e
 			if err != nil {
 				return err
 			}
+			var replicas int32 = 1
+			if deployment.Spec.Replicas != nil {
+				replicas = *deployment.Spec.Replicas
+			}
 			status := fmt.Sprintf("Ready: %v/%v, Up-to-date: %v, Available: %v.",
-				deployment.Status.ReadyReplicas, deployment.Status.ReadyReplicas, deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas)
-			addResourceStatus(&ac.Status.Resources, deployment.Name, deployment.APIVersion, deployment.Kind, deployment.Annotations["instance"], deployment.Annotations["role"], status)
+				deployment.Status.ReadyReplicas, replicas, deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas)
+
+			if err := patchResourceStatus(s.Oamclient, ac, deployment.Name, deployment.APIVersion, deployment.Kind, deployment.Annotations["instance"], deployment.Annotations["role"], status); err != nil {
+				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
+				return err
+			} else {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *DaemonSetHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
+	daemonSet, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return errors.New("type mismatch")
+	}
+	if daemonSet.OwnerReferences == nil {
+		return nil
+	}
+	for _, o := range daemonSet.OwnerReferences {
+		if o.Kind == "ApplicationConfiguration" {
+			ac, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(daemonSet.Namespace).Get(o.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			status := fmt.Sprintf("Desired: %v, Current: %v, Ready: %v, Up-to-date: %v, Available: %v.",
+				daemonSet.Status.DesiredNumberScheduled, daemonSet.Status.CurrentNumberScheduled, daemonSet.Status.NumberReady,
+				daemonSet.Status.UpdatedNumberScheduled, daemonSet.Status.NumberAvailable)
+
+			if err := patchResourceStatus(s.Oamclient, ac, daemonSet.Name, daemonSet.APIVersion, daemonSet.Kind, daemonSet.Annotations["instance"], daemonSet.Annotations["role"], status); err != nil {
+				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
+				return err
+			} else {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *StatefulSetHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
+	statefulSet, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return errors.New("type mismatch")
+	}
+	if statefulSet.OwnerReferences == nil {
+		return nil
+	}
+	for _, o := range statefulSet.OwnerReferences {
+		if o.Kind == "ApplicationConfiguration" {
+			ac, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(statefulSet.Namespace).Get(o.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			var replicas int32 = 1
+			if statefulSet.Spec.Replicas != nil {
+				replicas = *statefulSet.Spec.Replicas
+			}
+			status := fmt.Sprintf("Ready: %v/%v, Up-to-date: %v.",
+				statefulSet.Status.ReadyReplicas, replicas, statefulSet.Status.UpdatedReplicas)
 
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(deployment.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, statefulSet.Name, statefulSet.APIVersion, statefulSet.Kind, statefulSet.Annotations["instance"], statefulSet.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -77,8 +261,7 @@ func (s *ServiceHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eTyp
 			}
 			status := fmt.Sprintf("Type: %s, Cluster-IP: %s, Port(s): %s.",
 				service.Spec.Type, service.Spec.ClusterIP, ports)
-			addResourceStatus(&ac.Status.Resources, service.Name, service.APIVersion, service.Kind, service.Annotations["instance"], service.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(service.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, service.Name, service.APIVersion, service.Kind, service.Annotations["instance"], service.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -105,8 +288,7 @@ func (s *ConfigMapHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eT
 				return err
 			}
 			status := fmt.Sprintf("Data: %v.", len(configmap.Data))
-			addResourceStatus(&ac.Status.Resources, configmap.Name, configmap.APIVersion, configmap.Kind, configmap.Annotations["instance"], configmap.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(configmap.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, configmap.Name, configmap.APIVersion, configmap.Kind, configmap.Annotations["instance"], configmap.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -154,8 +336,7 @@ func (s *PvcHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oa
 					pvc.Status.Phase,
 				)
 			}
-			addResourceStatus(&ac.Status.Resources, pvc.Name, pvc.APIVersion, pvc.Kind, pvc.Annotations["instance"], pvc.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(pvc.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, pvc.Name, pvc.APIVersion, pvc.Kind, pvc.Annotations["instance"], pvc.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -183,8 +364,7 @@ func (s *JobHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oa
 			}
 			status := fmt.Sprintf("Active: %v, Succeeded: %v, Failed: %v.",
 				job.Status.Active, job.Status.Succeeded, job.Status.Failed)
-			addResourceStatus(&ac.Status.Resources, job.Name, job.APIVersion, job.Kind, job.Annotations["instance"], job.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(job.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, job.Name, job.APIVersion, job.Kind, job.Annotations["instance"], job.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -196,6 +376,107 @@ func (s *JobHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oa
 	return nil
 }
 
+func (s *CronJobHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
+	cronJob, ok := obj.(*batchv1beta1.CronJob)
+	if !ok {
+		return errors.New("type mismatch")
+	}
+	if cronJob.OwnerReferences == nil {
+		return nil
+	}
+	for _, o := range cronJob.OwnerReferences {
+		if o.Kind == "ApplicationConfiguration" {
+			ac, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(cronJob.Namespace).Get(o.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			lastSchedule := "<none>"
+			if cronJob.Status.LastScheduleTime != nil {
+				lastSchedule = cronJob.Status.LastScheduleTime.Format(time.RFC3339)
+			}
+			var activeJobs []string
+			for _, job := range cronJob.Status.Active {
+				activeJobs = append(activeJobs, job.Name)
+			}
+			suspend := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+			status := fmt.Sprintf("Schedule: %s, Suspend: %v, Active: %v, Last Schedule: %s.",
+				cronJob.Spec.Schedule, suspend, len(activeJobs), lastSchedule)
+			if len(activeJobs) > 0 {
+				status = fmt.Sprintf("%s Active Jobs: %s.", status, strings.Join(activeJobs, ","))
+			}
+			if lastJob := lastFinishedJob(s.K8sclient, cronJob); lastJob != "" {
+				status = fmt.Sprintf("%s Last Finished: %s.", status, lastJob)
+			}
+			if err := patchResourceStatus(s.Oamclient, ac, cronJob.Name, cronJob.APIVersion, cronJob.Kind, cronJob.Annotations["instance"], cronJob.Annotations["role"], status); err != nil {
+				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
+				return err
+			} else {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *TemplateWorkloadHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
+	object, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return errors.New("type mismatch")
+	}
+	for _, o := range object.GetOwnerReferences() {
+		if o.Kind == "ApplicationConfiguration" {
+			ac, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(object.GetNamespace()).Get(o.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			annotations := object.GetAnnotations()
+			if err := patchResourceStatus(s.Oamclient, ac, object.GetName(), object.GetAPIVersion(), object.GetKind(), annotations["instance"], annotations["role"], s.Renderer.status(object)); err != nil {
+				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
+				return err
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// lastFinishedJob returns the result of the last finished Job of the CronJob, e.g. the last backup. The Jobs are
+// listed by the labels of its job template, it has none if it was created before they were rendered.
+func lastFinishedJob(k8sclient kubernetes.Interface, cronJob *batchv1beta1.CronJob) string {
+	if k8sclient == nil || len(cronJob.Spec.JobTemplate.Labels) == 0 {
+		return ""
+	}
+	selector := labels.SelectorFromSet(cronJob.Spec.JobTemplate.Labels).String()
+	jobs, err := k8sclient.BatchV1().Jobs(cronJob.Namespace).List(v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		statusLog.Info("List jobs failed", "Namespace", cronJob.Namespace, "CronJob", cronJob.Name, "Error", err)
+		return ""
+	}
+	var last string
+	var lastTime v1.Time
+	for _, job := range jobs.Items {
+		if ref := v1.GetControllerOf(&job); ref == nil || ref.UID != cronJob.UID {
+			continue
+		}
+		for _, c := range job.Status.Conditions {
+			if (c.Type != batchv1.JobComplete && c.Type != batchv1.JobFailed) || c.Status != corev1.ConditionTrue {
+				continue
+			}
+			if last == "" || lastTime.Before(&c.LastTransitionTime) {
+				result := "Succeeded"
+				if c.Type == batchv1.JobFailed {
+					result = "Failed"
+				}
+				last = fmt.Sprintf("%s %s at %s", job.Name, result, c.LastTransitionTime.Format(time.RFC3339))
+				lastTime = c.LastTransitionTime
+			}
+		}
+	}
+	return last
+}
+
 func (s *MysqlClusterHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
 	mysqlCluster, ok := obj.(*v1alpha1.MysqlCluster)
 	if !ok {
@@ -210,10 +491,21 @@ func (s *MysqlClusterHandler) Handle(ctx *oam.ActionContext, obj runtime.Object,
 			if err != nil {
 				return err
 			}
-			status := fmt.Sprintf("Phase: %s, Replicas: %v, CurrentRevision: %s, UpdateRevision: %s, CurrentSwitchedNum: %v, FailedCount: %v, Reason: %s.",
-				mysqlCluster.Status.Phase, mysqlCluster.Status.Replicas, mysqlCluster.Status.CurrentRevision, mysqlCluster.Status.UpdateRevision, mysqlCluster.Status.CurrentSwitchedNum, mysqlCluster.Status.FailedCount, mysqlCluster.Status.Reason)
-			addResourceStatus(&ac.Status.Resources, mysqlCluster.Name, mysqlCluster.APIVersion, mysqlCluster.Kind, mysqlCluster.Annotations["instance"], mysqlCluster.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(mysqlCluster.Namespace).UpdateStatus(ac); err != nil {
+			if err := s.reportFailover(ac, mysqlCluster); err != nil {
+				statusLog.Info("Report failover failed", "Namespace", mysqlCluster.Namespace, "MysqlCluster", mysqlCluster.Name, "Error", err)
+			}
+			if s.K8sclient != nil {
+				if err := patchConnectionSecret(s.K8sclient, ac, mysqlCluster); err != nil {
+					statusLog.Info("Patch connection secret failed", "Namespace", mysqlCluster.Namespace, "MysqlCluster", mysqlCluster.Name, "Error", err)
+				}
+			}
+			var replicas int32
+			if mysqlCluster.Status.Replicas != nil {
+				replicas = *mysqlCluster.Status.Replicas
+			}
+			status := fmt.Sprintf("Phase: %s, Replicas: %v, Master: %s, CurrentRevision: %s, UpdateRevision: %s, CurrentSwitchedNum: %v, FailedCount: %v, Reason: %s.",
+				phaseOrPending(string(mysqlCluster.Status.Phase)), replicas, mysqlClusterMaster(mysqlCluster), mysqlCluster.Status.CurrentRevision, mysqlCluster.Status.UpdateRevision, mysqlCluster.Status.CurrentSwitchedNum, mysqlCluster.Status.FailedCount, mysqlCluster.Status.Reason)
+			if err := patchResourceStatus(s.Oamclient, ac, mysqlCluster.Name, MysqlClusterApiVersion, MysqlClusterKind, mysqlCluster.Annotations["instance"], mysqlCluster.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -225,6 +517,41 @@ func (s *MysqlClusterHandler) Handle(ctx *oam.ActionContext, obj runtime.Object,
 	return nil
 }
 
+// reportFailover raises a Failover event on the ApplicationConfiguration when the mysql operator switched the
+// master of the MysqlCluster. The number of switches already reported is kept by an annotation of the MysqlCluster,
+// a MysqlCluster without it is annotated with its current number silently, its past switches are not reported.
+func (s *MysqlClusterHandler) reportFailover(ac *oamv1alpha1.ApplicationConfiguration, mysqlCluster *v1alpha1.MysqlCluster) error {
+	current := mysqlCluster.Status.CurrentSwitchedNum
+	annotation, annotated := mysqlCluster.Annotations[SwitchedNumAnnotation]
+	reported, _ := strconv.ParseInt(annotation, 10, 32)
+	if (annotated && int32(reported) == current) || s.Hcclient == nil {
+		return nil
+	}
+	if annotated && int32(reported) < current && s.Recorder != nil {
+		s.Recorder.Event(ac, corev1.EventTypeWarning, Failover, fmt.Sprintf(MessageFailover, mysqlCluster.Name, current, mysqlClusterMaster(mysqlCluster)))
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{SwitchedNumAnnotation: strconv.Itoa(int(current))},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.Hcclient.MysqlV1alpha1().MysqlClusters(mysqlCluster.Namespace).Patch(nil, mysqlCluster.Name, types.MergePatchType, data, v1.PatchOptions{})
+	return err
+}
+
+// mysqlClusterMaster returns the member of the MysqlCluster reported as master.
+func mysqlClusterMaster(mysqlCluster *v1alpha1.MysqlCluster) string {
+	for _, c := range mysqlCluster.Status.Conditions {
+		if c.Type == v1alpha1.MasterMysql {
+			return c.Name
+		}
+	}
+	return "<none>"
+}
+
 func (s *IngressHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oam.EType) error {
 	ingress, ok := obj.(*v1beta1.Ingress)
 	if !ok {
@@ -248,8 +575,7 @@ func (s *IngressHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eTyp
 
 			}
 			status := fmt.Sprintf("Hosts: %s ", hosts)
-			addResourceStatus(&ac.Status.Resources, ingress.Name, ingress.APIVersion, ingress.Kind, ingress.Annotations["instance"], ingress.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ingress.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, ingress.Name, ingress.APIVersion, ingress.Kind, ingress.Annotations["instance"], ingress.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -276,8 +602,7 @@ func (s *HpaHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType oa
 				return err
 			}
 			status := fmt.Sprintf("CurrentReplicas: %v, DesiredReplicas: %v.", hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas)
-			addResourceStatus(&ac.Status.Resources, hpa.Name, hpa.APIVersion, hpa.Kind, hpa.Annotations["instance"], hpa.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(hpa.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, hpa.Name, hpa.APIVersion, hpa.Kind, hpa.Annotations["instance"], hpa.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -304,8 +629,7 @@ func (s *HcHpaHandler) Handle(ctx *oam.ActionContext, obj runtime.Object, eType
 				return err
 			}
 			status := fmt.Sprintf("CurrentReplicas: %v, DesiredReplicas: %v.", hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas)
-			addResourceStatus(&ac.Status.Resources, hpa.Name, hpa.APIVersion, hpa.Kind, hpa.Annotations["instance"], hpa.Annotations["role"], status)
-			if _, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(hpa.Namespace).UpdateStatus(ac); err != nil {
+			if err := patchResourceStatus(s.Oamclient, ac, hpa.Name, hpa.APIVersion, hpa.Kind, hpa.Annotations["instance"], hpa.Annotations["role"], status); err != nil {
 				statusLog.Info("Update status failed", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
 				return err
 			} else {
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	hcfake "hc-oam-controller/client/clientset/versioned/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestPatchResourceStatusConflict(t *testing.T) {
+	ac := newTestApplicationConfiguration()
+	ac.ResourceVersion = "1"
+	// the live ApplicationConfiguration got the status of a Service meanwhile
+	live := ac.DeepCopy()
+	live.ResourceVersion = "2"
+	addResourceStatus(&live.Status.Resources, "web", "v1", "Service", "web", "workload", "Type: ClusterIP.")
+	oamclient := oamfake.NewSimpleClientset(live)
+
+	var versions []string
+	oamclient.PrependReactor("patch", "applicationconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		var patch struct {
+			Metadata v1.ObjectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &patch); err != nil {
+			return true, nil, err
+		}
+		versions = append(versions, patch.Metadata.ResourceVersion)
+		if patch.Metadata.ResourceVersion != "2" {
+			return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "applicationconfigurations"}, ac.Name, nil)
+		}
+		return false, nil, nil
+	})
+
+	if err := patchResourceStatus(oamclient, ac, "web", "apps/v1", "Deployment", "web", "workload", "Ready: 1/1."); err != nil {
+		t.Fatalf("patchResourceStatus() error = %v", err)
+	}
+	if len(versions) != 2 || versions[0] != "1" || versions[1] != "2" {
+		t.Errorf("patchResourceStatus() patched resource versions %v, want [1 2]", versions)
+	}
+	result, err := oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]string{}
+	for _, r := range result.Status.Resources {
+		kinds[r.Kind] = r.Status
+	}
+	want := map[string]string{"Service": "Type: ClusterIP.", "Deployment": "Ready: 1/1."}
+	if len(kinds) != len(want) || kinds["Service"] != want["Service"] || kinds["Deployment"] != want["Deployment"] {
+		t.Errorf("resource status = %v, want %v", kinds, want)
+	}
+}
+
+func TestPatchStatusFieldsResourceVersion(t *testing.T) {
+	ac := newTestApplicationConfiguration()
+	ac.ResourceVersion = "1"
+	oamclient := oamfake.NewSimpleClientset(ac.DeepCopy())
+	oamclient.PrependReactor("patch", "applicationconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		result := ac.DeepCopy()
+		result.ResourceVersion = "2"
+		return true, result, nil
+	})
+	// the resource version written is kept so that the next status patch of the reconcile does not conflict
+	if err := patchStatusFields(oamclient, ac, map[string]interface{}{"phase": Synced}); err != nil {
+		t.Fatalf("patchStatusFields() error = %v", err)
+	}
+	if ac.ResourceVersion != "2" {
+		t.Errorf("patchStatusFields() resource version = %s, want 2", ac.ResourceVersion)
+	}
+}
+
+func TestReportFailover(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation *string
+		current    int32
+		wantEvent  bool
+		wantPatch  bool
+	}{
+		{"not annotated", nil, 3, false, true},
+		{"switched", stringPtr("2"), 3, true, true},
+		{"reported", stringPtr("3"), 3, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mysqlCluster := newTestMysqlCluster("", "db-1")
+			mysqlCluster.Status.CurrentSwitchedNum = tt.current
+			if tt.annotation != nil {
+				mysqlCluster.Annotations = map[string]string{SwitchedNumAnnotation: *tt.annotation}
+			}
+			hcclient := hcfake.NewSimpleClientset(mysqlCluster)
+			recorder := record.NewFakeRecorder(10)
+			s := &MysqlClusterHandler{Hcclient: hcclient, Recorder: recorder}
+			if err := s.reportFailover(newTestApplicationConfiguration(), mysqlCluster); err != nil {
+				t.Fatalf("reportFailover() error = %v", err)
+			}
+			if event := len(recorder.Events) > 0; event != tt.wantEvent {
+				t.Errorf("reportFailover() raised an event = %v, want %v", event, tt.wantEvent)
+			}
+			var patched bool
+			for _, action := range hcclient.Actions() {
+				patched = patched || action.GetVerb() == "patch"
+			}
+			if patched != tt.wantPatch {
+				t.Errorf("reportFailover() patched = %v, want %v", patched, tt.wantPatch)
+			}
+			result, err := hcclient.MysqlV1alpha1().MysqlClusters("default").Get(nil, "db", v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reported := result.Annotations[SwitchedNumAnnotation]; reported != "3" {
+				t.Errorf("reported switches = %s, want 3", reported)
+			}
+		})
+	}
+}
+
+func TestCronJobHandler(t *testing.T) {
+	ac := newTestApplicationConfiguration()
+	lastSchedule := v1.NewTime(time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC))
+	cronJob := &batchv1beta1.CronJob{
+		TypeMeta:   v1.TypeMeta{APIVersion: CronJobApiVersion, Kind: CronJobKind},
+		ObjectMeta: ownedObjectMeta(ac, "report", "report"),
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:    "0 2 * * *",
+			JobTemplate: batchv1beta1.JobTemplateSpec{ObjectMeta: v1.ObjectMeta{Labels: map[string]string{CronJobLabel: "report"}}},
+		},
+		Status: batchv1beta1.CronJobStatus{
+			Active:           []apiv1.ObjectReference{{Name: "report-3"}},
+			LastScheduleTime: &lastSchedule,
+		},
+	}
+	cronJob.UID = "report-uid"
+	job := func(name string, owner v1.Object, condition batchv1.JobConditionType, finished time.Time) *batchv1.Job {
+		j := &batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: ac.Namespace}}
+		if owner != nil {
+			j.Labels = map[string]string{CronJobLabel: owner.GetName()}
+			j.OwnerReferences = []v1.OwnerReference{*v1.NewControllerRef(owner, batchv1beta1.SchemeGroupVersion.WithKind(CronJobKind))}
+		}
+		if condition != "" {
+			j.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: apiv1.ConditionTrue, LastTransitionTime: v1.NewTime(finished)}}
+		}
+		return j
+	}
+	other := &batchv1beta1.CronJob{ObjectMeta: v1.ObjectMeta{Name: "cleanup", UID: "cleanup-uid"}}
+	k8sclient := k8sfake.NewSimpleClientset(
+		job("report-1", cronJob, batchv1.JobFailed, time.Date(2026, 10, 16, 2, 5, 0, 0, time.UTC)),
+		job("report-2", cronJob, batchv1.JobComplete, time.Date(2026, 10, 17, 2, 5, 0, 0, time.UTC)),
+		job("report-3", cronJob, "", time.Time{}),
+		// the Jobs of other CronJobs and the Jobs without an owner are not listed
+		job("cleanup-1", other, batchv1.JobComplete, time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)),
+		job("migrate", nil, batchv1.JobComplete, time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)),
+	)
+	oamclient := oamfake.NewSimpleClientset(ac)
+	s := &CronJobHandler{Name: "test", Oamclient: oamclient, K8sclient: k8sclient}
+	if err := s.Handle(nil, cronJob, oam.CreateOrUpdate); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	live, err := oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Schedule: 0 2 * * *, Suspend: false, Active: 1, Last Schedule: 2026-10-18T02:00:00Z. Active Jobs: report-3. " +
+		"Last Finished: report-2 Succeeded at 2026-10-17T02:05:00Z."
+	if len(live.Status.Resources) != 1 || live.Status.Resources[0].Status != want {
+		t.Errorf("resources = %+v, want the status %q of the CronJob", live.Status.Resources, want)
+	}
+	for _, action := range k8sclient.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok && list.GetListRestrictions().Labels.String() != CronJobLabel+"=report" {
+			t.Errorf("Jobs listed by %q, want the labels of the job template", list.GetListRestrictions().Labels)
+		}
+	}
+
+	// the Jobs of a CronJob without labels of its job template are not listed
+	k8sclient.ClearActions()
+	cronJob.Spec.JobTemplate.Labels = nil
+	if err := s.Handle(nil, cronJob, oam.CreateOrUpdate); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(k8sclient.Actions()) > 0 {
+		t.Errorf("actions = %v, want none", k8sclient.Actions())
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
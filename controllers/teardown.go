@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var (
+	teardownLog = ctrl.Log.WithName("teardown")
+)
+
+// defaultRetention keeps the data of an application unless its retention-policy trait says otherwise.
+var defaultRetention = map[string]string{
+	PvcKind:          RetentionRetain,
+	MysqlClusterKind: RetentionOrphan,
+}
+
+func hasFinalizer(ac *v1alpha1.ApplicationConfiguration) bool {
+	for _, f := range ac.Finalizers {
+		if f == Finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureFinalizer adds the finalizer, so that the resources are torn down before the ApplicationConfiguration is deleted.
+func ensureFinalizer(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+	if hasFinalizer(ac) {
+		return nil
+	}
+	return patchFinalizers(s, ac, append(append([]string(nil), ac.Finalizers...), Finalizer))
+}
+
+func removeFinalizer(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+	var finalizers []string
+	for _, f := range ac.Finalizers {
+		if f != Finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	return patchFinalizers(s, ac, finalizers)
+}
+
+func patchFinalizers(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, finalizers []string) error {
+	// the resource version makes the patch fail if the finalizers were changed meanwhile
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": ac.ResourceVersion,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	result, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Patch(ac.Name, types.MergePatchType, data)
+	if err != nil {
+		return err
+	}
+	ac.Finalizers = result.Finalizers
+	ac.ResourceVersion = result.ResourceVersion
+	return nil
+}
+
+// getRetentionPolicies returns the retention policies of the component instance, declared by its retention-policy traits.
+func getRetentionPolicies(compConf v1alpha1.ComponentConfiguration) []traits2.ResourceRetention {
+	var policies []traits2.ResourceRetention
+	for _, tr := range compConf.Traits {
+		if getTraitName(tr.Name) != TraitRetentionPolicy {
+			continue
+		}
+		retentionPolicy := new(traits2.RetentionPolicy)
+		if err := parsePropertiesOfTrait(tr, retentionPolicy); err != nil {
+			continue
+		}
+		policies = append(policies, retentionPolicy.Policies...)
+	}
+	return policies
+}
+
+// retentionPolicy returns the policy of a resource. Policies of the resource name take precedence over those of its kind.
+func retentionPolicy(policies []traits2.ResourceRetention, kind, name string) string {
+	policy := ""
+	for _, p := range policies {
+		if p.Kind != kind {
+			continue
+		}
+		if p.Name == name {
+			return p.Policy
+		}
+		if p.Name == "" && policy == "" {
+			policy = p.Policy
+		}
+	}
+	if policy != "" {
+		return policy
+	}
+	if policy, ok := defaultRetention[kind]; ok {
+		return policy
+	}
+	return RetentionDelete
+}
+
+// teardown deletes the resources of an ApplicationConfiguration being deleted, the instances which depend on others
+// before their dependencies, and removes the finalizer once all resources are gone. The progress is reported by
+// the Cleanup condition, the ApplicationConfiguration is requeued until the resources of an instance are gone.
+func teardown(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+	if !hasFinalizer(ac) {
+		return nil
+	}
+	handlerLog.Info("Tearing down ApplicationConfiguration.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
+	objects, err := listInstanceObjects(s, ac)
+	if err != nil {
+		return err
+	}
+
+	// instances removed from the spec first, then in reverse dependency order
+	components, _ := orderComponents(ac.Spec.Components)
+	specified := map[string]bool{}
+	policies := map[string][]traits2.ResourceRetention{}
+	for _, compConf := range components {
+		specified[compConf.InstanceName] = true
+		policies[compConf.InstanceName] = getRetentionPolicies(compConf)
+	}
+	var instances []string
+	for instance := range objects {
+		if !specified[instance] {
+			instances = append(instances, instance)
+		}
+	}
+	sort.Strings(instances)
+	for i := len(components) - 1; i >= 0; i-- {
+		instances = append(instances, components[i].InstanceName)
+	}
+
+	ac.Status.Phase = Terminating
+	for _, instance := range instances {
+		if len(objects[instance]) == 0 {
+			continue
+		}
+		remaining, err := teardownInstance(s, ac, objects[instance], policies[instance])
+		if err != nil {
+			ac.Status.SetConditionFalse(v1alpha1.Cleanup, TeardownFailed, err.Error())
+			if err := patchTeardownStatus(s, ac); err != nil {
+				teardownLog.Info("Update status failed.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Error", err)
+			}
+			return err
+		}
+		if len(remaining) > 0 {
+			msg := fmt.Sprintf(TeardownMessage, instance, strings.Join(remaining, ", "))
+			ac.Status.SetConditionFalse(v1alpha1.Cleanup, TeardownInProgress, msg)
+			if err := patchTeardownStatus(s, ac); err != nil {
+				return err
+			}
+			requeue(s, ac, requeueInterval(s), []string{msg})
+			return nil
+		}
+	}
+
+	if err := removeFinalizer(s, ac); err != nil {
+		return err
+	}
+	handlerLog.Info("ApplicationConfiguration torn down.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name)
+	s.Recorder.Event(ac, apiv1.EventTypeNormal, TornDown, fmt.Sprintf(MessageTornDown, ac.Name))
+	return nil
+}
+
+// teardownInstance deletes or retains the objects of a component instance, in reverse order of creation,
+// and returns the objects which are not gone yet.
+func teardownInstance(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, objects []runtime.Object, policies []traits2.ResourceRetention) ([]string, error) {
+	objects = append([]runtime.Object(nil), objects...)
+	sortObjects(objects)
+	var remaining []string
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+		key, err := getResourceKey(obj)
+		if err != nil {
+			return nil, err
+		}
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		resource := strings.ToLower(key.Kind) + "/" + o.GetName()
+		if o.GetDeletionTimestamp() != nil {
+			remaining = append(remaining, resource)
+			continue
+		}
+
+		policy := retentionPolicy(policies, key.Kind, o.GetName())
+		if policy == RetentionRetain {
+			if err := releaseObject(s, ac, obj, o); err != nil {
+				s.Recorder.Event(ac, apiv1.EventTypeWarning, Failed, err.Error())
+				return nil, err
+			}
+			teardownLog.Info("Resource retained.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, key.Kind, o.GetName())
+			s.Recorder.Event(ac, apiv1.EventTypeNormal, Retained, fmt.Sprintf(MessageResourceRetained, strings.ToLower(key.Kind), o.GetName()))
+		} else {
+			if err := deleteObject(s, ac, obj, policy); err != nil {
+				s.Recorder.Event(ac, apiv1.EventTypeWarning, Failed, err.Error())
+				return nil, err
+			}
+			teardownLog.Info("Resource deleted.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, key.Kind, o.GetName(), "Policy", policy)
+			s.Recorder.Event(ac, apiv1.EventTypeNormal, Deleted, fmt.Sprintf(MessageResourceDeleted, strings.ToLower(key.Kind), o.GetName()))
+			remaining = append(remaining, resource)
+		}
+		removeResourceStatus(&ac.Status.Resources, o.GetName(), key.Kind, o.GetAnnotations()[Instance])
+	}
+	return remaining, nil
+}
+
+// releaseObject removes the owner reference to the ApplicationConfiguration, so that the object is kept.
+func releaseObject(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, obj runtime.Object, o v1.Object) error {
+	var ownerReferences []v1.OwnerReference
+	for _, ref := range o.GetOwnerReferences() {
+		if ref.UID != ac.UID {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"ownerReferences": ownerReferences},
+	})
+	if err != nil {
+		return err
+	}
+	namespace := ac.Namespace
+	name := o.GetName()
+	switch obj.(type) {
+	case *appsv1.Deployment:
+		_, err = s.K8sclient.AppsV1().Deployments(namespace).Patch(name, types.MergePatchType, data)
+	case *appsv1.DaemonSet:
+		_, err = s.K8sclient.AppsV1().DaemonSets(namespace).Patch(name, types.MergePatchType, data)
+	case *appsv1.StatefulSet:
+		_, err = s.K8sclient.AppsV1().StatefulSets(namespace).Patch(name, types.MergePatchType, data)
+	case *batchv1.Job:
+		_, err = s.K8sclient.BatchV1().Jobs(namespace).Patch(name, types.MergePatchType, data)
+	case *batchv1beta1.CronJob:
+		_, err = s.K8sclient.BatchV1beta1().CronJobs(namespace).Patch(name, types.MergePatchType, data)
+	case *apiv1.Service:
+		_, err = s.K8sclient.CoreV1().Services(namespace).Patch(name, types.MergePatchType, data)
+	case *apiv1.ConfigMap:
+		_, err = s.K8sclient.CoreV1().ConfigMaps(namespace).Patch(name, types.MergePatchType, data)
+	case *apiv1.Secret:
+		_, err = s.K8sclient.CoreV1().Secrets(namespace).Patch(name, types.MergePatchType, data)
+	case *apiv1.PersistentVolumeClaim:
+		_, err = s.K8sclient.CoreV1().PersistentVolumeClaims(namespace).Patch(name, types.MergePatchType, data)
+	case *extensionsv1beta1.Ingress:
+		_, err = s.K8sclient.ExtensionsV1beta1().Ingresses(namespace).Patch(name, types.MergePatchType, data)
+	case *v2beta2.HorizontalPodAutoscaler:
+		_, err = s.K8sclient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Patch(name, types.MergePatchType, data)
+	case *hcv1beta1.HorizontalPodAutoscaler:
+		_, err = s.Hcclient.HarmonycloudV1beta1().HorizontalPodAutoscalers(namespace).Patch(nil, name, types.MergePatchType, data, v1.PatchOptions{})
+	case *hcv1alpha1.MysqlCluster:
+		_, err = s.Hcclient.MysqlV1alpha1().MysqlClusters(namespace).Patch(nil, name, types.MergePatchType, data, v1.PatchOptions{})
+	case *unstructured.Unstructured:
+		var client dynamic.ResourceInterface
+		if client, err = templateObjectClient(s, namespace, obj.(*unstructured.Unstructured)); err == nil {
+			_, err = client.Patch(name, types.MergePatchType, data, v1.PatchOptions{})
+		}
+	default:
+		err = fmt.Errorf("unsupported object %T", obj)
+	}
+	return err
+}
+
+// deleteObject deletes the object with its dependents, or orphans the dependents with the Orphan policy.
+// A MysqlCluster deleted with its data is marked by spec.delete, so that the mysql operator cleans up the data.
+func deleteObject(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, obj runtime.Object, policy string) error {
+	propagation := v1.DeletePropagationForeground
+	if policy == RetentionOrphan {
+		propagation = v1.DeletePropagationOrphan
+	}
+	if o, ok := obj.(*hcv1alpha1.MysqlCluster); ok && policy == RetentionDelete && !o.Spec.Delete {
+		// batchDelete marks the deletion of the whole application
+		data, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{"delete": true, "batchDelete": true},
+		})
+		if _, err := s.Hcclient.MysqlV1alpha1().MysqlClusters(ac.Namespace).Patch(nil, o.Name, types.MergePatchType, data, v1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	return deleteOwnedObject(s, ac.Namespace, obj, propagation)
+}
+
+// deleteOwnedObject deletes an object of an ApplicationConfiguration by the client of its kind.
+func deleteOwnedObject(s *ApplicationConfigurationHandler, namespace string, obj runtime.Object, propagation v1.DeletionPropagation) error {
+	deleteOptions := v1.DeleteOptions{PropagationPolicy: &propagation}
+	var err error
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		err = s.K8sclient.AppsV1().Deployments(namespace).Delete(o.Name, &deleteOptions)
+	case *appsv1.DaemonSet:
+		err = s.K8sclient.AppsV1().DaemonSets(namespace).Delete(o.Name, &deleteOptions)
+	case *appsv1.StatefulSet:
+		err = s.K8sclient.AppsV1().StatefulSets(namespace).Delete(o.Name, &deleteOptions)
+	case *batchv1.Job:
+		err = s.K8sclient.BatchV1().Jobs(namespace).Delete(o.Name, &deleteOptions)
+	case *batchv1beta1.CronJob:
+		err = s.K8sclient.BatchV1beta1().CronJobs(namespace).Delete(o.Name, &deleteOptions)
+	case *apiv1.Service:
+		err = s.K8sclient.CoreV1().Services(namespace).Delete(o.Name, &deleteOptions)
+	case *apiv1.ConfigMap:
+		err = s.K8sclient.CoreV1().ConfigMaps(namespace).Delete(o.Name, &deleteOptions)
+	case *apiv1.Secret:
+		err = s.K8sclient.CoreV1().Secrets(namespace).Delete(o.Name, &deleteOptions)
+	case *apiv1.PersistentVolumeClaim:
+		err = s.K8sclient.CoreV1().PersistentVolumeClaims(namespace).Delete(o.Name, &deleteOptions)
+	case *extensionsv1beta1.Ingress:
+		err = s.K8sclient.ExtensionsV1beta1().Ingresses(namespace).Delete(o.Name, &deleteOptions)
+	case *v2beta2.HorizontalPodAutoscaler:
+		err = s.K8sclient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Delete(o.Name, &deleteOptions)
+	case *hcv1beta1.HorizontalPodAutoscaler:
+		err = s.Hcclient.HarmonycloudV1beta1().HorizontalPodAutoscalers(namespace).Delete(nil, o.Name, deleteOptions)
+	case *hcv1alpha1.MysqlCluster:
+		err = s.Hcclient.MysqlV1alpha1().MysqlClusters(namespace).Delete(nil, o.Name, deleteOptions)
+	case *unstructured.Unstructured:
+		var client dynamic.ResourceInterface
+		if client, err = templateObjectClient(s, namespace, o); err == nil {
+			err = client.Delete(o.GetName(), &deleteOptions)
+		}
+	default:
+		err = fmt.Errorf("unsupported object %T", obj)
+	}
+	return err
+}
+
+func patchTeardownStatus(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration) error {
+	return patchStatusFields(s.Oamclient, ac, map[string]interface{}{
+		"phase":      ac.Status.Phase,
+		"conditions": statusConditions(ac),
+		"resources":  ac.Status.Resources,
+	})
+}
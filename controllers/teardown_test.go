@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	policies := []traits2.ResourceRetention{
+		{Kind: ServiceKind, Policy: RetentionRetain},
+		{Kind: ServiceKind, Name: "web", Policy: RetentionOrphan},
+		{Kind: PvcKind, Name: "logs", Policy: RetentionDelete},
+	}
+	tests := []struct {
+		kind string
+		name string
+		want string
+	}{
+		{ServiceKind, "web", RetentionOrphan},
+		{ServiceKind, "api", RetentionRetain},
+		{PvcKind, "logs", RetentionDelete},
+		{PvcKind, "data", RetentionRetain},
+		{MysqlClusterKind, "db", RetentionOrphan},
+		{DeploymentKind, "web", RetentionDelete},
+	}
+	for _, tt := range tests {
+		if got := retentionPolicy(policies, tt.kind, tt.name); got != tt.want {
+			t.Errorf("retentionPolicy(%s %s) = %s, want %s", tt.kind, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTeardown(t *testing.T) {
+	retention := func(policies ...traits2.ResourceRetention) v1alpha1.TraitBinding {
+		properties, _ := json.Marshal(traits2.RetentionPolicy{Policies: policies})
+		return v1alpha1.TraitBinding{Name: TraitRetentionPolicy, Properties: runtime.RawExtension{Raw: properties}}
+	}
+	dependsOn, _ := json.Marshal(traits2.DependsOn{Instances: []string{"db"}})
+	ac := newTestApplicationConfiguration(
+		v1alpha1.ComponentConfiguration{
+			ComponentName: "db",
+			InstanceName:  "db",
+			Traits:        []v1alpha1.TraitBinding{retention(traits2.ResourceRetention{Kind: MysqlClusterKind, Policy: RetentionDelete})},
+		},
+		v1alpha1.ComponentConfiguration{
+			ComponentName: "web",
+			InstanceName:  "web",
+			Traits: []v1alpha1.TraitBinding{
+				{Name: TraitDependsOn, Properties: runtime.RawExtension{Raw: dependsOn}},
+				retention(traits2.ResourceRetention{Kind: ServiceKind, Name: "web", Policy: RetentionRetain}),
+			},
+		},
+	)
+	now := v1.Now()
+	ac.DeletionTimestamp = &now
+	ac.Finalizers = []string{Finalizer}
+	s, k8sclient, hcclient := newTestHandler(
+		[]runtime.Object{ac},
+		[]runtime.Object{
+			&appsv1.Deployment{ObjectMeta: ownedObjectMeta(ac, "web", "web")},
+			&apiv1.Service{ObjectMeta: ownedObjectMeta(ac, "web", "web")},
+			&apiv1.PersistentVolumeClaim{ObjectMeta: ownedObjectMeta(ac, "data", "web")},
+		},
+		[]runtime.Object{&hcv1alpha1.MysqlCluster{ObjectMeta: ownedObjectMeta(ac, "db", "db")}},
+	)
+	recorder := record.NewFakeRecorder(100)
+	s.Recorder = recorder
+
+	teardown := func() *v1alpha1.ApplicationConfiguration {
+		t.Helper()
+		live, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Handle(nil, live, oam.Delete); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+		live, err = s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return live
+	}
+
+	// web depends on db, it is torn down first
+	live := teardown()
+	if want := fmt.Sprintf(TeardownMessage, "web", "deployment/web"); !hasCondition(live, v1alpha1.Cleanup, TeardownInProgress, want) {
+		t.Errorf("conditions = %+v, want Cleanup %s", live.Status.Conditions, want)
+	}
+	if live.Status.Phase != Terminating || !hasFinalizer(live) {
+		t.Errorf("phase = %s, finalizers = %v, want Terminating with the finalizer", live.Status.Phase, live.Finalizers)
+	}
+	if _, err := k8sclient.AppsV1().Deployments(ac.Namespace).Get("web", v1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Deployment web error = %v, want NotFound", err)
+	}
+	// the Service and the PersistentVolumeClaim are retained, released from the ApplicationConfiguration
+	service, err := k8sclient.CoreV1().Services(ac.Namespace).Get("web", v1.GetOptions{})
+	if err != nil || len(service.OwnerReferences) > 0 {
+		t.Errorf("Service web = %+v, %v, want it released", service, err)
+	}
+	pvc, err := k8sclient.CoreV1().PersistentVolumeClaims(ac.Namespace).Get("data", v1.GetOptions{})
+	if err != nil || len(pvc.OwnerReferences) > 0 {
+		t.Errorf("PersistentVolumeClaim data = %+v, %v, want it released", pvc, err)
+	}
+	if _, err := hcclient.MysqlV1alpha1().MysqlClusters(ac.Namespace).Get(nil, "db", v1.GetOptions{}); err != nil {
+		t.Errorf("MysqlCluster db error = %v, want it kept until web is gone", err)
+	}
+
+	// db is deleted with its data
+	live = teardown()
+	if want := fmt.Sprintf(TeardownMessage, "db", "mysqlcluster/db"); !hasCondition(live, v1alpha1.Cleanup, TeardownInProgress, want) {
+		t.Errorf("conditions = %+v, want Cleanup %s", live.Status.Conditions, want)
+	}
+	var marked bool
+	for _, action := range hcclient.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok && patch.GetName() == "db" && string(patch.GetPatch()) == `{"spec":{"batchDelete":true,"delete":true}}` {
+			marked = true
+		}
+	}
+	if !marked {
+		t.Error("MysqlCluster db was not marked deleted before its deletion")
+	}
+
+	// the finalizer is removed once all resources are gone
+	live = teardown()
+	if hasFinalizer(live) {
+		t.Errorf("finalizers = %v, want the finalizer removed", live.Finalizers)
+	}
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	if want := fmt.Sprintf("%s %s %s", apiv1.EventTypeNormal, TornDown, fmt.Sprintf(MessageTornDown, ac.Name)); events[len(events)-1] != want {
+		t.Errorf("last event = %q, want %q", events[len(events)-1], want)
+	}
+}
+
+func TestTeardownWithoutFinalizer(t *testing.T) {
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"})
+	now := v1.Now()
+	ac.DeletionTimestamp = &now
+	s, k8sclient, _ := newTestHandler([]runtime.Object{ac}, []runtime.Object{&appsv1.Deployment{ObjectMeta: ownedObjectMeta(ac, "web", "web")}}, nil)
+	if err := s.Handle(nil, ac.DeepCopy(), oam.Delete); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	// the garbage collector deletes the resources of ApplicationConfigurations without the finalizer
+	if _, err := k8sclient.AppsV1().Deployments(ac.Namespace).Get("web", v1.GetOptions{}); err != nil {
+		t.Errorf("Deployment web error = %v, want it left to the garbage collector", err)
+	}
+}
+
+func hasCondition(ac *v1alpha1.ApplicationConfiguration, conditionType v1alpha1.ApplicationConditionType, reason, message string) bool {
+	for _, c := range ac.Status.Conditions {
+		if c.Type == conditionType && c.Reason == reason && c.Message == message {
+			return true
+		}
+	}
+	return false
+}
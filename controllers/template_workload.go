@@ -0,0 +1,390 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// TemplateRenderer renders the workload types whose WorkloadType carries a template of the target object,
+// so that the custom resources of operators can be used as workloads without code changes.
+type TemplateRenderer struct {
+	workloadType string
+	gvk          schema.GroupVersionKind
+	template     *template.Template
+	health       *statusExpression
+	degraded     *statusExpression
+}
+
+// templateData is passed to the template of a WorkloadType.
+type templateData struct {
+	Name        string
+	Namespace   string
+	Application string
+	Component   string
+	// Settings are the workload settings of the component, objects and arrays are JSON
+	Settings   map[string]string
+	Parameters map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"toJson": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"default": func(d, v string) string {
+		if v == "" {
+			return d
+		}
+		return v
+	},
+}
+
+// WorkloadTypeName returns the workload type a WorkloadType defines, e.g. harmonycloud.cn/v1alpha1.Redis.
+func WorkloadTypeName(wt *v1alpha1.WorkloadType) string {
+	return wt.Spec.Group + "/" + wt.Spec.Version + "." + wt.Spec.Names.Kind
+}
+
+func isTemplateWorkloadType(wt *v1alpha1.WorkloadType) bool {
+	return wt.Annotations[TemplateAnnotation] != ""
+}
+
+// newTemplateRenderer parses the template, the target and the health expressions of the WorkloadType.
+func newTemplateRenderer(wt *v1alpha1.WorkloadType) (*TemplateRenderer, error) {
+	annotations := wt.Annotations
+	gv, err := schema.ParseGroupVersion(annotations[TargetApiVersionAnnotation])
+	if err != nil || gv.Version == "" || annotations[TargetKindAnnotation] == "" {
+		return nil, fmt.Errorf("WorkloadType %s: %s and %s of the target are required", wt.Name, TargetApiVersionAnnotation, TargetKindAnnotation)
+	}
+	tmpl, err := template.New(wt.Name).Option("missingkey=zero").Funcs(templateFuncs).Parse(annotations[TemplateAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("WorkloadType %s: %v", wt.Name, err)
+	}
+	health, err := parseStatusExpression(annotations[HealthAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("WorkloadType %s: %v", wt.Name, err)
+	}
+	degraded, err := parseStatusExpression(annotations[DegradedAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("WorkloadType %s: %v", wt.Name, err)
+	}
+	return &TemplateRenderer{
+		workloadType: WorkloadTypeName(wt),
+		gvk:          gv.WithKind(annotations[TargetKindAnnotation]),
+		template:     tmpl,
+		health:       health,
+		degraded:     degraded,
+	}, nil
+}
+
+func (r *TemplateRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	settings, err := getWorkloadSettings(ctx.Component, ctx.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	compConf := ctx.ComponentConfiguration
+	var buf bytes.Buffer
+	err = r.template.Execute(&buf, templateData{
+		Name:        compConf.InstanceName,
+		Namespace:   ctx.Namespace,
+		Application: ctx.Owner.Name,
+		Component:   compConf.ComponentName,
+		Settings:    settings,
+		Parameters:  ctx.Parameters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("render template of workload type %s: %v", r.workloadType, err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(buf.Bytes(), &obj.Object); err != nil {
+		return nil, fmt.Errorf("render template of workload type %s: %v", r.workloadType, err)
+	}
+	if obj.Object == nil {
+		return nil, fmt.Errorf("template of workload type %s rendered nothing", r.workloadType)
+	}
+	if gvk := obj.GroupVersionKind(); !gvk.Empty() && gvk != r.gvk {
+		return nil, fmt.Errorf("template of workload type %s rendered %s instead of %s", r.workloadType, gvk, r.gvk)
+	}
+	obj.SetGroupVersionKind(r.gvk)
+	if obj.GetName() == "" {
+		obj.SetName(compConf.InstanceName)
+	}
+	obj.SetNamespace("")
+	annotations := ctx.NewAnnotations()
+	annotations[Role] = Workload
+	for k, v := range obj.GetAnnotations() {
+		annotations[k] = v
+	}
+	obj.SetAnnotations(annotations)
+	obj.SetOwnerReferences([]v1.OwnerReference{ctx.Owner})
+
+	traitObjects, err := applyTraits(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return append([]runtime.Object{obj}, traitObjects...), nil
+}
+
+func (r *TemplateRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return instanceHealth(ac, instanceName, objects, func(obj runtime.Object) (Health, error) {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			return r.objectHealth(u), nil
+		}
+		return objectHealth(obj)
+	})
+}
+
+// objectHealth evaluates the health expressions of the WorkloadType, the object is ready if it has none.
+func (r *TemplateRenderer) objectHealth(obj *unstructured.Unstructured) Health {
+	if r.degraded != nil && r.degraded.matches(obj) {
+		return degraded("%s %s is degraded: %s", obj.GetKind(), obj.GetName(), r.degraded.display(obj))
+	}
+	if r.health == nil || r.health.matches(obj) {
+		return ready()
+	}
+	return progressing("%s %s is not ready: %s", obj.GetKind(), obj.GetName(), r.health.display(obj))
+}
+
+// status returns the fields of the health and degraded expressions as the status of a resource.
+func (r *TemplateRenderer) status(obj *unstructured.Unstructured) string {
+	var fields []string
+	for _, e := range []*statusExpression{r.health, r.degraded} {
+		if e != nil && (len(fields) == 0 || fields[0] != e.display(obj)) {
+			fields = append(fields, e.display(obj))
+		}
+	}
+	if len(fields) == 0 {
+		return Created
+	}
+	return strings.Join(fields, ", ") + "."
+}
+
+func (r *TemplateRenderer) Kind() string {
+	return r.workloadType[strings.LastIndex(r.workloadType, ".")+1:]
+}
+
+func (r *TemplateRenderer) GroupVersion() string {
+	return r.workloadType[:strings.LastIndex(r.workloadType, ".")]
+}
+
+// statusExpression is a condition on a field of an object, e.g. .status.phase == Running.
+// A field without an operator is true if it is set and neither false nor empty.
+type statusExpression struct {
+	path     []string
+	operator string
+	value    string
+}
+
+func parseStatusExpression(expr string) (*statusExpression, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+	e := &statusExpression{}
+	field := expr
+	for _, op := range []string{"==", "!="} {
+		if i := strings.Index(expr, op); i >= 0 {
+			field = strings.TrimSpace(expr[:i])
+			e.operator = op
+			e.value = strings.Trim(strings.TrimSpace(expr[i+len(op):]), `"'`)
+			break
+		}
+	}
+	if !strings.HasPrefix(field, ".") || len(field) < 2 {
+		return nil, fmt.Errorf("invalid status expression %q, the field must be a path like .status.phase", expr)
+	}
+	e.path = strings.Split(field[1:], ".")
+	return e, nil
+}
+
+func (e *statusExpression) field(obj *unstructured.Unstructured) (string, bool) {
+	value, found, err := unstructured.NestedFieldNoCopy(obj.Object, e.path...)
+	if err != nil || !found || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+func (e *statusExpression) matches(obj *unstructured.Unstructured) bool {
+	value, found := e.field(obj)
+	switch e.operator {
+	case "==":
+		return found && value == e.value
+	case "!=":
+		return !found || value != e.value
+	}
+	return found && value != "" && value != "false"
+}
+
+// display returns the field and its value, e.g. .status.phase: Creating.
+func (e *statusExpression) display(obj *unstructured.Unstructured) string {
+	value, found := e.field(obj)
+	if !found {
+		value = "<none>"
+	}
+	return fmt.Sprintf(".%s: %s", strings.Join(e.path, "."), value)
+}
+
+// TemplateWorkload is the target resource of a WorkloadType with a template.
+type TemplateWorkload struct {
+	Name     string
+	GVK      schema.GroupVersionKind
+	Resource schema.GroupVersionResource
+	Renderer *TemplateRenderer
+}
+
+// GetTemplateWorkloads returns the target resources of the WorkloadTypes with a template, objects of
+// these resources are watched and listed by the dynamic client to evaluate, prune and tear them down.
+// The WorkloadTypes are read from the cache of the reader, or listed by the oamclient without a reader.
+func GetTemplateWorkloads(reader client.Reader, oamclient versioned.Interface) ([]TemplateWorkload, error) {
+	workloadTypes, err := listWorkloadTypes(reader, oamclient)
+	if err != nil {
+		return nil, err
+	}
+	var workloads []TemplateWorkload
+	for i := range workloadTypes {
+		wt := &workloadTypes[i]
+		if !isTemplateWorkloadType(wt) {
+			continue
+		}
+		renderer, err := newTemplateRenderer(wt)
+		if err != nil {
+			handlerLog.Info("Invalid WorkloadType.", "WorkloadType", wt.Name, "Error", err)
+			continue
+		}
+		resource := wt.Annotations[TargetResourceAnnotation]
+		if resource == "" {
+			handlerLog.Info("Invalid WorkloadType.", "WorkloadType", wt.Name, "Error", fmt.Sprintf("%s of the target is required", TargetResourceAnnotation))
+			continue
+		}
+		workloads = append(workloads, TemplateWorkload{
+			Name:     wt.Name,
+			GVK:      renderer.gvk,
+			Resource: renderer.gvk.GroupVersion().WithResource(resource),
+			Renderer: renderer,
+		})
+	}
+	return workloads, nil
+}
+
+// listWorkloadTypes reads the WorkloadTypes from the cache of the reader, or lists them through the RESTClient
+// without a reader, the clientset has no typed client of them.
+func listWorkloadTypes(reader client.Reader, oamclient versioned.Interface) ([]v1alpha1.WorkloadType, error) {
+	if reader != nil {
+		list := new(v1alpha1.WorkloadTypeList)
+		if err := reader.List(context.Background(), list); err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+	if fake, ok := oamclient.(*oamfake.Clientset); ok {
+		// fake clientsets have no RESTClient, the WorkloadTypes of a diff are in their tracker
+		list, err := fake.Tracker().List(v1alpha1.SchemeGroupVersion.WithResource("workloadtypes"), v1alpha1.SchemeGroupVersion.WithKind("WorkloadType"), "")
+		if err != nil {
+			return nil, err
+		}
+		return list.(*v1alpha1.WorkloadTypeList).Items, nil
+	}
+	restClient, ok := oamclient.CoreV1alpha1().RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil {
+		return nil, nil
+	}
+	data, err := restClient.Get().Resource("workloadtypes").Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	list := new(v1alpha1.WorkloadTypeList)
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// getTemplateResource returns the resource of a rendered template object.
+func getTemplateResource(reader client.Reader, oamclient versioned.Interface, obj *unstructured.Unstructured) (schema.GroupVersionResource, error) {
+	workloads, err := GetTemplateWorkloads(reader, oamclient)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	for _, w := range workloads {
+		if w.GVK == obj.GroupVersionKind() {
+			return w.Resource, nil
+		}
+	}
+	return schema.GroupVersionResource{}, fmt.Errorf("no WorkloadType with a template of %s", obj.GroupVersionKind())
+}
+
+// listTemplateObjects lists the objects of the WorkloadTypes with a template in the namespace.
+func listTemplateObjects(s *ApplicationConfigurationHandler, namespace string, options v1.ListOptions) ([]*unstructured.Unstructured, error) {
+	if s.Dynamicclient == nil {
+		return nil, nil
+	}
+	workloads, err := GetTemplateWorkloads(s.Reader, s.Oamclient)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*unstructured.Unstructured
+	for _, w := range workloads {
+		list, err := s.Dynamicclient.Resource(w.Resource).Namespace(namespace).List(options)
+		if kindNotServed(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	}
+	return objects, nil
+}
+
+// templateObjectClient returns the dynamic client of the resource of a rendered template object.
+func templateObjectClient(s *ApplicationConfigurationHandler, namespace string, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	if s.Dynamicclient == nil {
+		return nil, errors.New("dynamic client is required by the objects of WorkloadTypes with a template")
+	}
+	resource, err := getTemplateResource(s.Reader, s.Oamclient, obj)
+	if err != nil {
+		return nil, err
+	}
+	return s.Dynamicclient.Resource(resource).Namespace(namespace), nil
+}
+
+// resolveWorkloadRenderer returns the registered renderer of the workload type, or the TemplateRenderer
+// of its WorkloadType. It returns nil if the workload type is undefined.
+func resolveWorkloadRenderer(resolver Resolver, workloadType string) (WorkloadRenderer, error) {
+	if renderer := getWorkloadRenderer(workloadType); renderer != nil {
+		return renderer, nil
+	}
+	wt, err := resolver.GetWorkloadType(workloadType)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !isTemplateWorkloadType(wt) {
+		return nil, nil
+	}
+	renderer, err := newTemplateRenderer(wt)
+	if err != nil {
+		return nil, err
+	}
+	return renderer, nil
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetTemplateWorkloads(t *testing.T) {
+	workloadType := func(name string, annotations map[string]string) *v1alpha1.WorkloadType {
+		return &v1alpha1.WorkloadType{
+			ObjectMeta: v1.ObjectMeta{Name: name, Annotations: annotations},
+			Spec:       v1alpha1.WorkloadTypeSpec{Group: "example.com", Version: "v1", Names: v1alpha1.Names{Kind: name}},
+		}
+	}
+	target := func(kind, resource string) map[string]string {
+		annotations := map[string]string{
+			TargetApiVersionAnnotation: "example.com/v1",
+			TargetKindAnnotation:       kind,
+			TemplateAnnotation:         "spec:\n  size: 1\n",
+		}
+		if resource != "" {
+			annotations[TargetResourceAnnotation] = resource
+		}
+		return annotations
+	}
+	invalid := target("Queue", "queues")
+	invalid[TemplateAnnotation] = "{{ .Name"
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	reader := fake.NewFakeClientWithScheme(scheme,
+		workloadType("Cache", target("Cache", "caches")),
+		// the WorkloadTypes without a resource or with an invalid template are skipped, the others are still watched
+		workloadType("Store", target("Store", "")),
+		workloadType("Queue", invalid),
+		workloadType("Server", nil),
+	)
+
+	// the WorkloadTypes are read from the cache, not listed by the oamclient
+	oamclient := oamfake.NewSimpleClientset(workloadType("Log", target("Log", "logs")))
+	workloads, err := GetTemplateWorkloads(reader, oamclient)
+	if err != nil {
+		t.Fatalf("GetTemplateWorkloads() error = %v", err)
+	}
+	if len(workloads) != 1 || workloads[0].Name != "Cache" || workloads[0].Resource != (schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "caches"}) {
+		t.Errorf("GetTemplateWorkloads() = %+v, want the resource caches of Cache", workloads)
+	}
+}
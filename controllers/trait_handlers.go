@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ManualScalerTrait sets the replicas of the workload.
+type ManualScalerTrait struct{}
+
+// VolumeMounterTrait renders a PersistentVolumeClaim for a volume of the component and mounts it.
+type VolumeMounterTrait struct{}
+
+// AutoScalerTrait renders a HorizontalPodAutoscaler for the workload.
+type AutoScalerTrait struct{}
+
+// BetterAutoScalerTrait renders a harmonycloud.cn HorizontalPodAutoscaler for the workload.
+type BetterAutoScalerTrait struct{}
+
+// IngressTrait renders an Ingress for the service of the component instance.
+type IngressTrait struct{}
+
+// LogPilotTrait collects the logs of a container by log-pilot.
+type LogPilotTrait struct{}
+
+// HostPolicyTrait sets the host namespaces of the pod.
+type HostPolicyTrait struct{}
+
+// ResourcesPolicyTrait sets the resource limits of a container.
+type ResourcesPolicyTrait struct{}
+
+// SchedulePolicyTrait sets the affinity of the pod.
+type SchedulePolicyTrait struct{}
+
+// DependsOnTrait declares the component instances which must be healthy before the component instance is rendered.
+// It is read by orderComponents and renders nothing.
+type DependsOnTrait struct{}
+
+// RetentionPolicyTrait declares whether the resources of the component instance are deleted, retained or orphaned
+// when the ApplicationConfiguration is deleted. It is read by teardown and renders nothing.
+type RetentionPolicyTrait struct{}
+
+// MysqlBackupTrait renders a CronJob which dumps the MysqlCluster to a backup volume, and a Job which
+// restores a backup when the ApplicationConfiguration requests it by the restore annotation.
+type MysqlBackupTrait struct{}
+
+// RolloutTrait sets the rolling update of the Deployment. Canary and blue/green rollouts are driven by
+// progressRollout against the live Deployments.
+type RolloutTrait struct{}
+
+func (t *ManualScalerTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	replicas, err := getManuelScale(trait)
+	if err != nil {
+		return nil, err
+	}
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		w.Spec.Replicas = &replicas
+	case *appsv1.StatefulSet:
+		w.Spec.Replicas = &replicas
+	case *appsv1.DaemonSet:
+		// a DaemonSet runs one replica on every node selected by its schedule policy
+	case *batchv1.Job:
+		w.Spec.Parallelism = &replicas
+	case *batchv1beta1.CronJob:
+		w.Spec.JobTemplate.Spec.Parallelism = &replicas
+	case *hcv1alpha1.MysqlCluster:
+		w.Spec.Replicas = &replicas
+	default:
+		return nil, fmt.Errorf("workload %T can not be scaled", workload)
+	}
+	return nil, nil
+}
+
+func (t *VolumeMounterTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	// MysqlCluster claims its volume in convertMysqlCluster
+	if _, ok := workload.(*hcv1alpha1.MysqlCluster); ok {
+		return nil, nil
+	}
+	template, err := requirePodTemplateSpec(workload)
+	if err != nil {
+		return nil, err
+	}
+	pvc, err := convertPvcFromVolumeMounter(ctx.Owner, ctx.NewAnnotations(), ctx.Component, trait)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := getVolumeFromVolumeMounter(trait)
+	if err != nil {
+		return nil, err
+	}
+	template.Spec.Volumes = append(template.Spec.Volumes, *volume)
+	return []runtime.Object{pvc}, nil
+}
+
+func (t *AutoScalerTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	kind, apiVersion, err := getScaleTarget(workload)
+	if err != nil {
+		return nil, err
+	}
+	hpa, err := convertHpa(ctx.Owner, ctx.NewAnnotations(), kind, apiVersion, ctx.ComponentConfiguration.InstanceName, trait)
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{hpa}, nil
+}
+
+func (t *AutoScalerTrait) ControlsReplicas() bool {
+	return true
+}
+
+func (t *BetterAutoScalerTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	kind, apiVersion, err := getScaleTarget(workload)
+	if err != nil {
+		return nil, err
+	}
+	hcHpa, err := convertHcHpa(ctx.Owner, ctx.NewAnnotations(), kind, apiVersion, ctx.ComponentConfiguration.InstanceName, trait)
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{hcHpa}, nil
+}
+
+func (t *BetterAutoScalerTrait) ControlsReplicas() bool {
+	return true
+}
+
+func (t *IngressTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	ingress, err := convertIngress(ctx.Owner, ctx.NewAnnotations(), ctx.ComponentConfiguration.InstanceName, trait)
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{ingress}, nil
+}
+
+func (t *LogPilotTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	template, err := requirePodTemplateSpec(workload)
+	if err != nil {
+		return nil, err
+	}
+	logPilot := new(traits2.LogPilot)
+	if err := parsePropertiesOfTrait(trait, logPilot); err != nil {
+		return nil, err
+	}
+	template.Spec.Volumes = append(template.Spec.Volumes, getLogPilotVolume(logPilot))
+	for i := range template.Spec.Containers {
+		injectLogPilotConfigs(&template.Spec.Containers[i], logPilot)
+	}
+	return nil, nil
+}
+
+func (t *HostPolicyTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	template, err := requirePodTemplateSpec(workload)
+	if err != nil {
+		return nil, err
+	}
+	injectHostPolicy(&template.Spec, trait)
+	return nil, nil
+}
+
+func (t *ResourcesPolicyTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	template, err := requirePodTemplateSpec(workload)
+	if err != nil {
+		return nil, err
+	}
+	for i := range template.Spec.Containers {
+		injectResourcesPolicy(&template.Spec.Containers[i], trait)
+	}
+	return nil, nil
+}
+
+func (t *SchedulePolicyTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	template, err := requirePodTemplateSpec(workload)
+	if err != nil {
+		return nil, err
+	}
+	injectSchedulePolicy(ctx.Namespace, &template.Spec, trait)
+	return nil, nil
+}
+
+func (t *DependsOnTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	return nil, nil
+}
+
+func (t *RetentionPolicyTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	return nil, nil
+}
+
+func (t *MysqlBackupTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	mysqlCluster, ok := workload.(*hcv1alpha1.MysqlCluster)
+	if !ok {
+		return nil, fmt.Errorf("workload %T is not a MysqlCluster", workload)
+	}
+	mysqlBackup := new(traits2.MysqlBackup)
+	if err := parsePropertiesOfTrait(trait, mysqlBackup); err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	if mysqlBackup.PvcName == "" {
+		pvc, err := convertBackupPvc(ctx.Owner, ctx.NewAnnotations(), mysqlCluster.Name, mysqlBackup)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, pvc)
+	}
+	cronJob, err := convertBackupCronJob(ctx.Owner, ctx.NewAnnotations(), mysqlCluster.Name, mysqlBackup)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, cronJob)
+
+	backup, err := getRestoreBackup(ctx.ApplicationAnnotations, mysqlCluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	if backup != "" {
+		objects = append(objects, convertRestoreJob(ctx.Owner, ctx.NewAnnotations(), mysqlCluster.Name, backup, mysqlBackup))
+	}
+	return objects, nil
+}
+
+func (t *RolloutTrait) Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error) {
+	deployment, ok := workload.(*appsv1.Deployment)
+	if !ok {
+		return nil, fmt.Errorf("workload %T is not a Deployment", workload)
+	}
+	rollout := new(traits2.Rollout)
+	if err := parsePropertiesOfTrait(trait, rollout); err != nil {
+		return nil, err
+	}
+	if rollout.ProgressDeadlineSeconds > 0 {
+		deployment.Spec.ProgressDeadlineSeconds = &rollout.ProgressDeadlineSeconds
+	}
+	if rollout.MaxSurge != nil || rollout.MaxUnavailable != nil {
+		deployment.Spec.Strategy = appsv1.DeploymentStrategy{
+			Type: appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{
+				MaxSurge:       rollout.MaxSurge,
+				MaxUnavailable: rollout.MaxUnavailable,
+			},
+		}
+	}
+	return nil, nil
+}
+
+func requirePodTemplateSpec(workload runtime.Object) (*apiv1.PodTemplateSpec, error) {
+	template := getPodTemplateSpec(workload)
+	if template == nil {
+		return nil, fmt.Errorf("workload %T has no pod template", workload)
+	}
+	return template, nil
+}
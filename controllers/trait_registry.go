@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TraitHandler applies a trait bound to a component instance.
+type TraitHandler interface {
+	// Apply mutates the rendered workload and returns the extra objects of the trait.
+	Apply(ctx *RenderContext, trait v1alpha1.TraitBinding, workload runtime.Object) ([]runtime.Object, error)
+}
+
+// ReplicasController is implemented by traits which manage the replicas of the workload at runtime,
+// e.g. autoscalers. The live replicas are kept when the workload is patched.
+type ReplicasController interface {
+	ControlsReplicas() bool
+}
+
+var (
+	traitHandlersLock = new(sync.RWMutex)
+	traitHandlers     = map[string]TraitHandler{}
+	// alias -> name of the Trait object
+	traitAliases = map[string]string{}
+)
+
+// RegisterTrait registers the handler of a trait. Aliases are other names a trait binding may use
+// for the same Trait, e.g. nginx-ingress for ingress.
+func RegisterTrait(name string, handler TraitHandler, aliases ...string) {
+	traitHandlersLock.Lock()
+	defer traitHandlersLock.Unlock()
+	traitHandlers[name] = handler
+	for _, alias := range aliases {
+		traitAliases[alias] = name
+	}
+}
+
+func getTraitName(name string) string {
+	traitHandlersLock.RLock()
+	defer traitHandlersLock.RUnlock()
+	if traitName, ok := traitAliases[name]; ok {
+		return traitName
+	}
+	return name
+}
+
+func getTraitHandler(name string) TraitHandler {
+	name = getTraitName(name)
+	traitHandlersLock.RLock()
+	defer traitHandlersLock.RUnlock()
+	return traitHandlers[name]
+}
+
+// controlsReplicas returns true if a trait of the component instance manages the replicas of its workload.
+func controlsReplicas(ac *v1alpha1.ApplicationConfiguration, instance string) bool {
+	for _, compConf := range ac.Spec.Components {
+		if compConf.InstanceName != instance {
+			continue
+		}
+		for _, tr := range compConf.Traits {
+			if c, ok := getTraitHandler(tr.Name).(ReplicasController); ok && c.ControlsReplicas() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// traitRejection is a trait binding which is not applied to the component instance.
+type traitRejection struct {
+	Trait   v1alpha1.TraitBinding
+	Reason  string
+	Message string
+}
+
+// applicableTraits returns the trait bindings of the component which apply to its workload type
+// according to spec.appliesTo of the Trait, with their properties validated against the schema of the Trait
+// and defaults applied. Rejected bindings are reported by events and in the status of the ApplicationConfiguration.
+func applicableTraits(s *ApplicationConfigurationHandler, ac *v1alpha1.ApplicationConfiguration, compConf v1alpha1.ComponentConfiguration, workloadType string) ([]v1alpha1.TraitBinding, []string, error) {
+	removeTraitStatus(&ac.Status.Resources, compConf.InstanceName)
+	traits, rejections, err := filterTraits(&clusterResolver{Oamclient: s.Oamclient, Reader: s.Reader}, compConf, workloadType)
+	if err != nil {
+		return nil, nil, err
+	}
+	var rejected []string
+	for _, r := range rejections {
+		handlerLog.Info("Trait rejected.", "Namespace", ac.Namespace, "ApplicationConfiguration", ac.Name, "Component", compConf.ComponentName, "Trait", r.Trait.Name, "Message", r.Message)
+		s.Recorder.Event(ac, apiv1.EventTypeWarning, r.Reason, r.Message)
+		addResourceStatus(&ac.Status.Resources, r.Trait.Name, OamV1alpha1GroupVersion, TraitKind, compConf.InstanceName, Trait, fmt.Sprintf(InvalidStatus, r.Message))
+		rejected = append(rejected, r.Message)
+	}
+	return traits, rejected, nil
+}
+
+// filterTraits splits the trait bindings of the component into the applicable ones and the rejected ones.
+func filterTraits(resolver Resolver, compConf v1alpha1.ComponentConfiguration, workloadType string) ([]v1alpha1.TraitBinding, []traitRejection, error) {
+	var traits []v1alpha1.TraitBinding
+	var rejections []traitRejection
+	reject := func(tr v1alpha1.TraitBinding, reason, msg string) {
+		rejections = append(rejections, traitRejection{Trait: tr, Reason: reason, Message: msg})
+	}
+	for _, tr := range compConf.Traits {
+		if getTraitHandler(tr.Name) == nil {
+			reject(tr, TraitNotApplicable, fmt.Sprintf(TraitUndefined, tr.Name, compConf.InstanceName))
+			continue
+		}
+		trait, err := resolver.GetTrait(getTraitName(tr.Name))
+		if apierrors.IsNotFound(err) {
+			reject(tr, TraitNotApplicable, fmt.Sprintf(TraitNotFound, tr.Name, compConf.InstanceName))
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if !traitAppliesTo(trait, workloadType) {
+			reject(tr, TraitNotApplicable, fmt.Sprintf(TraitNotApplicableMessage, tr.Name, workloadType, compConf.InstanceName))
+			continue
+		}
+		tr, err = validateTraitProperties(trait, tr)
+		if err != nil {
+			reject(tr, InvalidProperties, fmt.Sprintf(TraitInvalidMessage, tr.Name, compConf.InstanceName, err.Error()))
+			continue
+		}
+		traits = append(traits, tr)
+	}
+	return traits, rejections, nil
+}
+
+// validateTraitProperties validates the properties of the trait binding against spec.properties of the Trait,
+// and returns the binding with the defaults of the schema applied.
+func validateTraitProperties(trait *v1alpha1.Trait, tr v1alpha1.TraitBinding) (v1alpha1.TraitBinding, error) {
+	schema, err := parseSchema(trait.Spec.Properties)
+	if err != nil {
+		// a broken schema of the Trait should not block its bindings
+		handlerLog.Info("Invalid properties schema of trait.", "Trait", trait.Name, "Error", err)
+		return tr, nil
+	}
+	if schema == nil {
+		return tr, nil
+	}
+	var properties interface{} = map[string]interface{}{}
+	if len(tr.Properties.Raw) > 0 {
+		if err := json.Unmarshal(tr.Properties.Raw, &properties); err != nil {
+			return tr, err
+		}
+	}
+	properties = schema.applyDefaults(properties)
+	if errs := schema.validate("properties", properties); len(errs) > 0 {
+		return tr, errors.New(strings.Join(errs, ", "))
+	}
+	raw, err := json.Marshal(properties)
+	if err != nil {
+		return tr, err
+	}
+	tr.Properties.Raw = raw
+	tr.Properties.Object = nil
+	return tr, nil
+}
+
+func traitAppliesTo(trait *v1alpha1.Trait, workloadType string) bool {
+	for _, w := range trait.Spec.AppliesTo {
+		if w == "*" || w == workloadType {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTraits applies the traits of the component instance to the workload, and returns the objects rendered by the traits.
+func applyTraits(ctx *RenderContext, workload runtime.Object) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	for _, tr := range ctx.ComponentConfiguration.Traits {
+		handler := getTraitHandler(tr.Name)
+		if handler == nil {
+			continue
+		}
+		traitObjects, err := handler.Apply(ctx, tr, workload)
+		if err != nil {
+			return nil, fmt.Errorf("apply trait %s: %v", tr.Name, err)
+		}
+		objects = append(objects, traitObjects...)
+	}
+	return mergeIngresses(objects), nil
+}
+
+// getPodTemplateSpec returns the pod template of the workload, or nil if the workload has none.
+func getPodTemplateSpec(workload runtime.Object) *apiv1.PodTemplateSpec {
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	case *batchv1.Job:
+		return &w.Spec.Template
+	case *batchv1beta1.CronJob:
+		return &w.Spec.JobTemplate.Spec.Template
+	}
+	return nil
+}
+
+// getScaleTarget returns the kind and api version of the workload referenced by autoscalers.
+func getScaleTarget(workload runtime.Object) (string, string, error) {
+	switch workload.(type) {
+	case *appsv1.Deployment:
+		return DeploymentKind, DeploymentApiVersion, nil
+	case *appsv1.StatefulSet:
+		return StatefulSetKind, StatefulSetApiVersion, nil
+	case *batchv1.Job:
+		return JobKind, JobApiVersion, nil
+	}
+	return "", "", fmt.Errorf("workload %T can not be scaled", workload)
+}
+
+// sortObjects sorts the objects of a component instance in the order they should be applied,
+// configs and volumes first, then the workload, then the objects which refer to the workload.
+func sortObjects(objects []runtime.Object) {
+	order := func(obj runtime.Object) int {
+		key, _ := getResourceKey(obj)
+		switch key.Kind {
+		case ConfigMapKind, SecretKind:
+			return 0
+		case PvcKind:
+			return 1
+		case ServiceKind:
+			return 3
+		case IngressKind:
+			return 4
+		case HpaKind:
+			return 5
+		}
+		return 2
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return order(objects[i]) < order(objects[j])
+	})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	oamfake "github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestTraitAppliesTo(t *testing.T) {
+	tests := []struct {
+		appliesTo    []string
+		workloadType string
+		want         bool
+	}{
+		{[]string{WorkloadTypeServer}, WorkloadTypeServer, true},
+		{[]string{WorkloadTypeServer}, WorkloadTypeWorker, false},
+		{[]string{"*"}, WorkloadTypeWorker, true},
+		{nil, WorkloadTypeServer, false},
+	}
+	for _, tt := range tests {
+		trait := &v1alpha1.Trait{Spec: v1alpha1.TraitSpec{AppliesTo: tt.appliesTo}}
+		if got := traitAppliesTo(trait, tt.workloadType); got != tt.want {
+			t.Errorf("traitAppliesTo(%v, %s) = %v, want %v", tt.appliesTo, tt.workloadType, got, tt.want)
+		}
+	}
+}
+
+func TestFilterTraits(t *testing.T) {
+	RegisterBuiltins()
+	scaler := &v1alpha1.Trait{
+		ObjectMeta: v1.ObjectMeta{Name: TraitManualScaler},
+		Spec: v1alpha1.TraitSpec{
+			AppliesTo:  []string{WorkloadTypeServer},
+			Properties: `{"type": "object", "required": ["replicaCount"], "properties": {"replicaCount": {"type": "integer", "minimum": 0}}}`,
+		},
+	}
+	resolver := &clusterResolver{Oamclient: oamfake.NewSimpleClientset(scaler)}
+	binding := func(name, properties string) v1alpha1.TraitBinding {
+		return v1alpha1.TraitBinding{Name: name, Properties: runtime.RawExtension{Raw: []byte(properties)}}
+	}
+	tests := []struct {
+		name         string
+		workloadType string
+		trait        v1alpha1.TraitBinding
+		applied      bool
+		reason       string
+	}{
+		{"applicable", WorkloadTypeServer, binding(TraitManualScaler, `{"replicaCount":2}`), true, ""},
+		{"not applicable", WorkloadTypeWorker, binding(TraitManualScaler, `{"replicaCount":2}`), false, TraitNotApplicable},
+		{"invalid properties", WorkloadTypeServer, binding(TraitManualScaler, `{"replicaCount":-1}`), false, InvalidProperties},
+		{"undefined", WorkloadTypeServer, binding("unknown", `{}`), false, TraitNotApplicable},
+		{"not found", WorkloadTypeServer, binding(TraitIngress, `{}`), false, TraitNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compConf := v1alpha1.ComponentConfiguration{InstanceName: "web", Traits: []v1alpha1.TraitBinding{tt.trait}}
+			traits, rejections, err := filterTraits(resolver, compConf, tt.workloadType)
+			if err != nil {
+				t.Fatalf("filterTraits() error = %v", err)
+			}
+			if applied := len(traits) == 1; applied != tt.applied {
+				t.Errorf("filterTraits() applied = %v, want %v", applied, tt.applied)
+			}
+			var reason string
+			if len(rejections) > 0 {
+				reason = rejections[0].Reason
+			}
+			if reason != tt.reason {
+				t.Errorf("filterTraits() rejection reason = %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSortObjects(t *testing.T) {
+	objects := []runtime.Object{
+		&extensionsv1beta1.Ingress{}, &appsv1.Deployment{}, &apiv1.Service{}, &apiv1.ConfigMap{},
+	}
+	sortObjects(objects)
+	var kinds []string
+	for _, obj := range objects {
+		key, _ := getResourceKey(obj)
+		kinds = append(kinds, key.Kind)
+	}
+	if want := []string{ConfigMapKind, DeploymentKind, ServiceKind, IngressKind}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("sortObjects() = %v, want %v", kinds, want)
+	}
+}
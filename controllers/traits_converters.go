@@ -4,405 +4,350 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"hash/fnv"
 	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
 	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
 	"k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"strings"
 
 	//"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"reflect"
 )
 
 var (
 	traitsConverterLog = ctrl.Log.WithName("traits-converter")
 )
 
-func convertHpa(owner v1.OwnerReference, annotations map[string]string, kind string, apiVersion string, instanceName string, traits []v1alpha1.TraitBinding) *v2beta2.HorizontalPodAutoscaler {
+func convertHpa(owner v1.OwnerReference, annotations map[string]string, kind string, apiVersion string, instanceName string, tr v1alpha1.TraitBinding) (*v2beta2.HorizontalPodAutoscaler, error) {
 	annotations["role"] = "trait"
-	var hpa *v2beta2.HorizontalPodAutoscaler
-	for _, tr := range traits {
-		if tr.Name != "auto-scaler" {
-			continue
-		}
-		values, err := parsePropertiesOfTrait(tr)
-		if err != nil {
-			continue
-		}
-
-		min, ok := values["minimum"]
-		var minimum int32
-		if !ok {
-			minimum = 1
-		} else {
-			minimum = int32(min.(float64))
-		}
-
-		max, ok := values["maximum"]
-		var maximum int32
-		if !ok {
-			maximum = 10
-		} else {
-			maximum = int32(max.(float64))
-		}
+	autoScaler := new(traits2.AutoScaler)
+	if err := json.Unmarshal(tr.Properties.Raw, autoScaler); err != nil {
+		traitsConverterLog.Info(err.Error())
+		return nil, err
+	}
+	if autoScaler.Minimum < 1 {
+		autoScaler.Minimum = 1
+	}
+	if autoScaler.Maximum < 1 {
+		autoScaler.Maximum = 10
+	}
 
-		cpu, ok := values["cpu"]
-		var cpuMetric v2beta2.MetricSpec
-		if ok {
-			utilization := int32(cpu.(float64))
-			cpuMetric = v2beta2.MetricSpec{
-				Type: v2beta2.ResourceMetricSourceType,
-				Resource: &v2beta2.ResourceMetricSource{
-					Name: "cpu",
-					Target: v2beta2.MetricTarget{
-						Type:               v2beta2.UtilizationMetricType,
-						AverageUtilization: &utilization,
-					},
-				},
-			}
+	var metrics []v2beta2.MetricSpec
+	for _, m := range []struct {
+		name        apiv1.ResourceName
+		utilization int32
+	}{{apiv1.ResourceCPU, autoScaler.Cpu}, {apiv1.ResourceMemory, autoScaler.Memory}} {
+		if m.utilization <= 0 {
+			continue
 		}
-
-		memory, ok := values["memory"]
-		var memoryMetric v2beta2.MetricSpec
-		if ok {
-			utilization := int32(memory.(float64))
-			memoryMetric = v2beta2.MetricSpec{
-				Type: v2beta2.ResourceMetricSourceType,
-				Resource: &v2beta2.ResourceMetricSource{
-					Name: "memory",
-					Target: v2beta2.MetricTarget{
-						Type:               v2beta2.UtilizationMetricType,
-						AverageUtilization: &utilization,
-					},
+		utilization := m.utilization
+		metrics = append(metrics, v2beta2.MetricSpec{
+			Type: v2beta2.ResourceMetricSourceType,
+			Resource: &v2beta2.ResourceMetricSource{
+				Name: m.name,
+				Target: v2beta2.MetricTarget{
+					Type:               v2beta2.UtilizationMetricType,
+					AverageUtilization: &utilization,
 				},
-			}
-		}
+			},
+		})
+	}
 
-		hpa = &v2beta2.HorizontalPodAutoscaler{
-			ObjectMeta: v1.ObjectMeta{
-				Name: instanceName,
-				OwnerReferences: []v1.OwnerReference{
-					owner,
-				},
-				Annotations: annotations,
+	hpa := &v2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: v1.ObjectMeta{
+			Name: instanceName,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
 			},
-			Spec: v2beta2.HorizontalPodAutoscalerSpec{
-				ScaleTargetRef: v2beta2.CrossVersionObjectReference{
-					Kind:       kind,
-					Name:       instanceName,
-					APIVersion: apiVersion,
-				},
-				MinReplicas: &minimum,
-				MaxReplicas: maximum,
-				Metrics: []v2beta2.MetricSpec{
-					cpuMetric,
-					memoryMetric,
-				},
+			Annotations: annotations,
+		},
+		Spec: v2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: v2beta2.CrossVersionObjectReference{
+				Kind:       kind,
+				Name:       instanceName,
+				APIVersion: apiVersion,
 			},
-		}
+			MinReplicas: &autoScaler.Minimum,
+			MaxReplicas: autoScaler.Maximum,
+			Metrics:     metrics,
+		},
 	}
-	return hpa
+	return hpa, nil
 }
 
-func convertHcHpa(owner v1.OwnerReference, annotations map[string]string, kind string, apiVersion string, instanceName string, traits []v1alpha1.TraitBinding) *hcv1beta1.HorizontalPodAutoscaler {
+func convertHcHpa(owner v1.OwnerReference, annotations map[string]string, kind string, apiVersion string, instanceName string, tr v1alpha1.TraitBinding) (*hcv1beta1.HorizontalPodAutoscaler, error) {
 	annotations["role"] = "trait"
-	var hcHpa *hcv1beta1.HorizontalPodAutoscaler
-	for _, tr := range traits {
-		if tr.Name != "better-auto-scaler" {
-			continue
-		}
-		betterAutoScaler := new(traits2.BetterAutoScaler)
-		if err := json.Unmarshal(tr.Properties.Raw, &betterAutoScaler); err != nil {
-			traitsConverterLog.Info(err.Error())
-		}
+	betterAutoScaler := new(traits2.BetterAutoScaler)
+	if err := json.Unmarshal(tr.Properties.Raw, &betterAutoScaler); err != nil {
+		traitsConverterLog.Info(err.Error())
+		return nil, err
+	}
 
-		strVarToIntVar(&betterAutoScaler.Maximum)
-		strVarToIntVar(&betterAutoScaler.Minimum)
-		strVarToIntVar(&betterAutoScaler.CpuDown)
-		strVarToIntVar(&betterAutoScaler.CpuUp)
-		strVarToIntVar(&betterAutoScaler.MemoryDown)
-		strVarToIntVar(&betterAutoScaler.MemoryUp)
-
-		if betterAutoScaler.Minimum.IntVal < 1 {
-			betterAutoScaler.Minimum = intstr.IntOrString{
-				Type:   0,
-				IntVal: 1,
-				StrVal: "",
-			}
+	strVarToIntVar(&betterAutoScaler.Maximum)
+	strVarToIntVar(&betterAutoScaler.Minimum)
+	strVarToIntVar(&betterAutoScaler.CpuDown)
+	strVarToIntVar(&betterAutoScaler.CpuUp)
+	strVarToIntVar(&betterAutoScaler.MemoryDown)
+	strVarToIntVar(&betterAutoScaler.MemoryUp)
+
+	if betterAutoScaler.Minimum.IntVal < 1 {
+		betterAutoScaler.Minimum = intstr.IntOrString{
+			Type:   0,
+			IntVal: 1,
+			StrVal: "",
 		}
+	}
 
-		if betterAutoScaler.Minimum.IntVal < 1 {
-			betterAutoScaler.Minimum = intstr.IntOrString{
-				Type:   0,
-				IntVal: 2,
-				StrVal: "",
-			}
+	if betterAutoScaler.Minimum.IntVal < 1 {
+		betterAutoScaler.Minimum = intstr.IntOrString{
+			Type:   0,
+			IntVal: 2,
+			StrVal: "",
 		}
+	}
 
-		var cpuUpMetric hcv1beta1.MetricSpec
-		if betterAutoScaler.CpuUp.IntVal > 0 && betterAutoScaler.CpuUp.IntVal < 100 {
-			utilization := betterAutoScaler.CpuUp
-			cpuUpMetric = hcv1beta1.MetricSpec{
-				Type: hcv1beta1.ResourceMetricSourceType,
-				Resource: &hcv1beta1.ResourceMetricSource{
-					Name: "cpu",
-					Target: hcv1beta1.MetricTarget{
-						Type:               hcv1beta1.UtilizationMetricType,
-						AverageUtilization: &utilization.IntVal,
-						ScaleType:          hcv1beta1.ScaleUpMetricsTargetType,
-					},
+	var cpuUpMetric hcv1beta1.MetricSpec
+	if betterAutoScaler.CpuUp.IntVal > 0 && betterAutoScaler.CpuUp.IntVal < 100 {
+		utilization := betterAutoScaler.CpuUp
+		cpuUpMetric = hcv1beta1.MetricSpec{
+			Type: hcv1beta1.ResourceMetricSourceType,
+			Resource: &hcv1beta1.ResourceMetricSource{
+				Name: "cpu",
+				Target: hcv1beta1.MetricTarget{
+					Type:               hcv1beta1.UtilizationMetricType,
+					AverageUtilization: &utilization.IntVal,
+					ScaleType:          hcv1beta1.ScaleUpMetricsTargetType,
 				},
-			}
+			},
 		}
+	}
 
-		var cpuDownMetric hcv1beta1.MetricSpec
-		if betterAutoScaler.CpuDown.IntVal > 0 && betterAutoScaler.CpuDown.IntVal < 100 {
-			utilization := betterAutoScaler.CpuDown
-			cpuDownMetric = hcv1beta1.MetricSpec{
-				Type: hcv1beta1.ResourceMetricSourceType,
-				Resource: &hcv1beta1.ResourceMetricSource{
-					Name: "cpu",
-					Target: hcv1beta1.MetricTarget{
-						Type:               hcv1beta1.UtilizationMetricType,
-						AverageUtilization: &utilization.IntVal,
-						ScaleType:          hcv1beta1.ScaleDownMetricsTargetType,
-					},
+	var cpuDownMetric hcv1beta1.MetricSpec
+	if betterAutoScaler.CpuDown.IntVal > 0 && betterAutoScaler.CpuDown.IntVal < 100 {
+		utilization := betterAutoScaler.CpuDown
+		cpuDownMetric = hcv1beta1.MetricSpec{
+			Type: hcv1beta1.ResourceMetricSourceType,
+			Resource: &hcv1beta1.ResourceMetricSource{
+				Name: "cpu",
+				Target: hcv1beta1.MetricTarget{
+					Type:               hcv1beta1.UtilizationMetricType,
+					AverageUtilization: &utilization.IntVal,
+					ScaleType:          hcv1beta1.ScaleDownMetricsTargetType,
 				},
-			}
+			},
 		}
+	}
 
-		var memoryUpMetric hcv1beta1.MetricSpec
-		if betterAutoScaler.MemoryUp.IntVal > 0 && betterAutoScaler.MemoryUp.IntVal < 100 {
-			utilization := betterAutoScaler.MemoryUp
-			memoryUpMetric = hcv1beta1.MetricSpec{
-				Type: hcv1beta1.ResourceMetricSourceType,
-				Resource: &hcv1beta1.ResourceMetricSource{
-					Name: "memory",
-					Target: hcv1beta1.MetricTarget{
-						Type:               hcv1beta1.UtilizationMetricType,
-						AverageUtilization: &utilization.IntVal,
-						ScaleType:          hcv1beta1.ScaleUpMetricsTargetType,
-					},
+	var memoryUpMetric hcv1beta1.MetricSpec
+	if betterAutoScaler.MemoryUp.IntVal > 0 && betterAutoScaler.MemoryUp.IntVal < 100 {
+		utilization := betterAutoScaler.MemoryUp
+		memoryUpMetric = hcv1beta1.MetricSpec{
+			Type: hcv1beta1.ResourceMetricSourceType,
+			Resource: &hcv1beta1.ResourceMetricSource{
+				Name: "memory",
+				Target: hcv1beta1.MetricTarget{
+					Type:               hcv1beta1.UtilizationMetricType,
+					AverageUtilization: &utilization.IntVal,
+					ScaleType:          hcv1beta1.ScaleUpMetricsTargetType,
 				},
-			}
+			},
 		}
+	}
 
-		var memoryDownMetric hcv1beta1.MetricSpec
-		if betterAutoScaler.MemoryDown.IntVal > 0 && betterAutoScaler.MemoryDown.IntVal < 100 {
-			utilization := betterAutoScaler.MemoryDown
-			memoryDownMetric = hcv1beta1.MetricSpec{
-				Type: hcv1beta1.ResourceMetricSourceType,
-				Resource: &hcv1beta1.ResourceMetricSource{
-					Name: "memory",
-					Target: hcv1beta1.MetricTarget{
-						Type:               hcv1beta1.UtilizationMetricType,
-						AverageUtilization: &utilization.IntVal,
-						ScaleType:          hcv1beta1.ScaleDownMetricsTargetType,
-					},
+	var memoryDownMetric hcv1beta1.MetricSpec
+	if betterAutoScaler.MemoryDown.IntVal > 0 && betterAutoScaler.MemoryDown.IntVal < 100 {
+		utilization := betterAutoScaler.MemoryDown
+		memoryDownMetric = hcv1beta1.MetricSpec{
+			Type: hcv1beta1.ResourceMetricSourceType,
+			Resource: &hcv1beta1.ResourceMetricSource{
+				Name: "memory",
+				Target: hcv1beta1.MetricTarget{
+					Type:               hcv1beta1.UtilizationMetricType,
+					AverageUtilization: &utilization.IntVal,
+					ScaleType:          hcv1beta1.ScaleDownMetricsTargetType,
 				},
-			}
+			},
 		}
+	}
 
-		hcHpa = &hcv1beta1.HorizontalPodAutoscaler{
-			ObjectMeta: v1.ObjectMeta{
-				Name: instanceName,
-				OwnerReferences: []v1.OwnerReference{
-					owner,
-				},
-				Annotations: annotations,
+	hcHpa := &hcv1beta1.HorizontalPodAutoscaler{
+		ObjectMeta: v1.ObjectMeta{
+			Name: instanceName,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
 			},
-			Spec: hcv1beta1.HorizontalPodAutoscalerSpec{
-				ScaleTargetRef: hcv1beta1.CrossVersionObjectReference{
-					Kind:       kind,
-					Name:       instanceName,
-					APIVersion: apiVersion,
-				},
-				MinReplicas: &betterAutoScaler.Minimum.IntVal,
-				MaxReplicas: betterAutoScaler.Maximum.IntVal,
-				Metrics: []hcv1beta1.MetricSpec{
-					cpuUpMetric,
-					cpuDownMetric,
-					memoryUpMetric,
-					memoryDownMetric,
-				},
+			Annotations: annotations,
+		},
+		Spec: hcv1beta1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: hcv1beta1.CrossVersionObjectReference{
+				Kind:       kind,
+				Name:       instanceName,
+				APIVersion: apiVersion,
 			},
-		}
+			MinReplicas: &betterAutoScaler.Minimum.IntVal,
+			MaxReplicas: betterAutoScaler.Maximum.IntVal,
+			Metrics: []hcv1beta1.MetricSpec{
+				cpuUpMetric,
+				cpuDownMetric,
+				memoryUpMetric,
+				memoryDownMetric,
+			},
+		},
 	}
-	return hcHpa
+	return hcHpa, nil
 }
 
-func convertIngress(owner v1.OwnerReference, annotations map[string]string, instanceName string, traits []v1alpha1.TraitBinding) *v1beta1.Ingress {
+func convertIngress(owner v1.OwnerReference, annotations map[string]string, instanceName string, tr v1alpha1.TraitBinding) (*v1beta1.Ingress, error) {
 	annotations["role"] = "trait"
-	var ingressRules []v1beta1.IngressRule
-	var ingress *v1beta1.Ingress
-	for _, tr := range traits {
-		if tr.Name != "nginx-ingress" && tr.Name != "ingress" {
-			continue
-		}
-
-		ing := new(traits2.Ingress)
-		if err := json.Unmarshal(tr.Properties.Raw, &ing); err != nil {
-			traitsConverterLog.Info(err.Error())
-		}
+	ing := new(traits2.Ingress)
+	if err := json.Unmarshal(tr.Properties.Raw, &ing); err != nil {
+		traitsConverterLog.Info(err.Error())
+		return nil, err
+	}
 
-		if ing.Path == "" {
-			ing.Path = "/"
-		}
+	if ing.Path == "" {
+		ing.Path = "/"
+	}
 
-		if ing.IngressClass == "" {
-			ing.IngressClass = "nginx-ingress-controller"
-		}
-		annotations["kubernetes.io/ingress.class"] = ing.IngressClass
+	if ing.IngressClass == "" {
+		ing.IngressClass = "nginx-ingress-controller"
+	}
+	annotations["kubernetes.io/ingress.class"] = ing.IngressClass
 
-		strVarToIntVar(&ing.ServicePort)
+	strVarToIntVar(&ing.ServicePort)
 
-		httpIngressPath := v1beta1.HTTPIngressPath{
-			Path: ing.Path,
-			Backend: v1beta1.IngressBackend{
-				ServiceName: instanceName,
-				ServicePort: ing.ServicePort,
-			},
-		}
-		httpIngressRuleValue := new(v1beta1.HTTPIngressRuleValue)
-		httpIngressRuleValue.Paths = append(httpIngressRuleValue.Paths, httpIngressPath)
-		ingressRule := v1beta1.IngressRule{
-			Host:             ing.Hostname,
-			IngressRuleValue: v1beta1.IngressRuleValue{HTTP: httpIngressRuleValue},
-		}
-		ingressRules = append(ingressRules, ingressRule)
-	}
-	if cap(ingressRules) != 0 {
-		ingress = &v1beta1.Ingress{
-			ObjectMeta: v1.ObjectMeta{
-				Name: instanceName,
-				OwnerReferences: []v1.OwnerReference{
-					owner,
-				},
-				Annotations: annotations,
-			},
-			Spec: v1beta1.IngressSpec{
-				Rules: ingressRules,
+	httpIngressPath := v1beta1.HTTPIngressPath{
+		Path: ing.Path,
+		Backend: v1beta1.IngressBackend{
+			ServiceName: instanceName,
+			ServicePort: ing.ServicePort,
+		},
+	}
+	httpIngressRuleValue := new(v1beta1.HTTPIngressRuleValue)
+	httpIngressRuleValue.Paths = append(httpIngressRuleValue.Paths, httpIngressPath)
+	ingressRule := v1beta1.IngressRule{
+		Host:             ing.Hostname,
+		IngressRuleValue: v1beta1.IngressRuleValue{HTTP: httpIngressRuleValue},
+	}
+	ingress := &v1beta1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: instanceName,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
 			},
-		}
+			Annotations: annotations,
+		},
+		Spec: v1beta1.IngressSpec{
+			Rules: []v1beta1.IngressRule{ingressRule},
+		},
 	}
-	return ingress
+	return ingress, nil
 }
 
-func getManuelScale(traits []v1alpha1.TraitBinding) *int32 {
-	var def int32 = 1
-	for _, tr := range traits {
-		if tr.Name != "manual-scaler" {
-			continue
-		}
-		values, err := parsePropertiesOfTrait(tr)
-		if err != nil {
-			continue
-		}
-		f, ok := values["replicaCount"]
+// mergeIngresses merges the rules of ingresses with the same name, so that several ingress traits
+// of one component instance share one Ingress.
+func mergeIngresses(objects []runtime.Object) []runtime.Object {
+	var merged []runtime.Object
+	ingresses := map[string]*v1beta1.Ingress{}
+	for _, obj := range objects {
+		ing, ok := obj.(*v1beta1.Ingress)
 		if !ok {
-			handlerLog.Info("replicaCount didn't exist error")
+			merged = append(merged, obj)
 			continue
 		}
-		ff, ok := f.(float64)
-		if !ok {
-			handlerLog.Info("replicaCount type is " + reflect.TypeOf(f).Name())
+		if exist, ok := ingresses[ing.Name]; ok {
+			exist.Spec.Rules = append(exist.Spec.Rules, ing.Spec.Rules...)
 			continue
 		}
-		def = int32(ff)
+		ingresses[ing.Name] = ing
+		merged = append(merged, ing)
 	}
-	return &def
+	return merged
 }
 
-func getVolumesFromVolumeMounters(traits []v1alpha1.TraitBinding) []apiv1.Volume {
-	var volumes []apiv1.Volume
-	for _, tr := range traits {
-		if tr.Name != "volume-mounter" {
-			continue
-		}
-		values, err := parsePropertiesOfTrait(tr)
-		if err != nil {
-			continue
-		}
-		volume := apiv1.Volume{
-			Name: values["volumeName"].(string),
-			VolumeSource: apiv1.VolumeSource{
-				PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
-					ClaimName: values["volumeName"].(string),
-				},
-			},
-		}
-		volumes = append(volumes, volume)
+func getManuelScale(tr v1alpha1.TraitBinding) (int32, error) {
+	manualScaler := new(traits2.ManualScaler)
+	if err := parsePropertiesOfTrait(tr, manualScaler); err != nil {
+		return 0, err
 	}
+	return manualScaler.ReplicaCount, nil
+}
 
-	return volumes
+func getVolumeFromVolumeMounter(tr v1alpha1.TraitBinding) (*apiv1.Volume, error) {
+	volumeMounter := new(traits2.VolumeMounter)
+	if err := parsePropertiesOfTrait(tr, volumeMounter); err != nil {
+		return nil, err
+	}
+	volume := &apiv1.Volume{
+		Name: volumeMounter.VolumeName,
+		VolumeSource: apiv1.VolumeSource{
+			PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
+				ClaimName: volumeMounter.VolumeName,
+			},
+		},
+	}
+	return volume, nil
 }
 
-func convertPvcsFromVolumeMounters(owner v1.OwnerReference, annotations map[string]string, comp v1alpha1.ComponentSchematic, traits []v1alpha1.TraitBinding) []apiv1.PersistentVolumeClaim {
+func convertPvcFromVolumeMounter(owner v1.OwnerReference, annotations map[string]string, comp v1alpha1.ComponentSchematic, tr v1alpha1.TraitBinding) (*apiv1.PersistentVolumeClaim, error) {
 	annotations["role"] = "trait"
-	var pvcs []apiv1.PersistentVolumeClaim
-	for _, tr := range traits {
-		if tr.Name != "volume-mounter" {
-			continue
-		}
-		values, err := parsePropertiesOfTrait(tr)
-		if err != nil {
-			continue
-		}
-		volumeName := values["volumeName"].(string)
-		storageClass := values["storageClass"].(string)
-
-		var oamVolume v1alpha1.Volume
-		if comp.Spec.WorkloadType == WorkloadTypeMysqlCluster {
-			continue
-		}
-		for _, c := range comp.Spec.Containers {
-			for _, v := range c.Resources.Volumes {
-				if v.Name == volumeName {
-					oamVolume = v
-				}
+	volumeMounter := new(traits2.VolumeMounter)
+	if err := parsePropertiesOfTrait(tr, volumeMounter); err != nil {
+		return nil, err
+	}
+	volumeName := volumeMounter.VolumeName
+	storageClass := volumeMounter.StorageClass
+
+	var oamVolume v1alpha1.Volume
+	for _, c := range comp.Spec.Containers {
+		for _, v := range c.Resources.Volumes {
+			if v.Name == volumeName {
+				oamVolume = v
 			}
 		}
-		if &oamVolume == nil || oamVolume.Disk == nil {
-			msg := fmt.Sprintf("Volume can not found in componentSchematic.", "ComponentSchematic", comp.Name, "volume", volumeName)
-			handlerLog.Info(msg)
-			continue
-		}
+	}
+	if &oamVolume == nil || oamVolume.Disk == nil {
+		handlerLog.Info("Volume can not found in componentSchematic.", "ComponentSchematic", comp.Name, "volume", volumeName)
+		return nil, fmt.Errorf("volume %s can not found in componentSchematic %s", volumeName, comp.Name)
+	}
 
-		var required resource.Quantity
-		required, _ = resource.ParseQuantity(oamVolume.Disk.Required)
+	var required resource.Quantity
+	required, _ = resource.ParseQuantity(oamVolume.Disk.Required)
 
-		pvc := apiv1.PersistentVolumeClaim{
-			TypeMeta: v1.TypeMeta{},
-			ObjectMeta: v1.ObjectMeta{
-				Name: values["volumeName"].(string),
-				OwnerReferences: []v1.OwnerReference{
-					owner,
-				},
-				Annotations: annotations,
+	pvc := &apiv1.PersistentVolumeClaim{
+		TypeMeta: v1.TypeMeta{},
+		ObjectMeta: v1.ObjectMeta{
+			Name: volumeName,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
 			},
-			Spec: apiv1.PersistentVolumeClaimSpec{
-				AccessModes: []apiv1.PersistentVolumeAccessMode{
-					apiv1.PersistentVolumeAccessMode(getPvcAccessMode(&oamVolume.AccessMode)),
-				},
-				Resources: apiv1.ResourceRequirements{
-					Requests: apiv1.ResourceList{
-						"storage": required,
-					},
+			Annotations: annotations,
+		},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			AccessModes: []apiv1.PersistentVolumeAccessMode{
+				apiv1.PersistentVolumeAccessMode(getPvcAccessMode(&oamVolume.AccessMode)),
+			},
+			Resources: apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{
+					"storage": required,
 				},
-				StorageClassName: &storageClass,
-				VolumeMode:       nil,
 			},
-		}
-		if !oamVolume.Disk.Ephemeral {
-			pvc.OwnerReferences = append(pvc.OwnerReferences, owner)
-		}
-		pvcs = append(pvcs, pvc)
+			StorageClassName: &storageClass,
+			VolumeMode:       nil,
+		},
+	}
+	if !oamVolume.Disk.Ephemeral {
+		pvc.OwnerReferences = append(pvc.OwnerReferences, owner)
 	}
-	return pvcs
+	return pvc, nil
 }
 
 func getPvcAccessMode(mode *v1alpha1.AccessMode) string {
@@ -416,12 +361,195 @@ func getPvcAccessMode(mode *v1alpha1.AccessMode) string {
 	return "ReadWriteOnce"
 }
 
-func parsePropertiesOfTrait(trait v1alpha1.TraitBinding) (map[string]interface{}, error) {
-	values := make(map[string]interface{})
-	err := json.Unmarshal(trait.Properties.Raw, &values)
+func parsePropertiesOfTrait(trait v1alpha1.TraitBinding, properties interface{}) error {
+	if err := json.Unmarshal(trait.Properties.Raw, properties); err != nil {
+		handlerLog.Info("traits value spec error", "Trait", trait.Name, "Error", err)
+		return err
+	}
+	return nil
+}
+
+const (
+	defaultBackupRetention = 7
+	defaultBackupSize      = "10Gi"
+	defaultBackupImage     = "mysql:5.7"
+
+	// dumps the database to a new backup and removes the backups beyond the retention
+	backupScript = `set -eo pipefail
+file=$BACKUP_DIR/$INSTANCE-$(date +%Y%m%d%H%M%S).sql.gz
+databases=--all-databases
+if [ -n "$MYSQL_DATABASE" ]; then databases="--databases $MYSQL_DATABASE"; fi
+mysqldump -h"$MYSQL_HOST" -P"$MYSQL_PORT" -u"$MYSQL_USER" -p"$MYSQL_PASSWORD" --single-transaction $databases | gzip > $file.tmp
+mv $file.tmp $file
+ls -1t $BACKUP_DIR/$INSTANCE-[0-9]*.sql.gz | tail -n +$((RETENTION+1)) | xargs -r rm -f
+echo "backup $(basename $file) completed"`
+
+	restoreScript = `set -eo pipefail
+gunzip -c $BACKUP_DIR/$BACKUP | mysql -h"$MYSQL_HOST" -P"$MYSQL_PORT" -u"$MYSQL_USER" -p"$MYSQL_PASSWORD"
+echo "backup $BACKUP restored"`
+)
+
+func convertBackupPvc(owner v1.OwnerReference, annotations map[string]string, instanceName string, mysqlBackup *traits2.MysqlBackup) (*apiv1.PersistentVolumeClaim, error) {
+	annotations["role"] = "trait"
+	size := mysqlBackup.Size
+	if size == "" {
+		size = defaultBackupSize
+	}
+	required, err := resource.ParseQuantity(size)
 	if err != nil {
-		handlerLog.Info("traits value spec error", "Error", err)
-		return nil, err
+		return nil, fmt.Errorf("invalid size %s of the backup volume: %v", size, err)
+	}
+	pvc := &apiv1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name: instanceName + "-backup",
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+		},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			AccessModes: []apiv1.PersistentVolumeAccessMode{
+				apiv1.ReadWriteOnce,
+			},
+			Resources: apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{
+					"storage": required,
+				},
+			},
+		},
+	}
+	if mysqlBackup.StorageClass != "" {
+		pvc.Spec.StorageClassName = &mysqlBackup.StorageClass
+	}
+	return pvc, nil
+}
+
+func convertBackupCronJob(owner v1.OwnerReference, annotations map[string]string, instanceName string, mysqlBackup *traits2.MysqlBackup) (*batchv1beta1.CronJob, error) {
+	if mysqlBackup.Schedule == "" {
+		return nil, fmt.Errorf("schedule of the backup is required")
+	}
+	annotations["role"] = "trait"
+	retention := mysqlBackup.Retention
+	if retention <= 0 {
+		retention = defaultBackupRetention
+	}
+	podSpec := backupPodSpec(instanceName, "backup", backupScript, mysqlBackup)
+	podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, apiv1.EnvVar{Name: "RETENTION", Value: fmt.Sprint(retention)})
+	var backoffLimit int32 = 0
+	var historyLimit int32 = 3
+	return &batchv1beta1.CronJob{
+		ObjectMeta: v1.ObjectMeta{
+			Name: instanceName + "-backup",
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   mysqlBackup.Schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						CronJobLabel: instanceName + "-backup",
+					},
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: apiv1.PodTemplateSpec{
+						Spec: podSpec,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// convertRestoreJob restores a backup once, the Job is named by the backup so that it is not run again
+// until another backup is requested.
+func convertRestoreJob(owner v1.OwnerReference, annotations map[string]string, instanceName string, backup string, mysqlBackup *traits2.MysqlBackup) *batchv1.Job {
+	annotations["role"] = "trait"
+	h := fnv.New32a()
+	h.Write([]byte(backup))
+	podSpec := backupPodSpec(instanceName, "restore", restoreScript, mysqlBackup)
+	podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, apiv1.EnvVar{Name: "BACKUP", Value: backup})
+	var backoffLimit int32 = 0
+	return &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			Name: fmt.Sprintf("%s-restore-%08x", instanceName, h.Sum32()),
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: apiv1.PodTemplateSpec{
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+// backupPodSpec runs the script with the backup volume and the connection of the MysqlCluster.
+func backupPodSpec(instanceName, name, script string, mysqlBackup *traits2.MysqlBackup) apiv1.PodSpec {
+	image := mysqlBackup.Image
+	if image == "" {
+		image = defaultBackupImage
+	}
+	claimName := mysqlBackup.PvcName
+	if claimName == "" {
+		claimName = instanceName + "-backup"
+	}
+	env := []apiv1.EnvVar{
+		{Name: "INSTANCE", Value: instanceName},
+		{Name: "BACKUP_DIR", Value: BackupMountPath},
+	}
+	for _, key := range []string{ConnectionHost, ConnectionPort, ConnectionUser, ConnectionPassword, ConnectionDatabase} {
+		env = append(env, apiv1.EnvVar{
+			Name: "MYSQL_" + strings.ToUpper(key),
+			ValueFrom: &apiv1.EnvVarSource{
+				SecretKeyRef: &apiv1.SecretKeySelector{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: instanceName + ConnectionSecretSuffix},
+					Key:                  key,
+				},
+			},
+		})
+	}
+	return apiv1.PodSpec{
+		RestartPolicy: apiv1.RestartPolicyNever,
+		Containers: []apiv1.Container{
+			{
+				Name:    name,
+				Image:   image,
+				Command: []string{"/bin/bash", "-c", script},
+				Env:     env,
+				VolumeMounts: []apiv1.VolumeMount{
+					{Name: "backup", MountPath: BackupMountPath},
+				},
+			},
+		},
+		Volumes: []apiv1.Volume{
+			{
+				Name: "backup",
+				VolumeSource: apiv1.VolumeSource{
+					PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+				},
+			},
+		},
+	}
+}
+
+// getRestoreBackup returns the backup the restore annotation requests for the instance.
+func getRestoreBackup(annotations map[string]string, instanceName string) (string, error) {
+	backup := getInstanceAnnotation(annotations, RestoreAnnotation, instanceName)
+	if backup == "" {
+		return "", nil
+	}
+	if strings.Contains(backup, "/") || !strings.HasPrefix(backup, instanceName+"-") {
+		return "", fmt.Errorf("invalid backup %q of instance %s in annotation %s", backup, instanceName, RestoreAnnotation)
 	}
-	return values, nil
+	return backup, nil
 }
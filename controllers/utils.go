@@ -1,10 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"hash/fnv"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -13,17 +20,192 @@ var (
 	utilsLog = ctrl.Log.WithName("utils")
 )
 
-func parseParameters(parameterValues []v1alpha1.ParameterValue, variables []v1alpha1.Variable) map[string]string {
+// parseParameters resolves the parameter values of a component, applies the defaults of the parameters
+// declared by the ComponentSchematic, and checks required parameters and parameter types.
+func parseParameters(parameters []v1alpha1.Parameter, parameterValues []v1alpha1.ParameterValue, variables []v1alpha1.Variable) (map[string]string, error) {
 	parameterMap := map[string]string{}
 	variablesMap := parseVariables(variables)
+	var errs []string
 	for _, p := range parameterValues {
 		if strings.HasPrefix(p.Value, "[fromVariable(") && strings.HasSuffix(p.Value, ")]") {
 			key := MiddleString(p.Value, "[fromVariable(", ")]")
-			p.Value = variablesMap[key]
+			value, ok := variablesMap[key]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("variable %s of parameter %s not found", key, p.Name))
+			}
+			p.Value = value
+		}
+		if isConnectionReference(p.Value) {
+			if _, _, err := parseConnectionReference(p.Value); err != nil {
+				errs = append(errs, fmt.Sprintf("parameter %s: %v", p.Name, err))
+			}
+		}
+		if isSecretReference(p.Value) {
+			if _, _, err := parseSecretReference(p.Value); err != nil {
+				errs = append(errs, fmt.Sprintf("parameter %s: %v", p.Name, err))
+			}
 		}
 		parameterMap[p.Name] = p.Value
 	}
-	return parameterMap
+	for _, p := range parameters {
+		value, ok := parameterMap[p.Name]
+		if !ok {
+			if p.Default == "" {
+				if p.Required {
+					errs = append(errs, fmt.Sprintf("parameter %s is required", p.Name))
+				}
+				continue
+			}
+			value = p.Default
+			parameterMap[p.Name] = value
+		}
+		if !matchesParameterType(p.ParameterType, value) {
+			errs = append(errs, fmt.Sprintf("parameter %s must be of type %s", p.Name, p.ParameterType))
+		}
+	}
+	if len(errs) > 0 {
+		return parameterMap, errors.New(strings.Join(errs, ", "))
+	}
+	return parameterMap, nil
+}
+
+func matchesParameterType(parameterType v1alpha1.ParameterType, value string) bool {
+	switch parameterType {
+	case v1alpha1.Number:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case v1alpha1.Boolean:
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	case v1alpha1.Null:
+		return value == "" || value == "null"
+	}
+	return true
+}
+
+func isConnectionReference(value string) bool {
+	return strings.HasPrefix(value, "[fromConnection(") && strings.HasSuffix(value, ")]")
+}
+
+// parseConnectionReference parses [fromConnection(instance,key)], a key of the connection Secret
+// published by the MysqlCluster instance of the same ApplicationConfiguration.
+func parseConnectionReference(value string) (string, string, error) {
+	args := strings.Split(MiddleString(value, "[fromConnection(", ")]"), ",")
+	if len(args) != 2 || strings.TrimSpace(args[0]) == "" {
+		return "", "", fmt.Errorf("invalid connection reference %s, must be [fromConnection(instance,key)]", value)
+	}
+	instance, key := strings.TrimSpace(args[0]), strings.TrimSpace(args[1])
+	switch key {
+	case ConnectionHost, ConnectionPort, ConnectionUser, ConnectionPassword, ConnectionDatabase:
+		return instance, key, nil
+	}
+	return "", "", fmt.Errorf("invalid connection key %s of %s, must be one of %s, %s, %s, %s, %s", key, value,
+		ConnectionHost, ConnectionPort, ConnectionUser, ConnectionPassword, ConnectionDatabase)
+}
+
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, "[fromSecret(") && strings.HasSuffix(value, ")]")
+}
+
+// parseSecretReference parses [fromSecret(name,key)], a key of a Secret in the namespace of the ApplicationConfiguration.
+func parseSecretReference(value string) (string, string, error) {
+	args := strings.Split(MiddleString(value, "[fromSecret(", ")]"), ",")
+	if len(args) != 2 || strings.TrimSpace(args[0]) == "" || strings.TrimSpace(args[1]) == "" {
+		return "", "", fmt.Errorf("invalid secret reference %s, must be [fromSecret(name,key)]", value)
+	}
+	return strings.TrimSpace(args[0]), strings.TrimSpace(args[1]), nil
+}
+
+// secretKeySelector returns the key of a Secret a [fromSecret(name,key)] or [fromConnection(instance,key)] value
+// refers to, or nil if the value is no valid reference.
+func secretKeySelector(value string) *apiv1.SecretKeySelector {
+	var name, key string
+	if isSecretReference(value) {
+		n, k, err := parseSecretReference(value)
+		if err != nil {
+			return nil
+		}
+		name, key = n, k
+	} else if isConnectionReference(value) {
+		instance, k, err := parseConnectionReference(value)
+		if err != nil {
+			return nil
+		}
+		name, key = instance+ConnectionSecretSuffix, k
+	} else {
+		return nil
+	}
+	return &apiv1.SecretKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: name}, Key: key}
+}
+
+// getSensitiveParameters returns the parameters the ComponentSchematic marks as sensitive.
+func getSensitiveParameters(comp v1alpha1.ComponentSchematic) map[string]bool {
+	sensitive := map[string]bool{}
+	for _, name := range strings.Split(comp.Annotations[SensitiveParametersAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sensitive[name] = true
+		}
+	}
+	return sensitive
+}
+
+// maskingRecorder replaces the values of sensitive parameters in the messages of events by MaskedValue.
+type maskingRecorder struct {
+	record.EventRecorder
+	values []string
+}
+
+// add masks the values of the sensitive parameters of the component, values shorter than 4 characters would mask
+// too much of the messages and are left as they are.
+func (r *maskingRecorder) add(comp v1alpha1.ComponentSchematic, parameterMap map[string]string) {
+	for name := range getSensitiveParameters(comp) {
+		if value := parameterMap[name]; len(value) >= 4 && secretKeySelector(value) == nil {
+			r.values = append(r.values, value)
+		}
+	}
+	// longer values first, so that a value containing another one is masked as a whole
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+}
+
+func (r *maskingRecorder) mask(message string) string {
+	for _, value := range r.values {
+		message = strings.Replace(message, value, MaskedValue, -1)
+	}
+	return message
+}
+
+func (r *maskingRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(object, eventtype, reason, r.mask(message))
+}
+
+func (r *maskingRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.Event(object, eventtype, reason, r.mask(fmt.Sprintf(messageFmt, args...)))
+}
+
+func (r *maskingRecorder) PastEventf(object runtime.Object, timestamp v1.Time, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.PastEventf(object, timestamp, eventtype, reason, "%s", r.mask(fmt.Sprintf(messageFmt, args...)))
+}
+
+func (r *maskingRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", r.mask(fmt.Sprintf(messageFmt, args...)))
+}
+
+// getInstanceAnnotation returns the value of the instance in an annotation of <instance>=<value>[,<instance>=<value>].
+func getInstanceAnnotation(annotations map[string]string, key string, instanceName string) string {
+	for _, pair := range strings.Split(annotations[key], ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) == 2 && parts[0] == instanceName {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// contentHash returns a short hash of the content, set as annotation to roll out pods when the content changes.
+func contentHash(content string) string {
+	h := fnv.New32a()
+	h.Write([]byte(content))
+	return fmt.Sprintf("%08x", h.Sum32())
 }
 
 func parseVariables(variables []v1alpha1.Variable) map[string]string {
@@ -75,6 +257,17 @@ func addResourceStatus(statusList *[]v1alpha1.ResourceStatus, name string, apiVe
 	}
 }
 
+func removeResourceStatus(statusList *[]v1alpha1.ResourceStatus, name string, kind string, component string) {
+	var resources []v1alpha1.ResourceStatus
+	for _, s := range *statusList {
+		if s.Kind == kind && s.NamespacedName == name && s.Component == component {
+			continue
+		}
+		resources = append(resources, s)
+	}
+	*statusList = resources
+}
+
 func addModuleStatus(statusList *[]v1alpha1.ModuleStatus, name string, kind string, groupVersion, status string) {
 	moduleStatus := v1alpha1.ModuleStatus{
 		NamespacedName: name,
@@ -111,3 +304,15 @@ func strVarToIntVar(ios *intstr.IntOrString) {
 		}
 	}
 }
+
+// removeTraitStatus removes the status of the traits of the component instance.
+func removeTraitStatus(statusList *[]v1alpha1.ResourceStatus, component string) {
+	var resources []v1alpha1.ResourceStatus
+	for _, s := range *statusList {
+		if s.Kind == TraitKind && s.Component == component {
+			continue
+		}
+		resources = append(resources, s)
+	}
+	*statusList = resources
+}
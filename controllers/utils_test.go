@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSecretKeySelector(t *testing.T) {
+	selector := func(name, key string) *apiv1.SecretKeySelector {
+		return &apiv1.SecretKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: name}, Key: key}
+	}
+	tests := []struct {
+		value string
+		want  *apiv1.SecretKeySelector
+	}{
+		{"[fromSecret(api-token, token)]", selector("api-token", "token")},
+		{"[fromConnection(db,password)]", selector("db"+ConnectionSecretSuffix, ConnectionPassword)},
+		{"[fromSecret(api-token)]", nil},
+		{"[fromConnection(db,secret)]", nil},
+		{"changeme", nil},
+	}
+	for _, tt := range tests {
+		if got := secretKeySelector(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("secretKeySelector(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMaskingRecorder(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	masker := &maskingRecorder{EventRecorder: recorder}
+	comp := v1alpha1.ComponentSchematic{ObjectMeta: v1.ObjectMeta{
+		Annotations: map[string]string{SensitiveParametersAnnotation: "password,passwordHash,pin,token"},
+	}}
+	masker.add(comp, map[string]string{
+		"password":     "changeme",
+		"passwordHash": "changeme-hashed",
+		"pin":          "123",
+		"token":        "[fromSecret(api-token,token)]",
+		"user":         "admin",
+	})
+
+	ac := newTestApplicationConfiguration()
+	masker.Event(ac, apiv1.EventTypeWarning, Failed, "login of admin with changeme-hashed and changeme failed, pin 123")
+	masker.Eventf(ac, apiv1.EventTypeWarning, Failed, "invalid password %s", "changeme")
+	// values shorter than 4 characters and references to Secrets are not masked
+	want := []string{
+		"Warning Failed login of admin with *** and *** failed, pin 123",
+		"Warning Failed invalid password ***",
+	}
+	for _, w := range want {
+		if got := <-recorder.Events; got != w {
+			t.Errorf("event = %q, want %q", got, w)
+		}
+	}
+	if got := masker.mask("token [fromSecret(api-token,token)]"); got != "token [fromSecret(api-token,token)]" {
+		t.Errorf("mask() = %q, want the reference kept", got)
+	}
+}
@@ -2,15 +2,87 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
-	hcv1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
+	traits2 "hc-oam-controller/api/core.oam.dev/v1alpha1/traits"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
-func convertMysqlCluster(owner v1.OwnerReference, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) (*hcv1alpha1.MysqlCluster, *corev1.ConfigMap, *corev1.PersistentVolumeClaim, error) {
+// workloadSetting is a workload setting of a ComponentSchematic, the value is taken from the parameter fromParam if it is set.
+type workloadSetting struct {
+	Name      string               `json:"name"`
+	Type      string               `json:"type,omitempty"`
+	Required  bool                 `json:"required,omitempty"`
+	Default   string               `json:"default,omitempty"`
+	Value     runtime.RawExtension `json:"value,omitempty"`
+	FromParam string               `json:"fromParam,omitempty"`
+}
+
+// getWorkloadSettings returns the scalar workload settings of the component by name.
+func getWorkloadSettings(comp v1alpha1.ComponentSchematic, parameterMap map[string]string) (map[string]string, error) {
+	settings := map[string]string{}
+	if len(comp.Spec.WorkloadSettings.Raw) == 0 {
+		return settings, nil
+	}
+	var values []workloadSetting
+	if err := json.Unmarshal(comp.Spec.WorkloadSettings.Raw, &values); err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		value := v.Default
+		if p, ok := parameterMap[v.FromParam]; ok && v.FromParam != "" {
+			value = p
+		} else if len(v.Value.Raw) > 0 {
+			// strings are unquoted, numbers and booleans are kept as is
+			var str string
+			if err := json.Unmarshal(v.Value.Raw, &str); err == nil {
+				value = str
+			} else {
+				value = string(v.Value.Raw)
+			}
+		}
+		if value == "" && v.Required {
+			return nil, fmt.Errorf("workloadSettings %s is required", v.Name)
+		}
+		settings[v.Name] = value
+	}
+	return settings, nil
+}
+
+func getInt32Setting(settings map[string]string, name string) (*int32, error) {
+	value, err := getInt64Setting(settings, name)
+	if value == nil || err != nil {
+		return nil, err
+	}
+	if *value < math.MinInt32 || *value > math.MaxInt32 {
+		return nil, fmt.Errorf("workloadSettings %s must be a 32-bit integer: %d", name, *value)
+	}
+	i := int32(*value)
+	return &i, nil
+}
+
+func getInt64Setting(settings map[string]string, name string) (*int64, error) {
+	value, ok := settings[name]
+	if !ok || value == "" {
+		return nil, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("workloadSettings %s must be an integer: %s", name, value)
+	}
+	return &i, nil
+}
+
+func convertMysqlCluster(owner v1.OwnerReference, annotations map[string]string, compConf v1alpha1.ComponentConfiguration, comp v1alpha1.ComponentSchematic, parameterMap map[string]string) (*hcv1alpha1.MysqlCluster, *corev1.ConfigMap, *corev1.PersistentVolumeClaim, error) {
 	type value struct {
 		Name        string               `json:"name"`
 		Description string               `json:"description,omitempty"`
@@ -29,7 +101,6 @@ func convertMysqlCluster(owner v1.OwnerReference, compConf v1alpha1.ComponentCon
 	}
 
 	var mysqlClusterSpec *hcv1alpha1.MysqlClusterSpec
-	var configContent string
 	for _, v := range *values {
 		if v.Name == "spec" {
 			mysqlClusterSpec = new(hcv1alpha1.MysqlClusterSpec)
@@ -38,22 +109,37 @@ func convertMysqlCluster(owner v1.OwnerReference, compConf v1alpha1.ComponentCon
 				return nil, nil, nil, err
 			}
 		}
-		if v.Name == "config" {
-			for _, p := range comp.Spec.Parameters {
-				if p.Name == v.FromParam {
-					configContent = p.Default
-					break
-				}
-			}
-		}
 	}
 
+	if mysqlClusterSpec == nil {
+		return nil, nil, nil, errors.New("workloadSettings spec of MysqlCluster is required")
+	}
+
+	// my.cnf is the config template with the keys of the workload settings config.<section>.<key> merged over it
+	settings, err := getWorkloadSettings(comp, parameterMap)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	configParameters, err := getMysqlConfigParameters(settings)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	configContent := renderMysqlConfig(settings["config"], configParameters)
+	podAnnotations := make(map[string]string, len(mysqlClusterSpec.Statefulset.Annotations)+1)
+	for k, v := range mysqlClusterSpec.Statefulset.Annotations {
+		podAnnotations[k] = v
+	}
+	podAnnotations[ConfigHashAnnotation] = contentHash(configContent)
+	mysqlClusterSpec.Statefulset.Annotations = podAnnotations
+
+	annotations["role"] = "workload"
 	mysqlCluster := &hcv1alpha1.MysqlCluster{
 		ObjectMeta: v1.ObjectMeta{
 			Name: compConf.InstanceName,
 			OwnerReferences: []v1.OwnerReference{
 				owner,
 			},
+			Annotations: annotations,
 			Labels: map[string]string{
 				"operatorname": "mysql-operator",
 			},
@@ -67,6 +153,7 @@ func convertMysqlCluster(owner v1.OwnerReference, compConf v1alpha1.ComponentCon
 			OwnerReferences: []v1.OwnerReference{
 				owner,
 			},
+			Annotations: annotations,
 		},
 		Data: map[string]string{
 			"my.cnf.tmpl": configContent,
@@ -75,14 +162,14 @@ func convertMysqlCluster(owner v1.OwnerReference, compConf v1alpha1.ComponentCon
 
 	var storageClass string
 	for _, tr := range compConf.Traits {
-		if tr.Name != "volume-mounter" {
+		if getTraitName(tr.Name) != TraitVolumeMounter {
 			continue
 		}
-		vs, err := parsePropertiesOfTrait(tr)
-		if err != nil {
+		volumeMounter := new(traits2.VolumeMounter)
+		if err := parsePropertiesOfTrait(tr, volumeMounter); err != nil {
 			continue
 		}
-		storageClass = vs["storageClass"].(string)
+		storageClass = volumeMounter.StorageClass
 	}
 
 	var required resource.Quantity
@@ -95,6 +182,7 @@ func convertMysqlCluster(owner v1.OwnerReference, compConf v1alpha1.ComponentCon
 			OwnerReferences: []v1.OwnerReference{
 				owner,
 			},
+			Annotations: annotations,
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
 			AccessModes: []corev1.PersistentVolumeAccessMode{
@@ -112,3 +200,105 @@ func convertMysqlCluster(owner v1.OwnerReference, compConf v1alpha1.ComponentCon
 
 	return mysqlCluster, configMap, pvc, nil
 }
+
+// convertMysqlConnectionSecret publishes how to connect to the MysqlCluster. The host is the master reported by
+// the MysqlCluster, or its service before a master is reported. The user and database are of the first business
+// database or root. The password is taken from the spec only without spec.secretName, the keys of the Secret it
+// names are copied by the handler, see completeConnectionSecrets.
+func convertMysqlConnectionSecret(owner v1.OwnerReference, annotations map[string]string, namespace string, mysqlCluster *hcv1alpha1.MysqlCluster) *corev1.Secret {
+	port := mysqlCluster.Spec.Statefulset.ServerPort
+	if port == 0 {
+		port = 20001
+	}
+	user, password, database := "root", "", ""
+	for _, env := range mysqlCluster.Spec.Statefulset.Env {
+		if env.Name == "MYSQL_ROOT_PASSWORD" {
+			password = env.Value
+		}
+	}
+	if len(mysqlCluster.Spec.BusinessDeploy) > 0 {
+		business := mysqlCluster.Spec.BusinessDeploy[0]
+		user, password, database = business.User, business.Pwd, business.Database
+	}
+	data := map[string][]byte{
+		ConnectionHost:     []byte(mysqlConnectionHost(mysqlCluster, namespace)),
+		ConnectionPort:     []byte(strconv.Itoa(int(port))),
+		ConnectionUser:     []byte(user),
+		ConnectionDatabase: []byte(database),
+	}
+	if mysqlCluster.Spec.SecretName == "" {
+		data[ConnectionPassword] = []byte(password)
+	}
+
+	annotations["role"] = "workload"
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name: mysqlCluster.Name + ConnectionSecretSuffix,
+			OwnerReferences: []v1.OwnerReference{
+				owner,
+			},
+			Annotations: annotations,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// mysqlConnectionHost returns the host of the master of the MysqlCluster, the pod reported as master under the
+// headless service of the cluster, which is named after it. The service resolves to all members and is returned
+// only until a master is reported.
+func mysqlConnectionHost(mysqlCluster *hcv1alpha1.MysqlCluster, namespace string) string {
+	if namespace == "" {
+		return mysqlCluster.Name
+	}
+	if master := mysqlClusterMaster(mysqlCluster); master != "<none>" {
+		return fmt.Sprintf("%s.%s.%s.svc", master, mysqlCluster.Name, namespace)
+	}
+	return fmt.Sprintf("%s.%s.svc", mysqlCluster.Name, namespace)
+}
+
+// applyMysqlOperations sets the fields of the operations the annotations of the ApplicationConfiguration
+// request for the MysqlCluster, and records them in the annotations of the MysqlCluster.
+func applyMysqlOperations(annotations map[string]string, mysqlCluster *hcv1alpha1.MysqlCluster) error {
+	instanceName := mysqlCluster.Name
+	// the annotations are shared with the ConfigMap and PersistentVolumeClaim of the instance
+	recorded := make(map[string]string, len(mysqlCluster.Annotations))
+	for k, v := range mysqlCluster.Annotations {
+		recorded[k] = v
+	}
+	mysqlCluster.Annotations = recorded
+
+	pod := getInstanceAnnotation(annotations, SwitchoverAnnotation, instanceName)
+	forcePod := getInstanceAnnotation(annotations, ForceSwitchoverAnnotation, instanceName)
+	if pod != "" && forcePod != "" {
+		return fmt.Errorf("instance %s is requested by both annotations %s and %s", instanceName, SwitchoverAnnotation, ForceSwitchoverAnnotation)
+	}
+	if pod != "" {
+		mysqlCluster.Spec.SwitchMaster = hcv1alpha1.ClusterSwitch{Master: pod}
+		mysqlCluster.Annotations[SwitchoverAnnotation] = pod
+	}
+	if forcePod != "" {
+		mysqlCluster.Spec.SwitchMaster = hcv1alpha1.ClusterSwitch{Master: forcePod, ForceSwitched: true}
+		mysqlCluster.Annotations[ForceSwitchoverAnnotation] = forcePod
+	}
+
+	if value := getInstanceAnnotation(annotations, PauseAnnotation, instanceName); value != "" {
+		paused, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q of instance %s in annotation %s, must be true or false", value, instanceName, PauseAnnotation)
+		}
+		mysqlCluster.Spec.Paused = paused
+		mysqlCluster.Annotations[PauseAnnotation] = strconv.FormatBool(paused)
+	}
+
+	if value := getInstanceAnnotation(annotations, MigrateAnnotation, instanceName); value != "" {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid value %q of instance %s in annotation %s, must be <pod>:<node>", value, instanceName, MigrateAnnotation)
+		}
+		mysqlCluster.Spec.ProblemPodName = parts[0]
+		mysqlCluster.Spec.TargetNodeName = parts[1]
+		mysqlCluster.Annotations[MigrateAnnotation] = value
+	}
+	return nil
+}
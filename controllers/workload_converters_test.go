@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"testing"
+)
+
+func TestGetIntSettings(t *testing.T) {
+	settings := map[string]string{
+		"small": "3",
+		"large": "4294967296",
+		"text":  "three",
+		"empty": "",
+	}
+	tests := []struct {
+		name      string
+		want64    *int64
+		wantErr64 bool
+		want32    *int32
+		wantErr32 bool
+	}{
+		{name: "small", want64: int64Ptr(3), want32: int32Ptr(3)},
+		{name: "large", want64: int64Ptr(4294967296), wantErr32: true},
+		{name: "text", wantErr64: true, wantErr32: true},
+		{name: "empty"},
+		{name: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got64, err := getInt64Setting(settings, tt.name)
+			if (err != nil) != tt.wantErr64 || !equalInt64(got64, tt.want64) {
+				t.Errorf("getInt64Setting() = %v, %v, want %v, error %v", got64, err, tt.want64, tt.wantErr64)
+			}
+			got32, err := getInt32Setting(settings, tt.name)
+			if (err != nil) != tt.wantErr32 || !equalInt32(got32, tt.want32) {
+				t.Errorf("getInt32Setting() = %v, %v, want %v, error %v", got32, err, tt.want32, tt.wantErr32)
+			}
+		})
+	}
+}
+
+func int64Ptr(i int64) *int64 { return &i }
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func equalInt64(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func equalInt32(a, b *int32) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"sync"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RenderContext carries everything needed to render one component instance of an ApplicationConfiguration.
+type RenderContext struct {
+	Owner                  v1.OwnerReference
+	Namespace              string
+	Annotations            map[string]string
+	ComponentConfiguration v1alpha1.ComponentConfiguration
+	Component              v1alpha1.ComponentSchematic
+	Parameters             map[string]string
+	// ConfigMaps rendered from the container config files of the component
+	ConfigMaps []apiv1.ConfigMap
+	// annotations of the ApplicationConfiguration, which request operations such as restores
+	ApplicationAnnotations map[string]string
+}
+
+// ConfigVolumes returns the volumes of the config files, the ConfigMaps rendered from them and the Secrets
+// of the sensitive values.
+func (c *RenderContext) ConfigVolumes() []apiv1.Volume {
+	return append(convertVolumesFromConfig(c.ConfigMaps), convertVolumesFromSecrets(c.Component.Spec.Containers, c.Parameters)...)
+}
+
+// NewAnnotations returns a copy of the instance annotations, so that every rendered object owns its annotations.
+func (c *RenderContext) NewAnnotations() map[string]string {
+	annotations := make(map[string]string, len(c.Annotations))
+	for k, v := range c.Annotations {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// WorkloadRenderer converts a component of a workload type into kubernetes objects.
+type WorkloadRenderer interface {
+	// Render returns the objects of the component instance in the order they should be applied.
+	Render(ctx *RenderContext) ([]runtime.Object, error)
+	// Health evaluates the component instance of the ApplicationConfiguration from its live objects.
+	Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error)
+	// Kind and GroupVersion of the workload type shown in the module status.
+	Kind() string
+	GroupVersion() string
+}
+
+var (
+	workloadRenderersLock = new(sync.RWMutex)
+	workloadRenderers     = map[string]WorkloadRenderer{}
+)
+
+// RegisterWorkload registers the renderer of a workload type, e.g. core.oam.dev/v1alpha1.Server.
+func RegisterWorkload(workloadType string, renderer WorkloadRenderer) {
+	workloadRenderersLock.Lock()
+	defer workloadRenderersLock.Unlock()
+	workloadRenderers[workloadType] = renderer
+}
+
+func getWorkloadRenderer(workloadType string) WorkloadRenderer {
+	workloadRenderersLock.RLock()
+	defer workloadRenderersLock.RUnlock()
+	return workloadRenderers[workloadType]
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// configMapRenderer renders a workload type into a ConfigMap and reports the health it is given.
+type configMapRenderer struct {
+	health Health
+}
+
+func (r *configMapRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	annotations := ctx.NewAnnotations()
+	annotations[Role] = Workload
+	return []runtime.Object{&apiv1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: ctx.ComponentConfiguration.InstanceName, Annotations: annotations, OwnerReferences: []v1.OwnerReference{ctx.Owner}},
+		Data:       ctx.Parameters,
+	}}, nil
+}
+
+func (r *configMapRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return r.health, nil
+}
+
+func (r *configMapRenderer) Kind() string {
+	return "Settings"
+}
+
+func (r *configMapRenderer) GroupVersion() string {
+	return "example.com/v1"
+}
+
+func TestBuiltinRenderers(t *testing.T) {
+	RegisterBuiltins()
+	tests := []struct {
+		workloadType string
+		want         []string
+		wantReplicas int32
+	}{
+		{WorkloadTypeServer, []string{"*v1.Deployment", "*v1.Service"}, 1},
+		{WorkloadTypeSingletonServer, []string{"*v1.Deployment", "*v1.Service"}, 1},
+		{WorkloadTypeWorker, []string{"*v1.Deployment"}, 1},
+		{WorkloadTypeTask, []string{"*v1.Job"}, 1},
+		{WorkloadTypeDaemonWorker, []string{"*v1.DaemonSet"}, 0},
+		{WorkloadTypeStatefulServer, []string{"*v1.Service", "*v1.StatefulSet"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.workloadType, func(t *testing.T) {
+			renderer := getWorkloadRenderer(tt.workloadType)
+			if renderer == nil {
+				t.Fatalf("no renderer of %s", tt.workloadType)
+			}
+			ac := newTestApplicationConfiguration()
+			objects, err := renderer.Render(&RenderContext{
+				Owner:                  *v1.NewControllerRef(ac, v1alpha1.SchemeGroupVersion.WithKind(ApplicationConfigurationKind)),
+				Namespace:              ac.Namespace,
+				Annotations:            map[string]string{Instance: "web"},
+				ComponentConfiguration: v1alpha1.ComponentConfiguration{ComponentName: "web", InstanceName: "web"},
+				Component: v1alpha1.ComponentSchematic{
+					ObjectMeta: v1.ObjectMeta{Name: "web"},
+					Spec: v1alpha1.ComponentSpec{
+						WorkloadType: tt.workloadType,
+						Containers: []v1alpha1.Container{{
+							Name:  "web",
+							Image: "nginx:1",
+							Ports: []v1alpha1.Port{{Name: "http", ContainerPort: 80}},
+						}},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			var kinds []string
+			for _, obj := range objects {
+				kinds = append(kinds, fmt.Sprintf("%T", obj))
+				if replicas := reflect.ValueOf(obj).Elem().FieldByName("Spec").FieldByName("Replicas"); replicas.IsValid() && *replicas.Interface().(*int32) != tt.wantReplicas {
+					t.Errorf("%T replicas = %v, want %v", obj, *replicas.Interface().(*int32), tt.wantReplicas)
+				}
+			}
+			if !reflect.DeepEqual(kinds, tt.want) {
+				t.Errorf("Render() = %v, want %v", kinds, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterWorkload(t *testing.T) {
+	RegisterBuiltins()
+	RegisterWorkload("example.com/v1.Settings", &configMapRenderer{health: Health{Progressing: true, Reason: ResourcesProgressing, Message: "settings pending"}})
+	comp := &v1alpha1.ComponentSchematic{
+		ObjectMeta: v1.ObjectMeta{Name: "settings", Namespace: "default"},
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: "example.com/v1.Settings",
+			Parameters:   []v1alpha1.Parameter{{Name: "level", ParameterType: "string", Default: "info"}},
+		},
+	}
+	ac := newTestApplicationConfiguration(v1alpha1.ComponentConfiguration{ComponentName: "settings", InstanceName: "settings"})
+	s, k8sclient, _ := newTestHandler([]runtime.Object{comp, ac}, nil, nil)
+	if err := s.Handle(nil, ac.DeepCopy(), oam.CreateOrUpdate); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	configMap, err := k8sclient.CoreV1().ConfigMaps(ac.Namespace).Get("settings", v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("rendered ConfigMap: %v", err)
+	}
+	if configMap.Data["level"] != "info" {
+		t.Errorf("ConfigMap data = %v, want level=info", configMap.Data)
+	}
+	live, err := s.Oamclient.CoreV1alpha1().ApplicationConfigurations(ac.Namespace).Get(ac.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []v1alpha1.ModuleStatus{{NamespacedName: "settings", Kind: "Settings", GroupVersion: "example.com/v1", Status: Unhealthy}}
+	if !reflect.DeepEqual(live.Status.Modules, want) {
+		t.Errorf("modules = %+v, want %+v", live.Status.Modules, want)
+	}
+}
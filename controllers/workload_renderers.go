@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeploymentRenderer renders Server and Worker workloads into a Deployment.
+type DeploymentRenderer struct {
+	WorkloadKind string
+	// Singleton workloads always run one replica
+	Singleton bool
+	// Service renders a Service for the workload
+	Service bool
+}
+
+// JobRenderer renders Task workloads into a Job.
+type JobRenderer struct {
+	WorkloadKind string
+	Singleton    bool
+}
+
+// CronJobRenderer renders ScheduledTask workloads into a CronJob, which runs a Job on the schedule of the workload settings.
+type CronJobRenderer struct{}
+
+// DaemonSetRenderer renders DaemonWorker workloads into a DaemonSet, which runs one replica on every node.
+type DaemonSetRenderer struct{}
+
+// StatefulSetRenderer renders StatefulServer workloads into a StatefulSet with its headless Service.
+type StatefulSetRenderer struct{}
+
+// MysqlClusterRenderer renders MysqlCluster workloads into a MysqlCluster with its ConfigMap, PersistentVolumeClaim
+// and the connection Secret referenced by the other components.
+type MysqlClusterRenderer struct{}
+
+func (r *DeploymentRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	compConf := ctx.ComponentConfiguration
+	var objects []runtime.Object
+
+	deployment := convertDeployment(ctx.Owner, ctx.NewAnnotations(), compConf, ctx.Component, ctx.Parameters)
+	var replicas int32 = 1
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Template.Spec.Volumes = ctx.ConfigVolumes()
+	objects = append(objects, deployment)
+
+	if r.Service {
+		if service := convertService(ctx.Owner, ctx.NewAnnotations(), compConf, ctx.Component); service != nil {
+			objects = append(objects, service)
+		}
+	}
+
+	traitObjects, err := applyTraits(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	if r.Singleton {
+		deployment.Spec.Replicas = &replicas
+	}
+	return append(objects, traitObjects...), nil
+}
+
+func (r *DeploymentRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return resourcesHealth(ac, instanceName, objects)
+}
+
+func (r *DeploymentRenderer) Kind() string {
+	return r.WorkloadKind
+}
+
+func (r *DeploymentRenderer) GroupVersion() string {
+	return OamV1alpha1GroupVersion
+}
+
+func (r *JobRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	job := convertJob(ctx.Owner, ctx.NewAnnotations(), ctx.ComponentConfiguration, ctx.Component, ctx.Parameters)
+	var parallelism int32 = 1
+	job.Spec.Parallelism = &parallelism
+	job.Spec.Template.Spec.Volumes = ctx.ConfigVolumes()
+
+	traitObjects, err := applyTraits(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if r.Singleton {
+		job.Spec.Parallelism = &parallelism
+	}
+	return append([]runtime.Object{job}, traitObjects...), nil
+}
+
+func (r *JobRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return resourcesHealth(ac, instanceName, objects)
+}
+
+func (r *JobRenderer) Kind() string {
+	return r.WorkloadKind
+}
+
+func (r *JobRenderer) GroupVersion() string {
+	return OamV1alpha1GroupVersion
+}
+
+func (r *CronJobRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	cronJob, err := convertCronJob(ctx.Owner, ctx.NewAnnotations(), ctx.ComponentConfiguration, ctx.Component, ctx.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	cronJob.Spec.JobTemplate.Spec.Template.Spec.Volumes = ctx.ConfigVolumes()
+
+	traitObjects, err := applyTraits(ctx, cronJob)
+	if err != nil {
+		return nil, err
+	}
+	return append([]runtime.Object{cronJob}, traitObjects...), nil
+}
+
+func (r *CronJobRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return resourcesHealth(ac, instanceName, objects)
+}
+
+func (r *CronJobRenderer) Kind() string {
+	return ScheduledTaskKind
+}
+
+func (r *CronJobRenderer) GroupVersion() string {
+	return HcV1alpha1GroupVersion
+}
+
+func (r *DaemonSetRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	daemonSet := convertDaemonSet(ctx.Owner, ctx.NewAnnotations(), ctx.ComponentConfiguration, ctx.Component, ctx.Parameters)
+	daemonSet.Spec.Template.Spec.Volumes = ctx.ConfigVolumes()
+
+	traitObjects, err := applyTraits(ctx, daemonSet)
+	if err != nil {
+		return nil, err
+	}
+	return append([]runtime.Object{daemonSet}, traitObjects...), nil
+}
+
+func (r *DaemonSetRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return resourcesHealth(ac, instanceName, objects)
+}
+
+func (r *DaemonSetRenderer) Kind() string {
+	return DaemonWorkerKind
+}
+
+func (r *DaemonSetRenderer) GroupVersion() string {
+	return HcV1alpha1GroupVersion
+}
+
+func (r *StatefulSetRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	statefulSet, err := convertStatefulSet(ctx.Owner, ctx.NewAnnotations(), ctx.ComponentConfiguration, ctx.Component, ctx.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	var replicas int32 = 1
+	statefulSet.Spec.Replicas = &replicas
+	statefulSet.Spec.Template.Spec.Volumes = append(statefulSet.Spec.Template.Spec.Volumes, ctx.ConfigVolumes()...)
+	service := convertHeadlessService(ctx.Owner, ctx.NewAnnotations(), ctx.ComponentConfiguration, ctx.Component)
+
+	traitObjects, err := applyTraits(ctx, statefulSet)
+	if err != nil {
+		return nil, err
+	}
+	return append([]runtime.Object{service, statefulSet}, traitObjects...), nil
+}
+
+func (r *StatefulSetRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return resourcesHealth(ac, instanceName, objects)
+}
+
+func (r *StatefulSetRenderer) Kind() string {
+	return StatefulServerKind
+}
+
+func (r *StatefulSetRenderer) GroupVersion() string {
+	return HcV1alpha1GroupVersion
+}
+
+func (r *MysqlClusterRenderer) Render(ctx *RenderContext) ([]runtime.Object, error) {
+	mysqlCluster, mysqlCm, mysqlPvc, err := convertMysqlCluster(ctx.Owner, ctx.NewAnnotations(), ctx.ComponentConfiguration, ctx.Component, ctx.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	var replicas int32 = 1
+	mysqlCluster.Spec.Replicas = &replicas
+	if err := applyMysqlOperations(ctx.ApplicationAnnotations, mysqlCluster); err != nil {
+		return nil, err
+	}
+	secret := convertMysqlConnectionSecret(ctx.Owner, ctx.NewAnnotations(), ctx.Namespace, mysqlCluster)
+
+	traitObjects, err := applyTraits(ctx, mysqlCluster)
+	if err != nil {
+		return nil, err
+	}
+	return append([]runtime.Object{mysqlCm, secret, mysqlPvc, mysqlCluster}, traitObjects...), nil
+}
+
+func (r *MysqlClusterRenderer) Health(ac *v1alpha1.ApplicationConfiguration, instanceName string, objects []runtime.Object) (Health, error) {
+	return resourcesHealth(ac, instanceName, objects)
+}
+
+func (r *MysqlClusterRenderer) Kind() string {
+	return MysqlClusterKind
+}
+
+func (r *MysqlClusterRenderer) GroupVersion() string {
+	return MysqlClusterGroupVersion
+}
@@ -4,11 +4,13 @@ go 1.12
 
 require (
 	github.com/oam-dev/oam-go-sdk v0.0.0-20200311031835-ce9ec52bd420
+	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4
 	k8s.io/api v0.17.0
 	k8s.io/apimachinery v0.17.0
 	k8s.io/client-go v0.17.0
 	k8s.io/klog v1.0.0
 	sigs.k8s.io/controller-runtime v0.4.0
+	sigs.k8s.io/yaml v1.1.0
 )
 
 replace github.com/oam-dev/oam-go-sdk => github.com/chenbilong/oam-go-sdk v0.0.0-20200416154853-f4529ed960a7
@@ -0,0 +1,26 @@
+#!/usr/bin/env bash
+
+# Regenerates the clientset, listers and informers of the API packages into client/. The directory of each
+# package is named after its API group, code-generator keeps one group per directory.
+# code-generator v0.18 takes a context in the requests, which client-go v0.17 does not, so the context
+# is dropped from them after generating. Do not edit the generated code, rerun this script instead.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+CODEGEN_PKG=${CODEGEN_PKG:-$(go env GOPATH)/pkg/mod/k8s.io/code-generator@v0.18.0}
+OUTPUT_BASE=$(mktemp -d)
+trap 'rm -rf "${OUTPUT_BASE}"' EXIT
+
+bash "${CODEGEN_PKG}/generate-groups.sh" client,lister,informer \
+  hc-oam-controller/client hc-oam-controller/api \
+  "harmonycloud.cn:v1beta1 mysql.middleware.harmonycloud.cn:v1alpha1" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt"
+
+find "${OUTPUT_BASE}/hc-oam-controller/client" -name '*.go' -exec sed -i -e 's/Do(ctx)/Do()/' -e 's/Watch(ctx)/Watch()/' {} +
+
+rm -rf "${SCRIPT_ROOT}/client"
+cp -r "${OUTPUT_BASE}/hc-oam-controller/client" "${SCRIPT_ROOT}/client"
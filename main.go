@@ -5,16 +5,19 @@ import (
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
 	"github.com/oam-dev/oam-go-sdk/pkg/oam"
-	hcv1alpha1 "hc-oam-controller/api/harmonycloud.cn/v1alpha1"
 	hcv1beta1 "hc-oam-controller/api/harmonycloud.cn/v1beta1"
+	hcv1alpha1 "hc-oam-controller/api/mysql.middleware.harmonycloud.cn/v1alpha1"
 	hcversioned "hc-oam-controller/client/clientset/versioned"
 	"hc-oam-controller/controllers"
 	v1 "k8s.io/api/apps/v1"
 	"k8s.io/api/autoscaling/v2beta2"
 	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	kubescheme "k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -23,6 +26,7 @@ import (
 	"log"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 var (
@@ -46,10 +50,22 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var enableWebhooks bool
+	var webhookPort int
+	var webhookCertDir string
+	var revisionHistoryLimit int
+	var enqueueQPS float64
+	var enqueueBurst int
 	metricsAddr = ""
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the admission webhooks of ApplicationConfiguration and ComponentSchematic.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "The directory containing tls.crt and tls.key of the webhook server.")
+	flag.IntVar(&revisionHistoryLimit, "revision-history-limit", controllers.DefaultRevisionHistoryLimit, "The number of revisions kept of each ApplicationConfiguration and ComponentSchematic.")
+	flag.Float64Var(&enqueueQPS, "enqueue-qps", controllers.DefaultEnqueueQPS, "The number of ApplicationConfigurations per second of a namespace reconciled after a ComponentSchematic or Trait they refer to changed.")
+	flag.IntVar(&enqueueBurst, "enqueue-burst", controllers.DefaultEnqueueBurst, "The number of ApplicationConfigurations of a namespace reconciled at once after a ComponentSchematic or Trait they refer to changed.")
 	flag.Parse()
-	options := ctrl.Options{Scheme: scheme, MetricsBindAddress: metricsAddr}
+	options := ctrl.Options{Scheme: scheme, MetricsBindAddress: metricsAddr, Port: webhookPort, CertDir: webhookCertDir}
 	//options := ctrl.Options{Scheme: scheme}
 
 	// init
@@ -70,17 +86,40 @@ func main() {
 		log.Fatal("create hc client err: ", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(ctrl.GetConfigOrDie())
+	if err != nil {
+		log.Fatal("create dynamic client err: ", err)
+	}
+
 	//event
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(klog.Infof)
 	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
 	recorder := eventBroadcaster.NewRecorder(kubescheme.Scheme, corev1.EventSource{Component: "hc-oam-controller"})
 
+	// ApplicationConfigurations are reconciled when the ComponentSchematics and Traits they refer to change,
+	// and again while they wait
+	if err := controllers.IndexApplicationConfigurations(oam.GetMgr().GetFieldIndexer()); err != nil {
+		log.Fatal("index ApplicationConfigurations err: ", err)
+	}
+	enqueuer := controllers.NewApplicationEnqueuer(oamclient, recorder, enqueueQPS, enqueueBurst)
+	if err := oam.GetMgr().Add(enqueuer); err != nil {
+		log.Fatal("add enqueuer err: ", err)
+	}
+
 	// register workloadtpye & trait hooks and handlers
 	oam.RegisterHandlers(oam.STypeApplicationConfiguration,
-		&controllers.ApplicationConfigurationHandler{Name: "application-configuration-handler", Oamclient: oamclient, K8sclient: clientset, Hcclient: hcClient, Recorder: recorder})
+		&controllers.ApplicationConfigurationHandler{Name: "application-configuration-handler", Oamclient: oamclient, K8sclient: clientset, Hcclient: hcClient, Dynamicclient: dynamicClient, Reader: oam.GetMgr().GetClient(), Recorder: recorder, RevisionHistoryLimit: revisionHistoryLimit, Enqueuer: enqueuer})
+	oam.RegisterHandlers(oam.STypeComponent,
+		&controllers.ComponentSchematicHandler{Name: "component-schematic-handler", Oamclient: oamclient, K8sclient: clientset, Reader: oam.GetMgr().GetClient(), Enqueuer: enqueuer, RevisionHistoryLimit: revisionHistoryLimit})
+	oam.RegisterHandlers(oam.STypeTrait,
+		&controllers.TraitDefinitionHandler{Name: "trait-definition-handler", Oamclient: oamclient, Reader: oam.GetMgr().GetClient(), Enqueuer: enqueuer})
 	oam.RegisterObject("deployment", new(v1.Deployment))
 	oam.RegisterHandlers("deployment", &controllers.DeploymentHandler{Name: "deployment-handler", Oamclient: oamclient, K8sclient: clientset})
+	oam.RegisterObject("daemonset", new(v1.DaemonSet))
+	oam.RegisterHandlers("daemonset", &controllers.DaemonSetHandler{Name: "daemonset-handler", Oamclient: oamclient, K8sclient: clientset})
+	oam.RegisterObject("statefulset", new(v1.StatefulSet))
+	oam.RegisterHandlers("statefulset", &controllers.StatefulSetHandler{Name: "statefulset-handler", Oamclient: oamclient, K8sclient: clientset})
 	oam.RegisterObject("service", new(corev1.Service))
 	oam.RegisterHandlers("service", &controllers.ServiceHandler{Name: "service-handler", Oamclient: oamclient, K8sclient: clientset})
 	oam.RegisterObject("configmap", new(corev1.ConfigMap))
@@ -89,7 +128,10 @@ func main() {
 	oam.RegisterHandlers("persistentvolumeclaim", &controllers.PvcHandler{Name: "pvc-handler", Oamclient: oamclient, K8sclient: clientset})
 	oam.RegisterObject("job", new(batchv1.Job))
 	oam.RegisterHandlers("job", &controllers.JobHandler{Name: "job-handler", Oamclient: oamclient, K8sclient: clientset})
+	oam.RegisterObject("cronjob", new(batchv1beta1.CronJob))
+	oam.RegisterHandlers("cronjob", &controllers.CronJobHandler{Name: "cronjob-handler", Oamclient: oamclient, K8sclient: clientset})
 	oam.RegisterObject("mysqlcluster", new(hcv1alpha1.MysqlCluster))
+	oam.RegisterHandlers("mysqlcluster", &controllers.MysqlClusterHandler{Name: "mysqlcluster-handler", Oamclient: oamclient, K8sclient: clientset, Hcclient: hcClient, Recorder: recorder})
 	oam.RegisterObject("ingress", new(v1beta1.Ingress))
 	oam.RegisterHandlers("ingress", &controllers.IngressHandler{Name: "ingress-handler", Oamclient: oamclient, K8sclient: clientset})
 	oam.RegisterObject("hpa", new(v2beta2.HorizontalPodAutoscaler))
@@ -97,20 +139,54 @@ func main() {
 	oam.RegisterObject("hchpa", new(hcv1beta1.HorizontalPodAutoscaler))
 	oam.RegisterHandlers("hchpa", &controllers.HcHpaHandler{Name: "hchpa-handler", Oamclient: oamclient, K8sclient: clientset})
 
+	// workload types and traits, register your own renderer or trait handler after the builtins
+	controllers.RegisterBuiltins()
+
+	// objects of WorkloadTypes with a template, WorkloadTypes created later are watched after a restart.
+	// The cache is not started yet, they are listed by the oamclient
+	var templateSpecs []oam.Option
+	templateWorkloads, err := controllers.GetTemplateWorkloads(nil, oamclient)
+	if err != nil {
+		setupLog.Error(err, "list WorkloadTypes with a template failed.")
+	}
+	for _, w := range templateWorkloads {
+		obj := new(unstructured.Unstructured)
+		obj.SetGroupVersionKind(w.GVK)
+		sType := oam.SType(w.Name)
+		oam.RegisterObject(sType, obj)
+		oam.RegisterHandlers(sType, &controllers.TemplateWorkloadHandler{Name: w.Name + "-handler", Oamclient: oamclient, Renderer: w.Renderer})
+		templateSpecs = append(templateSpecs, oam.WithSpec(sType))
+		setupLog.Info("watch template workload.", "WorkloadType", w.Name, "Kind", w.GVK.String())
+	}
+
+	if enableWebhooks {
+		hookServer := oam.GetMgr().GetWebhookServer()
+		hookServer.Register(controllers.ValidateApplicationConfigurationPath, &webhook.Admission{Handler: &controllers.ApplicationConfigurationValidator{Oamclient: oamclient, K8sclient: clientset}})
+		hookServer.Register(controllers.MutateApplicationConfigurationPath, &webhook.Admission{Handler: &controllers.ApplicationConfigurationDefaulter{Oamclient: oamclient, K8sclient: clientset}})
+		hookServer.Register(controllers.ValidateComponentSchematicPath, &webhook.Admission{Handler: &controllers.ComponentSchematicValidator{Oamclient: oamclient}})
+		setupLog.Info("webhooks enabled.", "Port", webhookPort, "CertDir", webhookCertDir)
+	}
+
 	// reconcilers must register manualy
 	// cloudnativeapp/oam-runtime/pkg/oam as a pkg should not do os.Exit(), instead of
 	// panic or returning Error could be better
-	err = oam.Run(oam.WithApplicationConfiguration(),
+	specs := []oam.Option{oam.WithApplicationConfiguration(),
+		oam.WithComponent(),
+		oam.WithTrait(),
 		oam.WithSpec("deployment"),
+		oam.WithSpec("daemonset"),
+		oam.WithSpec("statefulset"),
 		oam.WithSpec("service"),
 		oam.WithSpec("configmap"),
 		oam.WithSpec("persistentvolumeclaim"),
 		oam.WithSpec("job"),
-		//oam.WithSpec("mysqlcluster"),
+		oam.WithSpec("cronjob"),
+		oam.WithSpec("mysqlcluster"),
 		//oam.WithSpec("hpa"),
 		oam.WithSpec("hchpa"),
 		oam.WithSpec("ingress"),
-	)
+	}
+	err = oam.Run(append(specs, templateSpecs...)...)
 
 	if err != nil {
 		panic(err)